@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// apiKeyPrincipalKey is the context key for the principal of the API key.
+const apiKeyPrincipalKey contextKey = "apiKeyPrincipal"
+
+// APIKeyLookup returns the principal, like the client or the account, that
+// owns the key, and nil when no one does. Errors are for failures looking
+// it up, like the database being down.
+type APIKeyLookup func(ctx context.Context, key string) (any, error)
+
+// APIKeyOption allows to configure the APIKeyAuth middleware.
+type APIKeyOption func(*apiKey)
+
+// WithAPIKeyHeader sets the header the key is read from, which
+// defaults to X-API-Key.
+func WithAPIKeyHeader(name string) APIKeyOption {
+	return func(ak *apiKey) {
+		ak.header = name
+	}
+}
+
+// WithAPIKeyQuery allows the key to be passed in the query parameter,
+// for the legacy clients that can't set headers. Keys in the query end
+// up in the logs of proxies and browsers, so they're rejected by default.
+func WithAPIKeyQuery(param string) APIKeyOption {
+	return func(ak *apiKey) {
+		ak.query = param
+	}
+}
+
+// apiKey is the configuration of the APIKeyAuth middleware.
+type apiKey struct {
+	header string
+	query  string
+}
+
+// APIKeyAuth returns a middleware that authenticates the requests with the
+// key of the X-API-Key header, or the bearer token of the Authorization
+// header, looking up its principal, which the handlers get with
+// APIKeyPrincipal. Requests without a key or with one that has no principal
+// get a 401 through the error handlers, and the ones the lookup fails for
+// a 500. Requests with the key in the api_key query parameter get a 401 too
+// unless it's allowed with WithAPIKeyQuery.
+func APIKeyAuth(lookup APIKeyLookup, options ...APIKeyOption) Middleware {
+	ak := &apiKey{header: "X-API-Key"}
+	for _, option := range options {
+		option(ak)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ak.query == "" && r.URL.Query().Has("api_key") {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				Error(w, fmt.Errorf("401 API keys are not accepted in the query string"), http.StatusUnauthorized)
+
+				return
+			}
+
+			var principal any
+			if key := ak.key(r); key != "" {
+				var err error
+				principal, err = lookup(r.Context(), key)
+				if err != nil {
+					Error(w, fmt.Errorf("looking up the API key: %w", err), http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if principal == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				Error(w, fmt.Errorf("401 unauthorized"), http.StatusUnauthorized)
+
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), apiKeyPrincipalKey, principal))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// key returns the API key of the request, from the header, the
+// bearer token or the query parameter when it's allowed.
+func (ak *apiKey) key(r *http.Request) string {
+	if key := r.Header.Get(ak.header); key != "" {
+		return key
+	}
+
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if strings.EqualFold(scheme, "Bearer") && token != "" {
+		return strings.TrimSpace(token)
+	}
+
+	if ak.query != "" {
+		return r.URL.Query().Get(ak.query)
+	}
+
+	return ""
+}
+
+// APIKeys returns an APIKeyLookup for a fixed set of keys and their
+// principals, like the ones of the environment. Keys are compared in
+// constant time.
+func APIKeys(keys map[string]any) APIKeyLookup {
+	type entry struct {
+		hash      [32]byte
+		principal any
+	}
+
+	entries := make([]entry, 0, len(keys))
+	for key, principal := range keys {
+		entries = append(entries, entry{sha256.Sum256([]byte(key)), principal})
+	}
+
+	return func(ctx context.Context, key string) (any, error) {
+		// all the keys are compared, so the known ones don't take less.
+		given := sha256.Sum256([]byte(key))
+
+		var principal any
+		for _, e := range entries {
+			if subtle.ConstantTimeCompare(given[:], e.hash[:]) == 1 {
+				principal = e.principal
+			}
+		}
+
+		return principal, nil
+	}
+}
+
+// APIKeyPrincipal returns the principal of the key the request was
+// authenticated with by APIKeyAuth, false when there is none or it's
+// not of the type T.
+func APIKeyPrincipal[T any](r *http.Request) (T, bool) {
+	principal, ok := r.Context().Value(apiKeyPrincipalKey).(T)
+	return principal, ok
+}
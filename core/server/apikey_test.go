@@ -0,0 +1,107 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	type client struct{ Name string }
+
+	lookup := server.APIKeys(map[string]any{
+		"key-1": &client{Name: "billing"},
+	})
+
+	s := server.New()
+	s.Group("/api/", func(r server.Router) {
+		r.Use(server.APIKeyAuth(lookup))
+		r.HandleFunc("GET /whoami", func(w http.ResponseWriter, r *http.Request) {
+			c, ok := server.APIKeyPrincipal[*client](r)
+			if !ok {
+				t.Error("Expected the principal in the context")
+				return
+			}
+
+			w.Write([]byte(c.Name))
+		})
+	})
+
+	s.Group("/legacy/", func(r server.Router) {
+		r.Use(server.APIKeyAuth(lookup, server.WithAPIKeyHeader("X-Token"), server.WithAPIKeyQuery("token")))
+		r.HandleFunc("GET /whoami", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+	})
+
+	s.Group("/failing/", func(r server.Router) {
+		r.Use(server.APIKeyAuth(func(ctx context.Context, key string) (any, error) {
+			return nil, errors.New("database is down")
+		}))
+
+		r.HandleFunc("GET /whoami", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	h := s.Handler()
+	serve := func(path string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("header", func(t *testing.T) {
+		res := serve("/api/whoami", "X-API-Key", "key-1")
+		if res.Code != http.StatusOK || res.Body.String() != "billing" {
+			t.Errorf("Expected the principal of the key, got %d %q", res.Code, res.Body.String())
+		}
+	})
+
+	t.Run("bearer", func(t *testing.T) {
+		if res := serve("/api/whoami", "Authorization", "bearer key-1"); res.Code != http.StatusOK {
+			t.Errorf("Expected the bearer token to be accepted, got %d", res.Code)
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		for _, headers := range [][]string{nil, {"X-API-Key", "key-2"}, {"Authorization", "Basic a2V5LTE6"}} {
+			res := serve("/api/whoami", headers...)
+			if res.Code != http.StatusUnauthorized || res.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("Expected 401 for %v, got %d", headers, res.Code)
+			}
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		if res := serve("/api/whoami?api_key=key-1"); res.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the key in the query to be rejected, got %d", res.Code)
+		}
+
+		if res := serve("/api/whoami?api_key=key-1", "X-API-Key", "key-1"); res.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the key in the query to be rejected with a header, got %d", res.Code)
+		}
+
+		if res := serve("/legacy/whoami?token=key-1"); res.Code != http.StatusOK {
+			t.Errorf("Expected the key in the query to be allowed, got %d", res.Code)
+		}
+
+		if res := serve("/legacy/whoami", "X-Token", "key-1"); res.Code != http.StatusOK {
+			t.Errorf("Expected the key in the custom header, got %d", res.Code)
+		}
+	})
+
+	t.Run("lookup fails", func(t *testing.T) {
+		if res := serve("/failing/whoami", "X-API-Key", "key-1"); res.Code != http.StatusInternalServerError {
+			t.Errorf("Expected 500, got %d", res.Code)
+		}
+	})
+}
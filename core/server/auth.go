@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+// currentUserKey is the context key for the user loaded by Authenticate.
+const currentUserKey contextKey = "currentUser"
+
+// UserLoader loads the user of the request from its session, it returns
+// nil when the request has no user, like when nobody has logged in.
+type UserLoader func(ctx context.Context, s *sessions.Session) (any, error)
+
+// Authenticate returns a middleware that loads the user of the requests
+// with the loader and stores it in their context, so the handlers can get
+// it with CurrentUser. Requests without a user are served as they are,
+// RequireAuth stops them, and the ones the loader fails for get a 500
+// through the error handlers. It requires the session of WithSession.
+func Authenticate(loader UserLoader) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := loader(r.Context(), session.FromCtx(r.Context()))
+			if err != nil {
+				Error(w, fmt.Errorf("loading the current user: %w", err), http.StatusInternalServerError)
+				return
+			}
+
+			if user != nil {
+				r = r.WithContext(context.WithValue(r.Context(), currentUserKey, user))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CurrentUser returns the user loaded by Authenticate for the request,
+// false when there is none or it's not of the type T.
+func CurrentUser[T any](r *http.Request) (T, bool) {
+	user, ok := r.Context().Value(currentUserKey).(T)
+	return user, ok
+}
+
+// RequireAuth returns a middleware that stops the requests without a user
+// loaded by Authenticate. With a path, like "/login", they're redirected
+// to it with a 303, and with a status, like http.StatusUnauthorized, the
+// response is written with the error handler for it. It panics when
+// redirectOrStatus is of another type.
+func RequireAuth(redirectOrStatus any) Middleware {
+	var deny func(w http.ResponseWriter, r *http.Request)
+	switch v := redirectOrStatus.(type) {
+	case string:
+		deny = func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, v, http.StatusSeeOther)
+		}
+	case int:
+		deny = func(w http.ResponseWriter, r *http.Request) {
+			Error(w, fmt.Errorf("%d %s", v, strings.ToLower(http.StatusText(v))), v)
+		}
+	default:
+		panic(fmt.Sprintf("server: RequireAuth takes a path or a status, got %T", redirectOrStatus))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Context().Value(currentUserKey) == nil {
+				deny(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
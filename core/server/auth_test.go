@@ -0,0 +1,120 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+type user struct {
+	Name string
+}
+
+func TestAuthenticate(t *testing.T) {
+	loader := func(ctx context.Context, s *sessions.Session) (any, error) {
+		switch s.Values["user_id"] {
+		case "1":
+			return &user{Name: "Jane"}, nil
+		case "broken":
+			return nil, errors.New("database is down")
+		}
+
+		return nil, nil
+	}
+
+	s := server.New(
+		server.WithSession("secret", "app"),
+		server.WithErrorHandler(http.StatusUnauthorized, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("please log in"))
+		}),
+	)
+
+	s.Use(server.Authenticate(loader))
+	s.HandleFunc("GET /login/{id}", func(w http.ResponseWriter, r *http.Request) {
+		session.FromCtx(r.Context()).Values["user_id"] = r.PathValue("id")
+		w.Write([]byte("logged in"))
+	})
+
+	s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		if u, ok := server.CurrentUser[*user](r); ok {
+			w.Write([]byte("hello " + u.Name))
+			return
+		}
+
+		w.Write([]byte("hello guest"))
+	})
+
+	s.Group("/dashboard", func(r server.Router) {
+		r.Use(server.RequireAuth("/login"))
+		r.HandleFunc("GET /home", func(w http.ResponseWriter, r *http.Request) {
+			u, _ := server.CurrentUser[*user](r)
+			w.Write([]byte("dashboard of " + u.Name))
+		})
+	})
+
+	s.Group("/api", func(r server.Router) {
+		r.Use(server.RequireAuth(http.StatusUnauthorized))
+		r.HandleFunc("GET /me", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	h := s.Handler()
+	serve := func(path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	login := func(id string) []*http.Cookie {
+		return serve("/login/"+id, nil).Result().Cookies()
+	}
+
+	t.Run("guests", func(t *testing.T) {
+		if body := serve("/", nil).Body.String(); body != "hello guest" {
+			t.Errorf("Expected no current user, got %q", body)
+		}
+
+		res := serve("/dashboard/home", nil)
+		if res.Code != http.StatusSeeOther || res.Header().Get("Location") != "/login" {
+			t.Errorf("Expected a redirect to /login, got %d %q", res.Code, res.Header().Get("Location"))
+		}
+
+		res = serve("/api/me", nil)
+		if res.Code != http.StatusUnauthorized || res.Body.String() != "please log in" {
+			t.Errorf("Expected the error handler for 401, got %d %q", res.Code, res.Body.String())
+		}
+	})
+
+	t.Run("users", func(t *testing.T) {
+		cookies := login("1")
+		if body := serve("/", cookies).Body.String(); body != "hello Jane" {
+			t.Errorf("Expected the current user, got %q", body)
+		}
+
+		if body := serve("/dashboard/home", cookies).Body.String(); body != "dashboard of Jane" {
+			t.Errorf("Expected the dashboard, got %q", body)
+		}
+
+		if _, ok := server.CurrentUser[string](httptest.NewRequest(http.MethodGet, "/", nil)); ok {
+			t.Error("Expected no user without Authenticate")
+		}
+	})
+
+	t.Run("loader errors", func(t *testing.T) {
+		if res := serve("/", login("broken")); res.Code != http.StatusInternalServerError {
+			t.Errorf("Expected 500, got %d", res.Code)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// basicAuthUserKey is the context key for the user authenticated with basic auth.
+const basicAuthUserKey contextKey = "basicAuthUser"
+
+// BasicAuth returns a middleware that requires the requests to be
+// authenticated with HTTP basic auth with one of the users and passwords
+// of the credentials. Passwords are compared in constant time.
+func BasicAuth(realm string, credentials map[string]string) Middleware {
+	hashed := make(map[string][32]byte, len(credentials))
+	for user, password := range credentials {
+		hashed[user] = sha256.Sum256([]byte(password))
+	}
+
+	return BasicAuthFunc(realm, func(user, password string) bool {
+		// unknown users are compared too, so they take as long as the known ones.
+		expected, ok := hashed[user]
+		given := sha256.Sum256([]byte(password))
+
+		return subtle.ConstantTimeCompare(given[:], expected[:]) == 1 && ok
+	})
+}
+
+// BasicAuthFunc returns a middleware that requires the requests to be
+// authenticated with HTTP basic auth, checking the users and passwords
+// with verify, which allows to check them against a store. Requests that
+// fail get a 401 with the WWW-Authenticate challenge for the realm through
+// the error handlers, and the user of the ones that pass is available
+// with BasicAuthUser.
+func BasicAuthFunc(realm string, verify func(user, password string) bool) Middleware {
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, password, ok := r.BasicAuth()
+			if !ok || !verify(user, password) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				Error(w, fmt.Errorf("401 unauthorized"), http.StatusUnauthorized)
+
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), basicAuthUserKey, user))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BasicAuthUser returns the user the request was authenticated
+// with by the BasicAuth middleware, empty when it wasn't.
+func BasicAuthUser(r *http.Request) string {
+	user, _ := r.Context().Value(basicAuthUserKey).(string)
+	return user
+}
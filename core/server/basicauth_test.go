@@ -0,0 +1,101 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestBasicAuth(t *testing.T) {
+	s := server.New(
+		server.WithErrorHandler(http.StatusUnauthorized, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("who are you?"))
+		}),
+	)
+
+	s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("home"))
+	})
+
+	s.Group("/admin/", func(r server.Router) {
+		r.Use(server.BasicAuth("Admin", map[string]string{"admin": "s3cret"}))
+		r.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello " + server.BasicAuthUser(r)))
+		})
+	})
+
+	s.Group("/staging/", func(r server.Router) {
+		r.Use(server.BasicAuthFunc("Staging", func(user, password string) bool {
+			return user == "qa" && password == "qa-pass"
+		}))
+
+		r.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("staging " + server.BasicAuthUser(r)))
+		})
+	})
+
+	h := s.Handler()
+
+	serve := func(path, user, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("valid credentials", func(t *testing.T) {
+		res := serve("/admin/users", "admin", "s3cret")
+		if res.Code != http.StatusOK || res.Body.String() != "hello admin" {
+			t.Errorf("Expected 200 with the user, got %d %q", res.Code, res.Body.String())
+		}
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		for name, creds := range map[string][2]string{
+			"missing":        {"", ""},
+			"wrong password": {"admin", "nope"},
+			"unknown user":   {"root", "s3cret"},
+		} {
+			res := serve("/admin/users", creds[0], creds[1])
+			if res.Code != http.StatusUnauthorized {
+				t.Errorf("%s: expected 401, got %d", name, res.Code)
+			}
+
+			if res.Body.String() != "who are you?" {
+				t.Errorf("%s: expected the error handler for 401, got %q", name, res.Body.String())
+			}
+
+			expected := `Basic realm="Admin", charset="UTF-8"`
+			if res.Header().Get("WWW-Authenticate") != expected {
+				t.Errorf("%s: expected challenge %q, got %q", name, expected, res.Header().Get("WWW-Authenticate"))
+			}
+		}
+	})
+
+	t.Run("verify func", func(t *testing.T) {
+		res := serve("/staging/status", "qa", "qa-pass")
+		if res.Code != http.StatusOK || res.Body.String() != "staging qa" {
+			t.Errorf("Expected 200 with the user, got %d %q", res.Code, res.Body.String())
+		}
+
+		res = serve("/staging/status", "admin", "s3cret")
+		if res.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", res.Code)
+		}
+	})
+
+	t.Run("routes outside the group", func(t *testing.T) {
+		res := serve("/", "", "")
+		if res.Code != http.StatusOK || res.Body.String() != "home" {
+			t.Errorf("Expected 200 without credentials, got %d %q", res.Code, res.Body.String())
+		}
+	})
+}
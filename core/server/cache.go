@@ -0,0 +1,275 @@
+package server
+
+import (
+	"container/list"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOption allows to configure the Cache middleware.
+type CacheOption func(*ResponseCache)
+
+// WithCacheVary sets the request headers, like Accept-Language, whose
+// values are part of the key of the cached responses, so the responses
+// for different values are cached apart.
+func WithCacheVary(headers ...string) CacheOption {
+	return func(rc *ResponseCache) {
+		for _, h := range headers {
+			rc.vary = append(rc.vary, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// WithCacheMaxSize sets the most bytes the cached responses take,
+// the least recently used ones are evicted to make room for the new
+// ones. It defaults to 32MB.
+func WithCacheMaxSize(size int) CacheOption {
+	return func(rc *ResponseCache) {
+		rc.maxSize = size
+	}
+}
+
+// WithCacheSessionCookie sets the name of the cookie that identifies
+// the users, only the requests with it are not cached. By default the
+// requests with any cookie are not cached.
+func WithCacheSessionCookie(name string) CacheOption {
+	return func(rc *ResponseCache) {
+		rc.cookie = name
+	}
+}
+
+// Cache returns a middleware that caches in memory the 200 responses to
+// the anonymous GET requests for the ttl, it's the Middleware of a cache
+// returned by NewCache, which is kept to remove the responses with Bust.
+func Cache(ttl time.Duration, options ...CacheOption) Middleware {
+	return NewCache(ttl, options...).Middleware
+}
+
+// NewCache returns a cache of the 200 responses to the anonymous GET
+// requests, kept in memory for the ttl, which its Middleware serves
+// without calling the handler, and which suits the pages that are the
+// same for everyone. The responses are keyed by their host, path and
+// query, and the headers passed with WithCacheVary, and have an X-Cache
+// header set to HIT or MISS. Requests with a cookie or an Authorization
+// header, and responses that set cookies, vary by other headers or are
+// private, are never cached, and neither are the streamed ones. HEAD
+// requests are served with the cached GET responses.
+func NewCache(ttl time.Duration, options ...CacheOption) *ResponseCache {
+	rc := &ResponseCache{
+		ttl:     ttl,
+		maxSize: 32 << 20,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+
+	for _, option := range options {
+		option(rc)
+	}
+
+	return rc
+}
+
+// Middleware serves the cached responses and caches the new ones.
+func (rc *ResponseCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || !rc.anonymous(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := rc.key(r)
+		if e := rc.get(key); e != nil {
+			h := w.Header()
+			for k, v := range e.header {
+				h[k] = v
+			}
+
+			h.Set("X-Cache", "HIT")
+			h.Set("Age", strconv.Itoa(int(time.Since(e.stored).Seconds())))
+			w.WriteHeader(http.StatusOK)
+			if r.Method == http.MethodGet {
+				w.Write(e.body)
+			}
+
+			return
+		}
+
+		sent := w.Header()
+		sent.Set("X-Cache", "MISS")
+		if r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, max: rc.maxSize}
+		next.ServeHTTP(rec, r)
+
+		// the headers sent include the ones set by the writers and
+		// header hooks of the outer middleware, like the session cookie.
+		if rec.status != http.StatusOK || !rec.whole() || !cacheable(rec.header, sent, rc.vary) {
+			return
+		}
+
+		rec.header.Del("X-Cache")
+		if rec.header.Get("Cache-Control") == "" && sent.Get("Cache-Control") != "" {
+			rec.header.Set("Cache-Control", sent.Get("Cache-Control"))
+		}
+
+		rc.put(key, r.URL.Path, &cacheEntry{header: rec.header, body: rec.body.Bytes()})
+	})
+}
+
+// ResponseCache is a cache of responses, created with NewCache.
+type ResponseCache struct {
+	ttl     time.Duration
+	vary    []string
+	cookie  string
+	maxSize int
+
+	moot    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int
+}
+
+// cacheEntry is a response cached by the Cache middleware.
+type cacheEntry struct {
+	key    string
+	path   string
+	header http.Header
+	body   []byte
+	stored time.Time
+	size   int
+}
+
+// anonymous returns whether the request is not made by a user.
+func (rc *ResponseCache) anonymous(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return false
+	}
+
+	if rc.cookie == "" {
+		return r.Header.Get("Cookie") == ""
+	}
+
+	_, err := r.Cookie(rc.cookie)
+	return err != nil
+}
+
+// key returns the key of the response to the request, the host is part
+// of it so the servers of several hosts don't share their responses.
+func (rc *ResponseCache) key(r *http.Request) string {
+	var sb strings.Builder
+	sb.WriteString(requestHost(r))
+	sb.WriteString(r.URL.RequestURI())
+	for _, h := range rc.vary {
+		sb.WriteString("\n" + h + ": " + strings.Join(r.Header.Values(h), ", "))
+	}
+
+	return sb.String()
+}
+
+// get returns the response cached for the key, nil if
+// there is none or it's expired, which is removed.
+func (rc *ResponseCache) get(key string) *cacheEntry {
+	rc.moot.Lock()
+	defer rc.moot.Unlock()
+
+	el, ok := rc.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := el.Value.(*cacheEntry)
+	if time.Since(e.stored) >= rc.ttl {
+		rc.remove(el)
+		return nil
+	}
+
+	rc.lru.MoveToFront(el)
+
+	return e
+}
+
+// put caches the response for the key, evicting the least recently
+// used responses until it fits. Responses larger than the cache are
+// not cached.
+func (rc *ResponseCache) put(key, path string, e *cacheEntry) {
+	e.key, e.path, e.stored = key, path, time.Now()
+	e.size = len(key) + len(e.body)
+	for k, v := range e.header {
+		e.size += len(k) + len(strings.Join(v, ""))
+	}
+
+	if e.size > rc.maxSize {
+		return
+	}
+
+	rc.moot.Lock()
+	defer rc.moot.Unlock()
+
+	if el, ok := rc.entries[key]; ok {
+		rc.remove(el)
+	}
+
+	for rc.size+e.size > rc.maxSize {
+		rc.remove(rc.lru.Back())
+	}
+
+	rc.entries[key] = rc.lru.PushFront(e)
+	rc.size += e.size
+}
+
+// Bust removes the cached responses for the paths that match the
+// pattern, with the syntax of path.Match, so "/posts/*" removes the
+// ones of the posts and "/posts" only the list.
+func (rc *ResponseCache) Bust(pattern string) {
+	rc.moot.Lock()
+	defer rc.moot.Unlock()
+
+	for _, el := range rc.entries {
+		if ok, _ := path.Match(pattern, el.Value.(*cacheEntry).path); ok {
+			rc.remove(el)
+		}
+	}
+}
+
+// remove removes the cached response of the list element.
+func (rc *ResponseCache) remove(el *list.Element) {
+	e := rc.lru.Remove(el).(*cacheEntry)
+	delete(rc.entries, e.key)
+	rc.size -= e.size
+}
+
+// cacheable returns whether the response with the headers written by the
+// handler and the ones sent can be shared with everyone, which it can't
+// when it sets cookies, is private or varies by headers that are not part
+// of the key. Accept-Encoding is allowed while the body is not encoded.
+func cacheable(written, sent http.Header, vary []string) bool {
+	if sent.Get("Set-Cookie") != "" {
+		return false
+	}
+
+	cc := strings.ToLower(sent.Get("Cache-Control"))
+	if strings.Contains(cc, "private") || strings.Contains(cc, "no-store") || strings.Contains(cc, "no-cache") {
+		return false
+	}
+
+	for _, v := range written.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" || slices.Contains(vary, name) || (name == "Accept-Encoding" && written.Get("Content-Encoding") == "") {
+				continue
+			}
+
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,214 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestCache(t *testing.T) {
+	var calls atomic.Int32
+	page := func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "page %d %s", n, r.Header.Get("Accept-Language"))
+	}
+
+	cache := server.NewCache(time.Minute, server.WithCacheVary("Accept-Language"), server.WithCacheMaxSize(256))
+
+	s := server.New()
+	s.Use(cache.Middleware)
+	s.HandleFunc("GET /posts", page)
+	s.HandleFunc("GET /posts/{id}", page)
+	s.HandleFunc("GET /large", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(strings.Repeat("x", 300)))
+	})
+
+	s.HandleFunc("GET /cookie", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: "visited", Value: "1"})
+		w.Write([]byte("ok"))
+	})
+
+	s.HandleFunc("GET /private", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "private")
+		w.Write([]byte("ok"))
+	})
+
+	s.HandleFunc("GET /missing", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	h := s.Handler()
+	serve := func(method, path string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		calls.Store(0)
+		first := serve(http.MethodGet, "/posts/1")
+		second := serve(http.MethodGet, "/posts/1")
+
+		if first.Header().Get("X-Cache") != "MISS" || second.Header().Get("X-Cache") != "HIT" {
+			t.Errorf("Expected MISS and HIT, got %q and %q", first.Header().Get("X-Cache"), second.Header().Get("X-Cache"))
+		}
+
+		if second.Body.String() != first.Body.String() || second.Header().Get("Content-Type") != "text/html" {
+			t.Errorf("Expected the cached response, got %q %q", second.Header().Get("Content-Type"), second.Body.String())
+		}
+
+		if calls.Load() != 1 {
+			t.Errorf("Expected the handler called once, got %d", calls.Load())
+		}
+
+		res := serve(http.MethodHead, "/posts/1")
+		if res.Header().Get("X-Cache") != "HIT" || res.Body.Len() != 0 {
+			t.Errorf("Expected a HEAD hit without body, got %q %q", res.Header().Get("X-Cache"), res.Body.String())
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		calls.Store(0)
+		serve(http.MethodGet, "/posts?page=2")
+		serve(http.MethodGet, "/posts?page=3")
+		en := serve(http.MethodGet, "/posts?page=2", "Accept-Language", "en")
+		es := serve(http.MethodGet, "/posts?page=2", "Accept-Language", "es")
+
+		if calls.Load() != 4 || en.Body.String() == es.Body.String() {
+			t.Errorf("Expected the queries and headers cached apart, got %d calls", calls.Load())
+		}
+	})
+
+	t.Run("anonymous only", func(t *testing.T) {
+		serve(http.MethodGet, "/posts/2")
+
+		calls.Store(0)
+		for _, header := range [][]string{{"Cookie", "session=abc"}, {"Authorization", "Bearer token"}} {
+			if res := serve(http.MethodGet, "/posts/2", header...); res.Header().Get("X-Cache") != "" {
+				t.Errorf("Expected the request with %s not to use the cache, got %q", header[0], res.Header().Get("X-Cache"))
+			}
+		}
+
+		if calls.Load() != 2 {
+			t.Errorf("Expected the handler called for each request, got %d", calls.Load())
+		}
+	})
+
+	t.Run("not cached", func(t *testing.T) {
+		for _, path := range []string{"/cookie", "/private", "/missing", "/large"} {
+			calls.Store(0)
+			serve(http.MethodGet, path)
+			res := serve(http.MethodGet, path)
+
+			if calls.Load() != 2 || res.Header().Get("X-Cache") != "MISS" {
+				t.Errorf("Expected %s not to be cached, got %d calls", path, calls.Load())
+			}
+		}
+	})
+
+	t.Run("evicted", func(t *testing.T) {
+		cache.Bust("/*")
+		serve(http.MethodGet, "/posts/3")
+		for i := 0; i < 10; i++ {
+			serve(http.MethodGet, fmt.Sprintf("/posts/%d", 10+i))
+		}
+
+		calls.Store(0)
+		serve(http.MethodGet, "/posts/3")
+		if calls.Load() != 1 {
+			t.Error("Expected the least recently used response to be evicted")
+		}
+	})
+
+	t.Run("bust", func(t *testing.T) {
+		serve(http.MethodGet, "/posts")
+		serve(http.MethodGet, "/posts/4")
+
+		cache.Bust("/posts/*")
+
+		calls.Store(0)
+		serve(http.MethodGet, "/posts")
+		serve(http.MethodGet, "/posts/4")
+		if calls.Load() != 1 {
+			t.Errorf("Expected only the post to be removed, got %d calls", calls.Load())
+		}
+	})
+
+	t.Run("hosts", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.Cache(time.Minute))
+		s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Host))
+		})
+
+		h := s.Handler()
+		get := func(host string) string {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = host
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			return res.Body.String()
+		}
+
+		get("a.example.com")
+		if body := get("b.example.com"); body != "b.example.com" {
+			t.Errorf("Expected the response of the host, got %q", body)
+		}
+
+		if body := get("A.example.com"); body != "a.example.com" {
+			t.Errorf("Expected the cached response of the host, got %q", body)
+		}
+	})
+
+	t.Run("session", func(t *testing.T) {
+		s := server.New(server.WithSession("secret", "leapkit"))
+		s.Use(server.Cache(time.Minute, server.WithCacheSessionCookie("leapkit")))
+		s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			server.Flash(w, r, "info", "welcome")
+			w.Write([]byte("ok"))
+		})
+
+		s.HandleFunc("GET /about", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("about"))
+		})
+
+		h := s.Handler()
+		for i := 0; i < 2; i++ {
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if res.Header().Get("X-Cache") != "MISS" || res.Header().Get("Set-Cookie") == "" {
+				t.Errorf("Expected the response with the session not to be cached, got %q", res.Header().Get("X-Cache"))
+			}
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/about", nil)
+		req.AddCookie(&http.Cookie{Name: "analytics", Value: "1"})
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if res.Header().Get("X-Cache") != "HIT" {
+			t.Errorf("Expected the requests with other cookies cached, got %q", res.Header().Get("X-Cache"))
+		}
+	})
+}
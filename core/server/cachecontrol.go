@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/leapkit/leapkit/core/server/internal/response"
+)
+
+// CacheControl returns a middleware that sets the Cache-Control header of
+// the responses to the directives, like "public, max-age=300", when the
+// handler hasn't set one. The header is set right before the response is
+// sent, and the responses that set cookies, like the session one, are
+// made private so shared caches don't store them.
+func CacheControl(directives string) Middleware {
+	return cacheControl(directives, nil)
+}
+
+// NoStore returns a middleware that tells the caches not to store the
+// responses, setting Cache-Control to no-store and the Pragma and Expires
+// headers for old proxies, when the handler hasn't set Cache-Control.
+func NoStore() Middleware {
+	return cacheControl("no-store", http.Header{
+		"Pragma":  {"no-cache"},
+		"Expires": {"0"},
+	})
+}
+
+// cacheControl returns the middleware that sets Cache-Control to the
+// directives and the extra headers when the handler hasn't set it.
+func cacheControl(directives string, extra http.Header) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := response.Root(w)
+			if rw == nil {
+				rw = &response.Writer{ResponseWriter: w}
+				w = rw
+			}
+
+			rw.HeaderHooks = append(rw.HeaderHooks, func(h http.Header) {
+				if h.Get("Cache-Control") != "" {
+					return
+				}
+
+				value := directives
+				if len(h.Values("Set-Cookie")) > 0 {
+					value = private(value)
+				}
+
+				h.Set("Cache-Control", value)
+				for k, v := range extra {
+					if h.Get(k) == "" {
+						h[k] = v
+					}
+				}
+			})
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// private returns the directives that let shared caches store the
+// response, like public, replaced with private.
+func private(directives string) string {
+	parts := strings.Split(directives, ",")
+	kept := parts[:0]
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.EqualFold(part, "no-store"), strings.EqualFold(part, "private"):
+			return directives
+		case strings.EqualFold(part, "public"), strings.HasPrefix(strings.ToLower(part), "s-maxage="):
+			continue
+		}
+
+		kept = append(kept, part)
+	}
+
+	return strings.Join(append([]string{"private"}, kept...), ", ")
+}
@@ -0,0 +1,74 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestCacheControl(t *testing.T) {
+	s := server.New(server.WithSession("secret", "app"))
+
+	s.Group("/pages", func(r server.Router) {
+		r.Use(server.CacheControl("public, max-age=300"))
+		r.HandleFunc("GET /about", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("about"))
+		})
+
+		r.HandleFunc("GET /live", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Write([]byte("live"))
+		})
+
+		r.HandleFunc("GET /welcome", func(w http.ResponseWriter, r *http.Request) {
+			session.FromCtx(r.Context()).Values["seen"] = true
+			w.Write([]byte("welcome"))
+		})
+
+		r.Group("/news", func(r server.Router) {
+			r.Use(server.CacheControl("public, max-age=60"))
+			r.HandleFunc("GET /latest", func(w http.ResponseWriter, r *http.Request) {})
+		})
+	})
+
+	s.Group("/account", func(r server.Router) {
+		r.Use(server.NoStore())
+		r.HandleFunc("GET /profile", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("profile"))
+		})
+	})
+
+	h := s.Handler()
+	serve := func(path string) http.Header {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return res.Header()
+	}
+
+	cases := map[string]string{
+		"/pages/about":       "public, max-age=300",
+		"/pages/live":        "no-cache",
+		"/pages/welcome":     "private, max-age=300",
+		"/pages/news/latest": "public, max-age=60",
+		"/account/profile":   "no-store",
+	}
+
+	for path, expected := range cases {
+		if v := serve(path).Values("Cache-Control"); len(v) != 1 || v[0] != expected {
+			t.Errorf("Expected Cache-Control %q for %s, got %v", expected, path, v)
+		}
+	}
+
+	h2 := serve("/account/profile")
+	if h2.Get("Pragma") != "no-cache" || h2.Get("Expires") != "0" {
+		t.Errorf("Expected Pragma and Expires with NoStore, got %q %q", h2.Get("Pragma"), h2.Get("Expires"))
+	}
+
+	if h2 := serve("/pages/about"); h2.Get("Pragma") != "" {
+		t.Errorf("Expected no Pragma with CacheControl, got %q", h2.Get("Pragma"))
+	}
+}
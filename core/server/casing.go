@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// originalURLKey is the context key for the URL of the request
+// before its path was rewritten to the case of the routes.
+const originalURLKey contextKey = "originalURL"
+
+// WithCaseInsensitiveRouting makes the literal segments of the path match
+// the routes regardless of their case, the route is served with the
+// original URL of the request. Path parameters and the query string keep
+// the case they were sent with.
+func WithCaseInsensitiveRouting() Option {
+	return func(m *mux) {
+		m.caseInsensitive = true
+	}
+}
+
+// WithCaseInsensitiveRedirect makes requests whose path only matches a
+// route when the literal segments are lowercased be redirected to that
+// path, with a 301 for GET and HEAD requests and a 308 for the rest.
+func WithCaseInsensitiveRedirect() Option {
+	return func(m *mux) {
+		m.caseInsensitive = true
+		m.caseRedirect = true
+	}
+}
+
+// caseAlternative returns a copy of the request with the literal segments
+// of its path lowercased when only that matches a route, nil otherwise.
+func (s *mux) caseAlternative(r *http.Request) *http.Request {
+	if _, pattern := s.lookup(r); pattern != "" {
+		return nil
+	}
+
+	lower := strings.ToLower(r.URL.Path)
+	if lower == r.URL.Path {
+		return nil
+	}
+
+	req := r.Clone(r.Context())
+	req.URL.RawPath = ""
+	req.URL.Path = lower
+
+	_, pattern := s.lookup(req)
+	if pattern == "" {
+		return nil
+	}
+
+	req.URL.Path = foldLiterals(pattern, r.URL.Path)
+	if _, pattern := s.lookup(req); pattern == "" {
+		return nil
+	}
+
+	return req
+}
+
+// foldLiterals lowercases the segments of the path that correspond to
+// literal segments of the pattern, leaving the wildcards untouched.
+func foldLiterals(pattern, path string) string {
+	if _, p, found := strings.Cut(pattern, " "); found {
+		pattern = p
+	}
+
+	// patterns with a host start with it.
+	pattern = pattern[strings.Index(pattern, "/"):]
+
+	psegs := strings.Split(pattern, "/")
+	segs := strings.Split(path, "/")
+	for i, seg := range psegs {
+		if i >= len(segs) {
+			break
+		}
+
+		last := i == len(psegs)-1
+		if strings.HasSuffix(seg, "...}") || (last && (seg == "" || seg == "{$}")) {
+			break
+		}
+
+		if !strings.HasPrefix(seg, "{") {
+			segs[i] = strings.ToLower(segs[i])
+		}
+	}
+
+	return strings.Join(segs, "/")
+}
+
+// restoreURL sets back the URL the request had before its
+// path was rewritten to match the case of the route.
+func restoreURL(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, ok := r.Context().Value(originalURLKey).(*url.URL); ok {
+			r = r.WithContext(context.WithValue(r.Context(), originalURLKey, nil))
+			r.URL = u
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,81 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestCaseInsensitiveRouting(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.String() + " " + r.PathValue("name")))
+	}
+
+	t.Run("rewrite", func(t *testing.T) {
+		s := server.New(server.WithCaseInsensitiveRouting())
+		s.HandleFunc("GET /users/{name}/profile", handler)
+		s.HandleFunc("GET /files/{path...}", handler)
+
+		cases := []struct {
+			path string
+			code int
+			body string
+		}{
+			{"/users/Alice/profile", http.StatusOK, "/users/Alice/profile Alice"},
+			{"/Users/Alice/PROFILE?Tab=Main", http.StatusOK, "/Users/Alice/PROFILE?Tab=Main Alice"},
+			{"/FILES/Docs/Readme.md", http.StatusOK, "/FILES/Docs/Readme.md "},
+			{"/people/Alice", http.StatusNotFound, ""},
+		}
+
+		for _, c := range cases {
+			t.Run(c.path, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, c.path, nil)
+				res := httptest.NewRecorder()
+				s.Handler().ServeHTTP(res, req)
+
+				if res.Code != c.code {
+					t.Errorf("Expected status %d, got %d", c.code, res.Code)
+				}
+
+				if c.body != "" && res.Body.String() != c.body {
+					t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+				}
+			})
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		s := server.New(server.WithCaseInsensitiveRedirect())
+		s.HandleFunc("GET /users/{name}/profile", handler)
+		s.HandleFunc("POST /users/{name}/profile", handler)
+
+		cases := []struct {
+			method   string
+			path     string
+			code     int
+			location string
+		}{
+			{http.MethodGet, "/users/Alice/profile", http.StatusOK, ""},
+			{http.MethodGet, "/Users/Alice/Profile?Tab=Main", http.StatusMovedPermanently, "/users/Alice/profile?Tab=Main"},
+			{http.MethodPost, "/USERS/Alice/profile", http.StatusPermanentRedirect, "/users/Alice/profile"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.method+" "+c.path, func(t *testing.T) {
+				req := httptest.NewRequest(c.method, c.path, nil)
+				res := httptest.NewRecorder()
+				s.Handler().ServeHTTP(res, req)
+
+				if res.Code != c.code {
+					t.Errorf("Expected status %d, got %d", c.code, res.Code)
+				}
+
+				if loc := res.Header().Get("Location"); loc != c.location {
+					t.Errorf("Expected location %q, got %q", c.location, loc)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WithCleanPathRedirect redirects the GET and HEAD requests whose path is
+// not in its canonical form, like //users/./1, to the clean path with a 301
+// instead of serving them with it. Requests with other methods are served
+// with the clean path.
+func WithCleanPathRedirect() Option {
+	return func(m *mux) {
+		m.cleanRedirect = true
+	}
+}
+
+// cleanPath returns the escaped path with the duplicate slashes collapsed
+// and the dot segments resolved, keeping the trailing slash. It returns
+// false when a .. segment goes above the root.
+func cleanPath(escaped string) (string, bool) {
+	if !strings.HasPrefix(escaped, "/") {
+		return escaped, true
+	}
+
+	// most paths are already clean, they're returned without splitting them.
+	if !strings.Contains(escaped, "//") && !strings.Contains(escaped, "/.") && !strings.Contains(escaped, "%") {
+		return escaped, true
+	}
+
+	segs := strings.Split(escaped[1:], "/")
+
+	var clean []string
+	trailing := false
+	for i, seg := range segs {
+		last := i == len(segs)-1
+
+		value, err := url.PathUnescape(seg)
+		if err != nil {
+			value = seg
+		}
+
+		switch value {
+		case "", ".":
+			trailing = last
+		case "..":
+			if len(clean) == 0 {
+				return "", false
+			}
+
+			clean = clean[:len(clean)-1]
+			trailing = last
+		default:
+			clean = append(clean, seg)
+		}
+	}
+
+	path := "/" + strings.Join(clean, "/")
+	if trailing && len(clean) > 0 {
+		path += "/"
+	}
+
+	return path, true
+}
+
+// cleanAlternative returns a copy of the request with the clean
+// path when the path of the request is not in its canonical form.
+func cleanAlternative(r *http.Request, clean string) *http.Request {
+	req := r.Clone(r.Context())
+	req.URL.RawPath = clean
+	if path, err := url.PathUnescape(clean); err == nil {
+		req.URL.Path = path
+	}
+
+	return req
+}
@@ -0,0 +1,85 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestCleanPath(t *testing.T) {
+	routes := func(s server.Router) {
+		s.Group("/admin/", func(r server.Router) {
+			r.HandleFunc("GET /secret", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("secret"))
+			})
+
+			r.HandleFunc("POST /secret", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("posted " + r.URL.Path))
+			})
+		}, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+		})
+
+		s.HandleFunc("GET /files/{path...}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("files " + r.URL.Path))
+		})
+	}
+
+	s := server.New()
+	routes(s)
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"//admin/secret", http.StatusForbidden, ""},
+		{"/files/../admin/secret", http.StatusForbidden, ""},
+		{"/admin/./secret", http.StatusForbidden, ""},
+		{"/files/%2e%2e/admin/secret", http.StatusForbidden, ""},
+		{"/files//docs/./a.txt", http.StatusOK, "files /files/docs/a.txt"},
+		{"/files/docs/../a.txt", http.StatusOK, "files /files/a.txt"},
+		{"/../etc/passwd", http.StatusBadRequest, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code {
+				t.Errorf("Expected status %d, got %d", c.code, res.Code)
+			}
+
+			if c.body != "" && res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+		})
+	}
+
+	t.Run("redirect", func(t *testing.T) {
+		s := server.New(server.WithCleanPathRedirect())
+		routes(s)
+
+		req := httptest.NewRequest(http.MethodGet, "/files//docs/../a.txt?v=1", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusMovedPermanently || res.Header().Get("Location") != "/files/a.txt?v=1" {
+			t.Errorf("Expected a redirect to /files/a.txt?v=1, got %d %q", res.Code, res.Header().Get("Location"))
+		}
+
+		req = httptest.NewRequest(http.MethodPost, "//admin/secret", nil)
+		res = httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, res.Code)
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressOption allows to configure the Compress middleware.
+type CompressOption func(*compress)
+
+// WithCompressMinSize sets the size a response must reach to be
+// compressed, smaller ones are sent as is. It defaults to 1024 bytes.
+func WithCompressMinSize(size int) CompressOption {
+	return func(c *compress) {
+		c.minSize = size
+	}
+}
+
+// WithCompressEncoder adds an encoding, like br with a brotli writer,
+// encodings added are preferred over gzip when the client accepts them.
+func WithCompressEncoder(encoding string, fn func(w io.Writer) io.WriteCloser) CompressOption {
+	return func(c *compress) {
+		c.encoders = append([]encoder{{name: encoding, new: fn}}, c.encoders...)
+	}
+}
+
+// compress is the configuration of the Compress middleware.
+type compress struct {
+	minSize  int
+	encoders []encoder
+}
+
+// encoder writes the responses with an encoding.
+type encoder struct {
+	name string
+	new  func(w io.Writer) io.WriteCloser
+}
+
+// gzipWriters pools the writers of the gzip encoding.
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(io.Discard) },
+}
+
+// Compress returns a middleware that compresses the responses with gzip, or
+// the encodings added with WithCompressEncoder, when the client accepts them.
+// Responses smaller than the minimum size, with a Content-Encoding or with a
+// content type that is already compressed, like images, are sent as is.
+// Flushing the response, like server-sent events do, sends what has been
+// written compressed so far.
+func Compress(options ...CompressOption) Middleware {
+	c := &compress{minSize: 1024}
+	c.encoders = []encoder{{name: "gzip", new: func(w io.Writer) io.WriteCloser {
+		gw := gzipWriters.Get().(*gzip.Writer)
+		gw.Reset(w)
+
+		return pooledGzip{gw}
+	}}}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			enc, ok := c.encoder(r.Header.Get("Accept-Encoding"))
+			if !ok || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// the response is not finished when the handler panics,
+			// the recoverer writes the error to the original writer.
+			cw := &compressWriter{ResponseWriter: w, compress: c, encoder: enc, status: http.StatusOK}
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}
+}
+
+// encoder returns the encoder for the most preferred encoding
+// accepted by the client, false when none of them is.
+func (c *compress) encoder(accept string) (encoder, bool) {
+	var best encoder
+	bestQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+
+		i := slices.IndexFunc(c.encoders, func(e encoder) bool { return strings.EqualFold(e.name, name) })
+		if i < 0 || q <= 0 {
+			continue
+		}
+
+		// ties are broken by the order of the encoders.
+		if q > bestQ || (q == bestQ && i < slices.IndexFunc(c.encoders, func(e encoder) bool { return e.name == best.name })) {
+			best, bestQ = c.encoders[i], q
+		}
+	}
+
+	return best, bestQ > 0
+}
+
+// compressedTypes are the prefixes of the content types
+// that are already compressed.
+var compressedTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/zstd", "application/octet-stream",
+}
+
+// compressWriter buffers the response until it reaches the minimum size,
+// then it decides whether to compress it and sends the headers.
+type compressWriter struct {
+	http.ResponseWriter
+	compress *compress
+	encoder  encoder
+
+	status int
+	buf    []byte
+
+	// decided is set once the headers have been sent,
+	// enc is the writer of the encoding if compressing.
+	decided bool
+	enc     io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	cw.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.compress.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// Flush sends what has been written so far, compressed
+// when the content type allows it whatever its size.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(true)
+	}
+
+	if f, ok := cw.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack takes over the connection, nothing is compressed after it.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the wrapped http.ResponseWriter.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide sends the headers of the response, compressing it when
+// it can be and the buffered content is large enough or the
+// response is being streamed, and then the buffered content.
+func (cw *compressWriter) decide(large bool) error {
+	cw.decided = true
+
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	ctype := h.Get("Content-Type")
+	compressed := slices.ContainsFunc(compressedTypes, func(t string) bool { return strings.HasPrefix(ctype, t) })
+	if large && !compressed && h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoder.name)
+		cw.enc = cw.encoder.new(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+// close sends the buffered content of the responses smaller
+// than the minimum size and finishes the compressed ones.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		// nothing was written, like a handler that only sets headers.
+		if len(cw.buf) == 0 && cw.status == http.StatusOK {
+			return
+		}
+
+		cw.decide(false)
+	}
+
+	if cw.enc != nil {
+		cw.enc.Close()
+	}
+}
+
+// pooledGzip returns the gzip writer to the pool when it's closed.
+type pooledGzip struct {
+	*gzip.Writer
+}
+
+func (pg pooledGzip) Close() error {
+	err := pg.Writer.Close()
+	pg.Writer.Reset(io.Discard)
+	gzipWriters.Put(pg.Writer)
+
+	return err
+}
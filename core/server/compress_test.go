@@ -0,0 +1,157 @@
+package server_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestCompress(t *testing.T) {
+	page := strings.Repeat("<p>hello leapkit</p>", 200)
+
+	s := server.New()
+	s.Use(server.Compress())
+	s.HandleFunc("GET /page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+		w.Write([]byte(page))
+	})
+
+	s.HandleFunc("GET /small", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("small"))
+	})
+
+	s.HandleFunc("GET /image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte(page))
+	})
+
+	s.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+	})
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("large responses are compressed", func(t *testing.T) {
+		res := serve("/page", "br;q=0.5, gzip")
+		if res.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("Expected gzip encoding, got %q", res.Header().Get("Content-Encoding"))
+		}
+
+		if res.Header().Get("Content-Length") != "" {
+			t.Errorf("Expected no Content-Length, got %q", res.Header().Get("Content-Length"))
+		}
+
+		if res.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Expected Vary Accept-Encoding, got %q", res.Header().Get("Vary"))
+		}
+
+		gr, err := gzip.NewReader(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(gr)
+		if string(body) != page {
+			t.Errorf("Expected the page, got %q", body)
+		}
+	})
+
+	t.Run("responses are not compressed", func(t *testing.T) {
+		for name, tc := range map[string]struct{ path, accept string }{
+			"small":          {"/small", "gzip"},
+			"compressed":     {"/image", "gzip"},
+			"not accepted":   {"/page", "identity"},
+			"refused":        {"/page", "gzip;q=0"},
+			"no encoding":    {"/page", ""},
+			"unknown coding": {"/page", "br"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				res := serve(tc.path, tc.accept)
+				if res.Header().Get("Content-Encoding") != "" {
+					t.Errorf("Expected no encoding, got %q", res.Header().Get("Content-Encoding"))
+				}
+
+				if tc.path == "/small" && res.Body.String() != "small" {
+					t.Errorf("Expected the body as is, got %q", res.Body.String())
+				}
+			})
+		}
+	})
+
+	t.Run("flushing sends the compressed content", func(t *testing.T) {
+		res := serve("/events", "gzip")
+		if !res.Flushed || res.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("Expected a flushed gzip response, got %v %v", res.Flushed, res.Header())
+		}
+
+		gr, err := gzip.NewReader(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(gr)
+		if string(body) != "data: first\n\n" {
+			t.Errorf("Expected the event, got %q", body)
+		}
+	})
+
+	t.Run("added encoders are preferred", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.Compress(
+			server.WithCompressMinSize(10),
+			server.WithCompressEncoder("deflate", func(w io.Writer) io.WriteCloser {
+				fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+				return fw
+			}),
+		))
+
+		s.HandleFunc("GET /page", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(page))
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/page", nil)
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Header().Get("Content-Encoding") != "deflate" {
+			t.Fatalf("Expected deflate encoding, got %q", res.Header().Get("Content-Encoding"))
+		}
+
+		body, _ := io.ReadAll(flate.NewReader(res.Body))
+		if string(body) != page {
+			t.Errorf("Expected the page, got %q", body)
+		}
+	})
+
+	t.Run("the logger records the compressed bytes", func(t *testing.T) {
+		var output bytes.Buffer
+		defer log.SetOutput(log.Writer())
+		log.SetOutput(&output)
+
+		res := serve("/page", "gzip")
+		if !strings.Contains(output.String(), "bytes="+strconv.Itoa(res.Body.Len())) {
+			t.Errorf("Expected bytes=%d in the log, got %q", res.Body.Len(), output.String())
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// LimitConcurrency returns a middleware that serves up to n requests at
+// a time, like for the routes backed by a fragile service. The requests
+// beyond n wait up to maxWait for one of the others to finish, then they
+// get a 503 with a Retry-After header through the error handlers. Requests
+// whose context is canceled while waiting, like when the client goes away,
+// leave the queue right away. The requests being served and waiting are
+// exposed by the Metrics endpoint for each route.
+func LimitConcurrency(n int, maxWait time.Duration) Middleware {
+	slots := make(chan struct{}, n)
+	retryAfter := strconv.Itoa(max(1, int(math.Ceil(maxWait.Seconds()))))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gauges := defaultMetrics.limited(r)
+
+			select {
+			case slots <- struct{}{}:
+			default:
+				if !wait(r, slots, maxWait, gauges) {
+					if r.Context().Err() != nil {
+						return
+					}
+
+					w.Header().Set("Retry-After", retryAfter)
+					Error(w, errors.New("503 service unavailable, too many concurrent requests"), http.StatusServiceUnavailable)
+
+					return
+				}
+			}
+
+			gauges.inFlight.Add(1)
+			defer func() {
+				gauges.inFlight.Add(-1)
+				<-slots
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// wait waits up to maxWait for a slot, it returns false when it
+// times out or the context of the request is canceled.
+func wait(r *http.Request, slots chan struct{}, maxWait time.Duration, gauges *concurrencyGauges) bool {
+	if maxWait <= 0 {
+		return false
+	}
+
+	gauges.queued.Add(1)
+	defer gauges.queued.Add(-1)
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,113 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestLimitConcurrency(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	started, release := make(chan struct{}, 4), make(chan struct{})
+	legacy := func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Write([]byte("report"))
+	}
+
+	s := server.New(server.WithMetricsEndpoint("/metrics"))
+	s.Use(server.Metrics())
+	s.Group("/legacy", func(r server.Router) {
+		r.Use(server.LimitConcurrency(1, 20*time.Millisecond))
+		r.HandleFunc("GET /report", legacy)
+	})
+
+	s.Group("/queued", func(r server.Router) {
+		r.Use(server.LimitConcurrency(1, 10*time.Second))
+		r.HandleFunc("GET /report", legacy)
+	})
+
+	h := s.Handler()
+	serve := func(ctx context.Context, path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+
+		return res
+	}
+
+	t.Run("rejected after waiting", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- serve(context.Background(), "/legacy/report") }()
+		<-started
+
+		res := serve(context.Background(), "/legacy/report")
+		if res.Code != http.StatusServiceUnavailable || res.Header().Get("Retry-After") != "1" {
+			t.Errorf("Expected 503 with Retry-After, got %d %q", res.Code, res.Header().Get("Retry-After"))
+		}
+
+		release <- struct{}{}
+		if res := <-done; res.Body.String() != "report" {
+			t.Errorf("Expected the first request to be served, got %q", res.Body.String())
+		}
+
+		go func() { done <- serve(context.Background(), "/legacy/report") }()
+		<-started
+		release <- struct{}{}
+		if res := <-done; res.Code != http.StatusOK {
+			t.Errorf("Expected the slot to be released, got %d", res.Code)
+		}
+	})
+
+	t.Run("queued and canceled", func(t *testing.T) {
+		first := make(chan *httptest.ResponseRecorder)
+		go func() { first <- serve(context.Background(), "/queued/report") }()
+		<-started
+
+		ctx, cancel := context.WithCancel(context.Background())
+		canceled := make(chan *httptest.ResponseRecorder)
+		go func() { canceled <- serve(ctx, "/queued/report") }()
+
+		queued := make(chan *httptest.ResponseRecorder)
+		go func() { queued <- serve(context.Background(), "/queued/report") }()
+
+		// waits for both requests to be in the queue.
+		var metrics string
+		for i := 0; i < 100 && !strings.Contains(metrics, `http_concurrency_queued{route="/queued/report"} 2`); i++ {
+			time.Sleep(time.Millisecond)
+			metrics = serve(context.Background(), "/metrics").Body.String()
+		}
+
+		for _, exp := range []string{
+			`http_concurrency_limited_in_flight{route="/queued/report"} 1`,
+			`http_concurrency_queued{route="/queued/report"} 2`,
+		} {
+			if !strings.Contains(metrics, exp) {
+				t.Errorf("Expected %q in the metrics, got %q", exp, metrics)
+			}
+		}
+
+		start := time.Now()
+		cancel()
+		if res := <-canceled; res.Body.Len() != 0 || time.Since(start) > time.Second {
+			t.Errorf("Expected the canceled request to leave the queue, got %d %q", res.Code, res.Body.String())
+		}
+
+		release <- struct{}{}
+		<-first
+
+		<-started
+		release <- struct{}{}
+		if res := <-queued; res.Body.String() != "report" {
+			t.Errorf("Expected the queued request to be served, got %d %q", res.Code, res.Body.String())
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Unless returns a middleware that applies mw to the requests except the
+// ones matching any of the predicates, like skipping the authentication
+// for /login. A predicate is a path, which matches the path and the ones
+// under it, or a func(*http.Request) bool. It panics when a predicate is
+// of another type.
+func Unless(mw Middleware, predicates ...any) Middleware {
+	match := compilePredicates("Unless", predicates)
+	return conditional(mw, func(r *http.Request) bool {
+		for _, m := range match {
+			if m(r) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// Only returns a middleware that applies mw to the requests matching all
+// the predicates, and skips it for the rest. A predicate is a path, which
+// matches the path and the ones under it, or a func(*http.Request) bool.
+// It panics when a predicate is of another type.
+func Only(mw Middleware, predicates ...any) Middleware {
+	match := compilePredicates("Only", predicates)
+	return conditional(mw, func(r *http.Request) bool {
+		for _, m := range match {
+			if !m(r) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// conditional returns the middleware that applies mw to the requests for
+// which apply returns true. The handler is wrapped once, so each request
+// only pays for the predicates.
+func conditional(mw Middleware, apply func(*http.Request) bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apply(r) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// compilePredicates turns the predicates into functions.
+func compilePredicates(name string, predicates []any) []func(*http.Request) bool {
+	match := make([]func(*http.Request) bool, 0, len(predicates))
+	for _, p := range predicates {
+		switch p := p.(type) {
+		case string:
+			paths := []string{p}
+			match = append(match, func(r *http.Request) bool {
+				return underPaths(r.URL.Path, paths)
+			})
+		case func(*http.Request) bool:
+			match = append(match, p)
+		default:
+			panic(fmt.Sprintf("server: %s predicates must be paths or func(*http.Request) bool, got %T", name, p))
+		}
+	}
+
+	return match
+}
@@ -0,0 +1,98 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestConditional(t *testing.T) {
+	// mark sets a header so the tests can tell whether it was applied.
+	mark := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Applied", "true")
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	isPost := func(r *http.Request) bool { return r.Method == http.MethodPost }
+
+	serve := func(mw server.Middleware, method, path string) bool {
+		s := server.New()
+		s.Use(mw)
+		s.HandleFunc("/{path...}", func(w http.ResponseWriter, r *http.Request) {})
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, httptest.NewRequest(method, path, nil))
+
+		return res.Header().Get("X-Applied") == "true"
+	}
+
+	t.Run("unless any predicate matches", func(t *testing.T) {
+		mw := server.Unless(mark, "/login", "/webhooks/", isPost)
+		cases := []struct {
+			method, path string
+			applied      bool
+		}{
+			{http.MethodGet, "/dashboard", true},
+			{http.MethodGet, "/login", false},
+			{http.MethodGet, "/loginx", true},
+			{http.MethodGet, "/webhooks/stripe", false},
+			{http.MethodPost, "/dashboard", false},
+		}
+
+		for _, c := range cases {
+			if applied := serve(mw, c.method, c.path); applied != c.applied {
+				t.Errorf("Expected %s %s applied to be %v, got %v", c.method, c.path, c.applied, applied)
+			}
+		}
+	})
+
+	t.Run("only when all predicates match", func(t *testing.T) {
+		mw := server.Only(mark, "/api", isPost)
+		cases := []struct {
+			method, path string
+			applied      bool
+		}{
+			{http.MethodPost, "/api/orders", true},
+			{http.MethodGet, "/api/orders", false},
+			{http.MethodPost, "/dashboard", false},
+		}
+
+		for _, c := range cases {
+			if applied := serve(mw, c.method, c.path); applied != c.applied {
+				t.Errorf("Expected %s %s applied to be %v, got %v", c.method, c.path, c.applied, applied)
+			}
+		}
+	})
+
+	t.Run("without predicates", func(t *testing.T) {
+		if !serve(server.Unless(mark), http.MethodGet, "/") || !serve(server.Only(mark), http.MethodGet, "/") {
+			t.Error("Expected the middleware to be applied without predicates")
+		}
+	})
+
+	t.Run("invalid predicates", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic for an invalid predicate")
+			}
+		}()
+
+		server.Unless(mark, 42)
+	})
+}
+
+func BenchmarkUnless(b *testing.B) {
+	mw := server.Unless(func(next http.Handler) http.Handler { return next }, "/login", "/webhooks/")
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/dashboard/reports", nil)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, req)
+	}
+}
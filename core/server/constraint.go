@@ -0,0 +1,316 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// typedConstraints are the named constraints that can be used for the
+// path parameters, like {id:int}, any other constraint is a regexp.
+var typedConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+}
+
+// paramCheck is a constraint on the value of a path parameter.
+type paramCheck struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// parseConstraints returns the pattern without the constraints of its path
+// parameters, so it can be registered in the mux, and the checks for them.
+func parseConstraints(pattern string) (string, []paramCheck, error) {
+	if !strings.Contains(pattern, ":") {
+		return pattern, nil, nil
+	}
+
+	var checks []paramCheck
+
+	segs := strings.Split(pattern, "/")
+	for i, seg := range segs {
+		if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") {
+			continue
+		}
+
+		name, expr, found := strings.Cut(seg[1:len(seg)-1], ":")
+		if !found {
+			continue
+		}
+
+		if typed, ok := typedConstraints[expr]; ok {
+			expr = typed
+		}
+
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid constraint for parameter %q: %w", name, err)
+		}
+
+		checks = append(checks, paramCheck{name: strings.TrimSuffix(name, "..."), re: re})
+		segs[i] = "{" + name + "}"
+	}
+
+	return strings.Join(segs, "/"), checks, nil
+}
+
+// patternShape returns the pattern with the names of its wildcards removed,
+// patterns with the same shape match the same requests in the mux.
+func patternShape(pattern string) string {
+	segs := strings.Split(pattern, "/")
+	for i, seg := range segs {
+		if !strings.HasPrefix(seg, "{") || seg == "{$}" {
+			continue
+		}
+
+		segs[i] = "{}"
+		if strings.HasSuffix(seg, "...}") {
+			segs[i] = "{...}"
+		}
+	}
+
+	return strings.Join(segs, "/")
+}
+
+// wildcardNames returns the names of the wildcards of the
+// pattern, without their constraints.
+func wildcardNames(pattern string) []string {
+	var names []string
+	for _, seg := range strings.Split(pattern, "/") {
+		if strings.HasPrefix(seg, "{") && seg != "{$}" {
+			name, _, _ := strings.Cut(strings.Trim(seg, "{}"), ":")
+			names = append(names, strings.TrimSuffix(name, "..."))
+		}
+	}
+
+	return names
+}
+
+// dispatcher is the handler registered in the mux for the routes with the
+// same shape, it serves the first of them whose constraints and query
+// matchers are met by the request. Routes without them go last.
+type dispatcher struct {
+	// pattern registered in the mux and the names of its wildcards.
+	pattern string
+	names   []string
+
+	candidates []candidate
+
+	// fallback serves the requests that don't meet
+	// the constraints of any of the routes.
+	fallback func() http.Handler
+}
+
+// candidate is one of the routes served by a dispatcher.
+type candidate struct {
+	route   Route
+	names   []string
+	checks  []paramCheck
+	handler http.Handler
+
+	// format is the name of the wildcard for the extension
+	// of the last segment, like {format} in {id}.{format}.
+	format string
+
+	// ref of the route, nil for the routes added by the server.
+	ref *RouteRef
+
+	// query matchers of the route when it was added.
+	query []queryMatcher
+}
+
+// isFallback returns whether the candidate is a fallback route.
+func (c candidate) isFallback() bool {
+	return c.ref != nil && c.ref.fallback
+}
+
+// constrained returns whether the candidate has constraints
+// on its path parameters or query matchers.
+func (c candidate) constrained() bool {
+	return len(c.checks) > 0 || len(c.query) > 0
+}
+
+// add adds the route to the dispatcher, it returns the route it conflicts
+// with when both have no constraints and can't be told apart.
+func (d *dispatcher) add(c candidate) (Route, bool) {
+	if !c.constrained() {
+		for _, other := range d.candidates {
+			if !other.constrained() {
+				return other.route, true
+			}
+		}
+	}
+
+	d.candidates = append(d.candidates, c)
+
+	return Route{}, false
+}
+
+// match returns the candidate that should serve the request with the
+// passed path values and URL, nil when none of the constraints are met.
+// Candidates with constraints are tried first in the order they were added.
+func (d *dispatcher) match(value func(name string) string, u *url.URL) *candidate {
+	var query url.Values
+	for _, constrained := range []bool{true, false} {
+		for i, c := range d.candidates {
+			if c.constrained() != constrained {
+				continue
+			}
+
+			if len(c.query) > 0 && query == nil {
+				query = u.Query()
+			}
+
+			if d.meets(c, value, query) {
+				return &d.candidates[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// meets returns whether the path values and the query
+// meet the constraints and query matchers of the candidate.
+func (d *dispatcher) meets(c candidate, value func(name string) string, query url.Values) bool {
+	value = d.values(c, value)
+	for _, check := range c.checks {
+		v := value(d.nameOf(c, check.name))
+		if check.name == c.format && v == "" {
+			continue
+		}
+
+		if !check.re.MatchString(v) {
+			return false
+		}
+	}
+
+	for _, qm := range c.query {
+		if !qm.match(query) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// values returns the path values for the candidate, splitting the
+// extension of the last wildcard when the candidate has a format.
+func (d *dispatcher) values(c candidate, value func(name string) string) func(name string) string {
+	if c.format == "" || len(d.names) == 0 {
+		return value
+	}
+
+	last := d.names[len(d.names)-1]
+	base, ext := splitFormat(value(last))
+
+	return func(name string) string {
+		switch name {
+		case last:
+			return base
+		case c.format:
+			return ext
+		}
+
+		return value(name)
+	}
+}
+
+// nameOf returns the name the wildcard of the
+// candidate has in the pattern registered in the mux.
+func (d *dispatcher) nameOf(c candidate, name string) string {
+	for i, n := range c.names {
+		if n == name && i < len(d.names) {
+			return d.names[i]
+		}
+	}
+
+	return name
+}
+
+func (d *dispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// a single route without constraints doesn't need to be matched.
+	c := &d.candidates[0]
+	if len(d.candidates) > 1 || c.constrained() {
+		c = d.match(r.PathValue, r.URL)
+	}
+
+	if c == nil || (c.isFallback() && c.ref.shadowed(r)) {
+		if h := d.fallback(); h != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		http.NotFound(w, r)
+		return
+	}
+
+	// the wildcards of the candidate could have other names
+	// than the ones of the pattern registered in the mux.
+	if !slices.Equal(c.names, d.names) {
+		values := make([]string, len(d.names))
+		for i, name := range d.names {
+			values[i] = r.PathValue(name)
+			r.SetPathValue(name, "")
+		}
+
+		for i, name := range c.names {
+			r.SetPathValue(name, values[i])
+		}
+	}
+
+	if c.format != "" && len(c.names) > 0 {
+		last := c.names[len(c.names)-1]
+		base, ext := splitFormat(r.PathValue(last))
+
+		r.SetPathValue(last, base)
+		r.SetPathValue(c.format, ext)
+		r = r.WithContext(context.WithValue(r.Context(), formatKey, ext))
+	}
+
+	c.handler.ServeHTTP(w, r)
+}
+
+// pathValues returns the values of the wildcards of the pattern
+// in the path of the request, like the mux does when serving it.
+func (d *dispatcher) pathValues(r *http.Request) func(name string) string {
+	pattern := d.pattern
+	if _, p, found := strings.Cut(pattern, " "); found {
+		pattern = p
+	}
+
+	// patterns with a host start with it.
+	pattern = pattern[strings.Index(pattern, "/"):]
+
+	psegs := strings.Split(pattern, "/")
+	segs := strings.Split(r.URL.EscapedPath(), "/")
+
+	values := map[string]string{}
+	for i, seg := range psegs {
+		if i >= len(segs) || !strings.HasPrefix(seg, "{") || seg == "{$}" {
+			continue
+		}
+
+		value := segs[i]
+		if strings.HasSuffix(seg, "...}") {
+			value = strings.Join(segs[i:], "/")
+		}
+
+		if v, err := url.PathUnescape(value); err == nil {
+			value = v
+		}
+
+		values[strings.TrimSuffix(strings.Trim(seg, "{}"), "...")] = value
+	}
+
+	return func(name string) string {
+		return values[name]
+	}
+}
@@ -0,0 +1,87 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestConstraints(t *testing.T) {
+	write := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + r.PathValue("id") + r.PathValue("slug") + r.PathValue("code")))
+		}
+	}
+
+	s := server.New(server.WithErrorHandler(http.StatusNotFound, func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}))
+
+	s.HandleFunc("GET /users/{id:int}", write("id"))
+	s.HandleFunc("GET /users/{slug}", write("slug"))
+	s.HandleFunc("GET /orders/{code:[A-Z]{3}-[0-9]+}", write("code"))
+	s.HandleFunc("POST /orders/{id:int}", write("id"))
+
+	cases := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/users/42", http.StatusOK, "id 42"},
+		{http.MethodGet, "/users/avatar.png", http.StatusOK, "slug avatar.png"},
+		{http.MethodGet, "/orders/ABC-12", http.StatusOK, "code ABC-12"},
+		{http.MethodGet, "/orders/abc", http.StatusNotFound, "not found"},
+		{http.MethodPost, "/orders/7", http.StatusOK, "id 7"},
+		{http.MethodPost, "/orders/seven", http.StatusNotFound, "not found"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code {
+				t.Errorf("Expected status %d, got %d", c.code, res.Code)
+			}
+
+			if res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+		})
+	}
+
+	t.Run("routes", func(t *testing.T) {
+		if p := s.Routes()[0].Pattern; p != "/users/{id:int}" {
+			t.Errorf("Expected the constraint in the pattern, got %q", p)
+		}
+
+		if _, err := s.Routes()[0].URL("id", "abc"); err == nil {
+			t.Errorf("Expected an error building the URL with an invalid value")
+		}
+	})
+
+	t.Run("invalid constraint", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("GET /users/{id:[0-9}", write("id"))
+
+		if err := s.Check(); err == nil || !strings.Contains(err.Error(), `invalid constraint for parameter "id"`) {
+			t.Errorf("Expected an invalid constraint error, got %v", err)
+		}
+	})
+
+	t.Run("conflicting routes", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("GET /users/{id}", write("id"))
+		s.HandleFunc("GET /users/{name}", write("name"))
+
+		if err := s.Check(); err == nil || !strings.Contains(err.Error(), "conflicts with") {
+			t.Errorf("Expected a conflict error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures the CORS middleware.
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests,
+	// like https://example.com. Subdomains can be allowed with a wildcard
+	// like https://*.example.com and any origin with "*".
+	AllowedOrigins []string
+
+	// AllowedMethods are the methods allowed in the cross-origin
+	// requests, it defaults to GET, HEAD and POST.
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers allowed in the cross-origin
+	// requests, "*" allows any of them. It defaults to Accept,
+	// Content-Type and X-Requested-With.
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers the browser exposes.
+	ExposedHeaders []string
+
+	// AllowCredentials allows the requests with cookies
+	// or authorization, it can't be used with any origin.
+	AllowCredentials bool
+
+	// MaxAge is how long the browser can cache the preflight response.
+	MaxAge time.Duration
+}
+
+// CORS returns a middleware that sets the CORS headers for the requests
+// from the allowed origins and answers their preflight requests with a 204.
+// It can be used for the whole server with Use or for the routes of a group,
+// preflight requests are served with the middleware of the route for the
+// requested method. It panics when the options are invalid, like allowing
+// credentials for any origin.
+func CORS(options CORSOptions) Middleware {
+	c, err := newCORS(options)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			h := w.Header()
+			if !c.any || c.AllowCredentials {
+				h.Add("Vary", "Origin")
+			}
+
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if origin == "" || !c.allowed(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.Set("Access-Control-Allow-Origin", origin)
+			if c.any && !c.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			}
+
+			if c.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if len(c.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			method := r.Header.Get("Access-Control-Request-Method")
+			headers := r.Header.Get("Access-Control-Request-Headers")
+			if !slices.Contains(c.AllowedMethods, method) || !c.allowedHeaders(headers) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			h.Set("Access-Control-Allow-Methods", strings.Join(c.AllowedMethods, ", "))
+			if headers != "" {
+				h.Set("Access-Control-Allow-Headers", headers)
+			}
+
+			if c.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// cors is the validated configuration of the CORS middleware.
+type cors struct {
+	CORSOptions
+
+	// any is set when all the origins are allowed.
+	any bool
+
+	// anyHeader is set when all the request headers are allowed.
+	anyHeader bool
+}
+
+// newCORS validates the options and sets their defaults.
+func newCORS(options CORSOptions) (*cors, error) {
+	c := &cors{CORSOptions: options}
+	if len(c.AllowedOrigins) == 0 {
+		return nil, errors.New("cors: no allowed origins")
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			c.any = true
+			continue
+		}
+
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok || scheme == "" || host == "" || strings.Contains(strings.TrimPrefix(host, "*."), "*") || strings.Contains(host, "/") {
+			return nil, fmt.Errorf("cors: invalid origin %q", origin)
+		}
+	}
+
+	if c.any && c.AllowCredentials {
+		return nil, errors.New(`cors: credentials can't be allowed for any origin "*"`)
+	}
+
+	if len(c.AllowedMethods) == 0 {
+		c.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+
+	c.AllowedMethods = slices.Clone(c.AllowedMethods)
+	for i, method := range c.AllowedMethods {
+		c.AllowedMethods[i] = strings.ToUpper(method)
+	}
+
+	if err := checkMethods(c.AllowedMethods); err != nil {
+		return nil, fmt.Errorf("cors: %w", err)
+	}
+
+	if len(c.AllowedHeaders) == 0 {
+		c.AllowedHeaders = []string{"Accept", "Content-Type", "X-Requested-With"}
+	}
+
+	c.anyHeader = slices.Contains(c.AllowedHeaders, "*")
+
+	return c, nil
+}
+
+// allowed returns whether the origin can make cross-origin requests.
+func (c *cors) allowed(origin string) bool {
+	if c.any {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	for _, allowed := range c.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		scheme, host, _ := strings.Cut(allowed, "://")
+		suffix, ok := strings.CutPrefix(host, "*")
+		if ok && strings.EqualFold(scheme, u.Scheme) && len(u.Host) > len(suffix) && strings.HasSuffix(strings.ToLower(u.Host), strings.ToLower(suffix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowedHeaders returns whether all the headers of the
+// comma separated list are allowed.
+func (c *cors) allowedHeaders(headers string) bool {
+	if c.anyHeader {
+		return true
+	}
+
+	for _, header := range strings.Split(headers, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+
+		if !slices.ContainsFunc(c.AllowedHeaders, func(h string) bool { return strings.EqualFold(h, header) }) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,179 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestCORS(t *testing.T) {
+	options := server.CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+
+	s := server.New()
+	s.HandleFunc("GET /about", ok)
+	s.Group("/api", func(r server.Router) {
+		r.Use(server.CORS(options))
+		r.HandleFunc("GET /users/{id}", ok)
+		r.HandleFunc("DELETE /users/{id}", ok)
+	})
+
+	serve := func(method, path string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("request from an allowed origin", func(t *testing.T) {
+		res := serve(http.MethodGet, "/api/users/1", "Origin", "https://app.example.com")
+		if res.Body.String() != "ok" {
+			t.Fatalf("Expected the handler to run, got %q", res.Body.String())
+		}
+
+		for header, value := range map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Expose-Headers":    "X-Total",
+			"Vary":                             "Origin",
+		} {
+			if got := res.Header().Get(header); got != value {
+				t.Errorf("Expected %s %q, got %q", header, value, got)
+			}
+		}
+	})
+
+	t.Run("request from a subdomain of a wildcard origin", func(t *testing.T) {
+		res := serve(http.MethodGet, "/api/users/1", "Origin", "https://shop.example.org")
+		if got := res.Header().Get("Access-Control-Allow-Origin"); got != "https://shop.example.org" {
+			t.Errorf("Expected the origin to be allowed, got %q", got)
+		}
+
+		res = serve(http.MethodGet, "/api/users/1", "Origin", "https://example.org")
+		if got := res.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected the bare domain not to be allowed, got %q", got)
+		}
+	})
+
+	t.Run("request from another origin", func(t *testing.T) {
+		res := serve(http.MethodGet, "/api/users/1", "Origin", "https://evil.com")
+		if res.Body.String() != "ok" || res.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Expected the handler to run without CORS headers, got %q %v", res.Body.String(), res.Header())
+		}
+
+		if res.Header().Get("Vary") != "Origin" {
+			t.Errorf("Expected Vary Origin, got %q", res.Header().Get("Vary"))
+		}
+	})
+
+	t.Run("preflight in a group", func(t *testing.T) {
+		res := serve(http.MethodOptions, "/api/users/1",
+			"Origin", "https://app.example.com",
+			"Access-Control-Request-Method", "DELETE",
+			"Access-Control-Request-Headers", "authorization",
+		)
+
+		if res.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", res.Code)
+		}
+
+		for header, value := range map[string]string{
+			"Access-Control-Allow-Origin":  "https://app.example.com",
+			"Access-Control-Allow-Methods": "GET, POST, DELETE",
+			"Access-Control-Allow-Headers": "authorization",
+			"Access-Control-Max-Age":       "3600",
+		} {
+			if got := res.Header().Get(header); got != value {
+				t.Errorf("Expected %s %q, got %q", header, value, got)
+			}
+		}
+
+		if vary := strings.Join(res.Header().Values("Vary"), ", "); !strings.Contains(vary, "Access-Control-Request-Method") {
+			t.Errorf("Expected Vary to list the preflight headers, got %q", vary)
+		}
+	})
+
+	t.Run("preflight with a header that is not allowed", func(t *testing.T) {
+		res := serve(http.MethodOptions, "/api/users/1",
+			"Origin", "https://app.example.com",
+			"Access-Control-Request-Method", "GET",
+			"Access-Control-Request-Headers", "X-Secret",
+		)
+
+		if res.Code != http.StatusNoContent || res.Header().Get("Access-Control-Allow-Methods") != "" {
+			t.Errorf("Expected the preflight to be rejected, got %d %v", res.Code, res.Header())
+		}
+	})
+
+	t.Run("routes outside the group", func(t *testing.T) {
+		res := serve(http.MethodGet, "/about", "Origin", "https://app.example.com")
+		if res.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Expected no CORS headers, got %v", res.Header())
+		}
+
+		res = serve(http.MethodOptions, "/about",
+			"Origin", "https://app.example.com",
+			"Access-Control-Request-Method", "GET",
+		)
+
+		if res.Code != http.StatusMethodNotAllowed || res.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Expected a 405 without CORS headers, got %d %v", res.Code, res.Header())
+		}
+	})
+
+	t.Run("used for the whole server", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.CORS(server.CORSOptions{AllowedOrigins: []string{"*"}}))
+		s.HandleFunc("POST /messages", ok)
+
+		req := httptest.NewRequest(http.MethodOptions, "/messages", nil)
+		req.Header.Set("Origin", "https://any.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusNoContent || res.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("Expected a 204 for any origin, got %d %v", res.Code, res.Header())
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for name, options := range map[string]server.CORSOptions{
+			"credentials with any origin": {AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			"no origins":                  {},
+			"origin without scheme":       {AllowedOrigins: []string{"example.com"}},
+			"wildcard in the middle":      {AllowedOrigins: []string{"https://api.*.example.com"}},
+			"invalid method":              {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GE T"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				defer func() {
+					if recover() == nil {
+						t.Error("Expected CORS to panic")
+					}
+				}()
+
+				server.CORS(options)
+			})
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/leapkit/leapkit/core/server/internal/response"
+)
+
+// cspNonceKey is the context key for the nonce of the CSP middleware.
+const cspNonceKey contextKey = "cspNonce"
+
+// CSPOption allows to configure the CSP middleware.
+type CSPOption func(*csp)
+
+// WithCSPMerge adds the nonce to the Content-Security-Policy header the
+// handlers set, which is left as it is by default.
+func WithCSPMerge() CSPOption {
+	return func(c *csp) {
+		c.merge = true
+	}
+}
+
+// csp is the configuration of the CSP middleware.
+type csp struct {
+	merge bool
+}
+
+// CSP returns a middleware that sends the policy in the
+// Content-Security-Policy header with a random nonce for each request in
+// its script-src directive, so the inline scripts stamped with the nonce
+// are the only ones that run. When the policy has no script-src the nonce
+// is added to the sources of its default-src. The nonce is available to
+// the handlers with CSPNonce and to the templates as cspNonce. Policies
+// set by the handlers are not changed unless WithCSPMerge is passed.
+func CSP(policy string, options ...CSPOption) Middleware {
+	c := &csp{}
+	for _, option := range options {
+		option(c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce := newNonce()
+			r = r.WithContext(context.WithValue(r.Context(), cspNonceKey, nonce))
+			if vlr, ok := r.Context().Value("valuer").(interface{ Set(string, any) }); ok {
+				vlr.Set("cspNonce", nonce)
+			}
+
+			header := func(h http.Header) {
+				switch current := h.Get("Content-Security-Policy"); {
+				case current == "":
+					h.Set("Content-Security-Policy", withNonce(policy, nonce))
+				case c.merge:
+					h.Set("Content-Security-Policy", withNonce(current, nonce))
+				}
+			}
+
+			// the header is set right before it's sent to
+			// know whether the handler has set its own.
+			rw := response.Root(w)
+			if rw == nil {
+				header(w.Header())
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rw.HeaderHooks = append(rw.HeaderHooks, header)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CSPNonce returns the nonce of the CSP middleware for the
+// request, empty when the middleware wasn't used.
+func CSPNonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(cspNonceKey).(string)
+	return nonce
+}
+
+// newNonce returns a random nonce of 128 bits encoded in base64.
+func newNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// withNonce returns the policy with the nonce added to its script-src
+// directive, or to a script-src with the sources of default-src when it
+// doesn't have one.
+func withNonce(policy, nonce string) string {
+	source := "'nonce-" + nonce + "'"
+
+	var directives []string
+	script, fallback := -1, "script-src"
+	for _, d := range strings.Split(policy, ";") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+
+		name, sources, _ := strings.Cut(d, " ")
+		switch strings.ToLower(name) {
+		case "script-src":
+			script = len(directives)
+		case "default-src":
+			fallback = strings.TrimSpace("script-src " + sources)
+		}
+
+		directives = append(directives, d)
+	}
+
+	if script < 0 {
+		return strings.Join(append(directives, fallback+" "+source), "; ")
+	}
+
+	directives[script] += " " + source
+
+	return strings.Join(directives, "; ")
+}
@@ -0,0 +1,90 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestCSP(t *testing.T) {
+	page := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(server.CSPNonce(r)))
+	}
+
+	own := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "script-src 'self'")
+		w.Write([]byte(server.CSPNonce(r)))
+	}
+
+	serve := func(mw server.Middleware, handler http.HandlerFunc) (string, string) {
+		s := server.New()
+		s.Use(mw)
+		s.HandleFunc("GET /{$}", handler)
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		return res.Header().Get("Content-Security-Policy"), res.Body.String()
+	}
+
+	t.Run("nonce in script-src", func(t *testing.T) {
+		mw := server.CSP("default-src 'self'; script-src 'self' 'strict-dynamic'; object-src 'none'")
+
+		policy, nonce := serve(mw, page)
+		if len(nonce) != 24 {
+			t.Fatalf("Expected a base64 nonce of 16 bytes, got %q", nonce)
+		}
+
+		expected := "default-src 'self'; script-src 'self' 'strict-dynamic' 'nonce-" + nonce + "'; object-src 'none'"
+		if policy != expected {
+			t.Errorf("Expected policy %q, got %q", expected, policy)
+		}
+
+		if _, again := serve(mw, page); again == nonce {
+			t.Error("Expected a different nonce for each request")
+		}
+	})
+
+	t.Run("without script-src", func(t *testing.T) {
+		policy, nonce := serve(server.CSP("default-src 'self' https://cdn.example.com;"), page)
+		expected := "default-src 'self' https://cdn.example.com; script-src 'self' https://cdn.example.com 'nonce-" + nonce + "'"
+		if policy != expected {
+			t.Errorf("Expected policy %q, got %q", expected, policy)
+		}
+
+		policy, nonce = serve(server.CSP("img-src *"), page)
+		if policy != "img-src *; script-src 'nonce-"+nonce+"'" {
+			t.Errorf("Expected a script-src with the nonce, got %q", policy)
+		}
+	})
+
+	t.Run("policies set by handlers", func(t *testing.T) {
+		if policy, _ := serve(server.CSP("default-src 'self'"), own); policy != "script-src 'self'" {
+			t.Errorf("Expected the policy of the handler, got %q", policy)
+		}
+
+		policy, nonce := serve(server.CSP("default-src 'self'", server.WithCSPMerge()), own)
+		if policy != "script-src 'self' 'nonce-"+nonce+"'" {
+			t.Errorf("Expected the nonce merged in the policy of the handler, got %q", policy)
+		}
+	})
+
+	t.Run("templates", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.CSP("script-src 'self'"))
+		s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			vlr := r.Context().Value("valuer").(interface{ Value(string) any })
+			if vlr.Value("cspNonce") != server.CSPNonce(r) {
+				t.Error("Expected the nonce in the values of the templates")
+			}
+		})
+
+		s.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+
+	if server.CSPNonce(httptest.NewRequest(http.MethodGet, "/", nil)) != "" {
+		t.Error("Expected no nonce without the middleware")
+	}
+}
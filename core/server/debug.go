@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// DebugOption allows to configure the Debug middleware.
+type DebugOption func(*dumper)
+
+// WithDebugEnabled enables the Debug middleware outside
+// of development, like to debug a staging server.
+func WithDebugEnabled() DebugOption {
+	return func(d *dumper) {
+		d.enabled = true
+	}
+}
+
+// WithDebugMaxBody sets how many bytes of the bodies are logged,
+// the rest is left out. It defaults to 4KB.
+func WithDebugMaxBody(size int) DebugOption {
+	return func(d *dumper) {
+		d.maxBody = size
+	}
+}
+
+// WithDebugUnredacted logs the sensitive headers, like Authorization
+// and Cookie, which are redacted by default.
+func WithDebugUnredacted() DebugOption {
+	return func(d *dumper) {
+		d.redacted = nil
+	}
+}
+
+// dumper is the configuration of the Debug middleware.
+type dumper struct {
+	enabled  bool
+	maxBody  int
+	redacted []string
+}
+
+// Debug returns a middleware that logs the requests, with their headers
+// and body, and the responses, with their status, headers and body, to
+// see what a form or a webhook sent and what was answered. The bodies are
+// logged up to a size, and binary ones are summarized with their size and
+// type. The request body is restored so the handler can read it. The
+// Authorization, Proxy-Authorization, Cookie and Set-Cookie headers are
+// redacted unless WithDebugUnredacted is passed. It only logs when GO_ENV
+// is development, or when WithDebugEnabled is passed.
+func Debug(options ...DebugOption) Middleware {
+	d := &dumper{
+		enabled:  os.Getenv("GO_ENV") == "development",
+		maxBody:  4 << 10,
+		redacted: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+	}
+
+	for _, option := range options {
+		option(d)
+	}
+
+	return func(next http.Handler) http.Handler {
+		if !d.enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				// the body is read up to the limit and put back in
+				// front of the rest, which the handler reads as is.
+				body, _ = io.ReadAll(io.LimitReader(r.Body, int64(d.maxBody)+1))
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			}
+
+			req := fmt.Sprintf("%s %s %s\nHost: %s\n", r.Method, r.URL.RequestURI(), r.Proto, r.Host)
+			Logger(r).Info("debug request", "dump", req+d.headers(r.Header)+"\n"+d.body(body, r.Header.Get("Content-Type")))
+
+			dw := &recorder{ResponseWriter: w, max: d.maxBody}
+			defer func() {
+				// the headers of the responses the handler didn't write.
+				if dw.header == nil {
+					dw.header = w.Header().Clone()
+				}
+
+				status := cmp.Or(dw.status, http.StatusOK)
+				res := fmt.Sprintf("%d %s\n", status, http.StatusText(status))
+				Logger(r).Info("debug response", "dump", res+d.headers(dw.header)+"\n"+d.body(dw.body.Bytes(), dw.header.Get("Content-Type")))
+			}()
+
+			next.ServeHTTP(dw, r)
+		})
+	}
+}
+
+// headers returns the headers sorted by name, one per line,
+// with the values of the redacted ones replaced.
+func (d *dumper) headers(h http.Header) string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		for _, value := range h[name] {
+			if slices.Contains(d.redacted, name) {
+				value = "[REDACTED]"
+			}
+
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+
+	return b.String()
+}
+
+// body returns the body as text up to the limit,
+// or a summary of it when it's binary.
+func (d *dumper) body(b []byte, contentType string) string {
+	if len(b) == 0 {
+		return ""
+	}
+
+	if !textual(b, contentType) {
+		return fmt.Sprintf("[binary body of %s]", cmp.Or(contentType, http.DetectContentType(b)))
+	}
+
+	if len(b) > d.maxBody {
+		return string(b[:d.maxBody]) + "... (truncated)"
+	}
+
+	return string(b)
+}
+
+// textual returns whether the body is text, judging by its
+// content type and, when it doesn't have one, by its content.
+func textual(b []byte, contentType string) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(b)
+	}
+
+	contentType = strings.ToLower(contentType)
+	for _, t := range []string{"text/", "json", "xml", "x-www-form-urlencoded", "javascript", "multipart/form-data"} {
+		if strings.Contains(contentType, t) {
+			// multipart bodies can have files in them.
+			return t != "multipart/form-data" || utf8.Valid(b)
+		}
+	}
+
+	return false
+}
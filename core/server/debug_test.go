@@ -0,0 +1,117 @@
+package server_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestDebug(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	newServer := func(options ...server.DebugOption) http.Handler {
+		s := server.New(server.WithLogger(logger))
+		s.Use(server.Debug(options...))
+		s.HandleFunc("POST /hooks", func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Set-Cookie", "token=secret")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("received " + string(body)))
+		})
+
+		return s.Handler()
+	}
+
+	serve := func(h http.Handler, body, contentType string) *httptest.ResponseRecorder {
+		logs.Reset()
+
+		req := httptest.NewRequest(http.MethodPost, "/hooks?source=stripe", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("development", func(t *testing.T) {
+		t.Setenv("GO_ENV", "development")
+
+		res := serve(newServer(), `{"event":"paid"}`, "application/json")
+		if res.Body.String() != `received {"event":"paid"}` {
+			t.Errorf("Expected the handler to read the body, got %q", res.Body.String())
+		}
+
+		expected := []string{
+			"POST /hooks?source=stripe HTTP/1.1",
+			`Content-Type: application/json`,
+			`{\"event\":\"paid\"}`,
+			"Authorization: [REDACTED]",
+			"202 Accepted",
+			"Set-Cookie: [REDACTED]",
+			`received {\"event\":\"paid\"}`,
+		}
+
+		for _, exp := range expected {
+			if !strings.Contains(logs.String(), exp) {
+				t.Errorf("Expected %q in the logs, got %q", exp, logs.String())
+			}
+		}
+
+		if strings.Contains(logs.String(), "secret") {
+			t.Errorf("Expected the sensitive headers to be redacted, got %q", logs.String())
+		}
+	})
+
+	t.Run("large and binary bodies", func(t *testing.T) {
+		t.Setenv("GO_ENV", "development")
+		h := newServer(server.WithDebugMaxBody(8))
+
+		res := serve(h, "name=Jane&email=jane@example.com", "application/x-www-form-urlencoded")
+		if res.Body.String() != "received name=Jane&email=jane@example.com" {
+			t.Errorf("Expected the handler to read the whole body, got %q", res.Body.String())
+		}
+
+		if !strings.Contains(logs.String(), "name=Jan... (truncated)") {
+			t.Errorf("Expected the body to be truncated, got %q", logs.String())
+		}
+
+		serve(h, "\x89PNG\r\n\x1a\n\x00\x00", "image/png")
+		if !strings.Contains(logs.String(), "[binary body of image/png]") {
+			t.Errorf("Expected the binary body to be summarized, got %q", logs.String())
+		}
+	})
+
+	t.Run("unredacted", func(t *testing.T) {
+		t.Setenv("GO_ENV", "development")
+
+		serve(newServer(server.WithDebugUnredacted()), "", "text/plain")
+		if !strings.Contains(logs.String(), "Authorization: Bearer secret") {
+			t.Errorf("Expected the headers as they are, got %q", logs.String())
+		}
+	})
+
+	t.Run("production", func(t *testing.T) {
+		t.Setenv("GO_ENV", "production")
+
+		serve(newServer(), "ping", "text/plain")
+		if strings.Contains(logs.String(), "debug request") {
+			t.Errorf("Expected no dumps, got %q", logs.String())
+		}
+
+		serve(newServer(server.WithDebugEnabled()), "ping", "text/plain")
+		if !strings.Contains(logs.String(), "debug request") {
+			t.Errorf("Expected the dumps when enabled, got %q", logs.String())
+		}
+	})
+}
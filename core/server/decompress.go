@@ -0,0 +1,150 @@
+package server
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DecompressOption allows to configure the Decompress middleware.
+type DecompressOption func(*decompress)
+
+// WithDecompressMaxSize sets the most bytes the decompressed body of a
+// request can take, reading more fails with an *http.MaxBytesError so
+// small payloads that expand to huge bodies are stopped. It defaults to
+// 10MB.
+func WithDecompressMaxSize(size int64) DecompressOption {
+	return func(d *decompress) {
+		d.maxSize = size
+	}
+}
+
+// WithDecompressDecoder adds an encoding, like zstd with a zstd reader,
+// to the ones the request bodies can be sent with.
+func WithDecompressDecoder(encoding string, fn func(r io.Reader) (io.ReadCloser, error)) DecompressOption {
+	return func(d *decompress) {
+		d.decoders = append(d.decoders, decoder{name: strings.ToLower(encoding), new: fn})
+	}
+}
+
+// decompress is the configuration of the Decompress middleware.
+type decompress struct {
+	maxSize  int64
+	decoders []decoder
+}
+
+// decoder reads the request bodies sent with an encoding.
+type decoder struct {
+	name string
+	new  func(r io.Reader) (io.ReadCloser, error)
+}
+
+// Decompress returns a middleware that decompresses the bodies of the
+// requests sent with a gzip or deflate Content-Encoding, or the encodings
+// added with WithDecompressDecoder, so handlers read them as if they were
+// sent as is. The Content-Encoding and Content-Length headers are removed,
+// and the decompressed body is limited to the maximum size. Requests with
+// other encodings get a 415 with the accepted ones in an Accept-Encoding
+// header, and the ones whose body can't be decompressed a 400, both
+// through the error handlers.
+func Decompress(options ...DecompressOption) Middleware {
+	d := &decompress{maxSize: 10 << 20}
+	d.decoders = []decoder{
+		{name: "gzip", new: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }},
+		{name: "x-gzip", new: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }},
+		{name: "deflate", new: zlib.NewReader},
+	}
+
+	for _, option := range options {
+		option(d)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encodings := r.Header.Values("Content-Encoding")
+			if len(encodings) == 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body := &decompressedBody{ReadCloser: r.Body}
+
+			// the encodings are listed in the order they were applied.
+			list := strings.Split(strings.Join(encodings, ","), ",")
+			for i := len(list) - 1; i >= 0; i-- {
+				name := strings.ToLower(strings.TrimSpace(list[i]))
+				if name == "" || name == "identity" {
+					continue
+				}
+
+				j := slices.IndexFunc(d.decoders, func(dec decoder) bool { return dec.name == name })
+				if j < 0 {
+					body.Close()
+					w.Header().Set("Accept-Encoding", d.accepted())
+					Error(w, fmt.Errorf("415 unsupported content encoding %q", name), http.StatusUnsupportedMediaType)
+
+					return
+				}
+
+				rc, err := d.decoders[j].new(body.reader())
+				if err != nil {
+					body.Close()
+					Error(w, fmt.Errorf("400 invalid %s request body: %w", name, err), http.StatusBadRequest)
+
+					return
+				}
+
+				body.decoders = append(body.decoders, rc)
+			}
+
+			r.Body = http.MaxBytesReader(w, body, d.maxSize)
+			r.ContentLength = -1
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// accepted returns the encodings accepted for the request bodies.
+func (d *decompress) accepted() string {
+	names := make([]string, 0, len(d.decoders))
+	for _, dec := range d.decoders {
+		names = append(names, dec.name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// decompressedBody reads the body of a request through its
+// decoders, closing them with the original body.
+type decompressedBody struct {
+	io.ReadCloser
+	decoders []io.ReadCloser
+}
+
+// reader returns the reader of the body through the decoders added so far.
+func (db *decompressedBody) reader() io.Reader {
+	if len(db.decoders) == 0 {
+		return db.ReadCloser
+	}
+
+	return db.decoders[len(db.decoders)-1]
+}
+
+func (db *decompressedBody) Read(p []byte) (int, error) {
+	return db.reader().Read(p)
+}
+
+func (db *decompressedBody) Close() error {
+	for _, dec := range db.decoders {
+		dec.Close()
+	}
+
+	return db.ReadCloser.Close()
+}
@@ -0,0 +1,121 @@
+package server_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestDecompress(t *testing.T) {
+	s := server.New()
+	s.Use(server.Decompress(
+		server.WithDecompressMaxSize(64),
+		server.WithDecompressDecoder("base64", func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(r), nil
+		}),
+	))
+
+	s.HandleFunc("POST /echo", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			server.Error(w, err, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("X-Content-Encoding", r.Header.Get("Content-Encoding"))
+		w.Write(body)
+	})
+
+	h := s.Handler()
+	serve := func(body []byte, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader(body))
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		gw.Write([]byte(s))
+		gw.Close()
+
+		return buf.Bytes()
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		res := serve(gzipped(`{"name":"leapkit"}`), "gzip")
+		if res.Code != http.StatusOK || res.Body.String() != `{"name":"leapkit"}` {
+			t.Errorf("Expected the decompressed body, got %d %q", res.Code, res.Body.String())
+		}
+
+		if res.Header().Get("X-Content-Encoding") != "" {
+			t.Error("Expected the Content-Encoding to be removed")
+		}
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write([]byte("deflated"))
+		zw.Close()
+
+		if res := serve(buf.Bytes(), "deflate"); res.Body.String() != "deflated" {
+			t.Errorf("Expected the decompressed body, got %d %q", res.Code, res.Body.String())
+		}
+	})
+
+	t.Run("plain and identity", func(t *testing.T) {
+		for _, encoding := range []string{"", "identity"} {
+			if res := serve([]byte("plain"), encoding); res.Body.String() != "plain" {
+				t.Errorf("Expected the body as is for %q, got %q", encoding, res.Body.String())
+			}
+		}
+	})
+
+	t.Run("chained", func(t *testing.T) {
+		if res := serve(gzipped("twice"), "base64, gzip"); res.Body.String() != "twice" {
+			t.Errorf("Expected the body decoded in reverse order, got %d %q", res.Code, res.Body.String())
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		res := serve([]byte("data"), "br")
+		if res.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("Expected 415, got %d", res.Code)
+		}
+
+		if res.Header().Get("Accept-Encoding") != "gzip, x-gzip, deflate, base64" {
+			t.Errorf("Expected the accepted encodings, got %q", res.Header().Get("Accept-Encoding"))
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if res := serve([]byte("not gzip"), "gzip"); res.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", res.Code)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		body := gzipped(strings.Repeat("a", 1000))
+		if len(body) > 64 {
+			t.Fatalf("Expected the compressed body under the limit, got %d bytes", len(body))
+		}
+
+		if res := serve(body, "gzip"); res.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413, got %d", res.Code)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/leapkit/leapkit/core/server/internal/response"
+)
+
+// devNoCache returns the middleware the server uses in development so
+// the browsers don't cache the pages and assets while they're being
+// changed, it serves the requests as they are when dev is false. It's
+// named devNoCache so groups can skip it to try their caching.
+func devNoCache(dev bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		if !dev {
+			return next
+		}
+
+		return noCache(next)
+	}
+}
+
+// noCache makes the responses not to be cached.
+func noCache(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// without the validators the handlers, like the file
+		// server, send the full response instead of a 304.
+		r.Header.Del("If-None-Match")
+		r.Header.Del("If-Modified-Since")
+
+		headers := func(h http.Header) {
+			h.Set("Cache-Control", "no-store")
+			h.Set("X-Leapkit-Dev", "1")
+			h.Del("ETag")
+			h.Del("Last-Modified")
+		}
+
+		// the hook runs after the ones of the middleware that come
+		// later, like CacheControl, so the header is always replaced.
+		rw := response.Root(w)
+		if rw == nil {
+			headers(w.Header())
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw.HeaderHooks = append(rw.HeaderHooks, headers)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,79 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestDevNoCache(t *testing.T) {
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	page := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "app.css", modified, strings.NewReader("body{}"))
+	}
+
+	newServer := func() http.Handler {
+		s := server.New()
+		s.HandleFunc("GET /app.css", page)
+		s.Group("/cached/", func(r server.Router) {
+			r.Skip("devNoCache")
+			r.Use(server.CacheControl("public, max-age=60"))
+			r.HandleFunc("GET /app.css", page)
+		})
+
+		return s.Handler()
+	}
+
+	serve := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("If-None-Match", `"v1"`)
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("development", func(t *testing.T) {
+		t.Setenv("GO_ENV", "development")
+		h := newServer()
+
+		res := serve(h, "/app.css")
+		if res.Code != http.StatusOK || res.Body.String() != "body{}" {
+			t.Errorf("Expected the full response, got %d %q", res.Code, res.Body.String())
+		}
+
+		expected := map[string]string{
+			"Cache-Control": "no-store",
+			"X-Leapkit-Dev": "1",
+			"ETag":          "",
+			"Last-Modified": "",
+		}
+
+		for name, value := range expected {
+			if res.Header().Get(name) != value {
+				t.Errorf("Expected %s to be %q, got %q", name, value, res.Header().Get(name))
+			}
+		}
+
+		res = serve(h, "/cached/app.css")
+		if res.Code != http.StatusNotModified || res.Header().Get("Cache-Control") != "public, max-age=60" {
+			t.Errorf("Expected the group to keep caching, got %d %q", res.Code, res.Header().Get("Cache-Control"))
+		}
+	})
+
+	t.Run("production", func(t *testing.T) {
+		t.Setenv("GO_ENV", "production")
+		h := newServer()
+
+		res := serve(h, "/app.css")
+		if res.Code != http.StatusNotModified || res.Header().Get("X-Leapkit-Dev") != "" {
+			t.Errorf("Expected the middleware to be disabled, got %d %v", res.Code, res.Header())
+		}
+	})
+}
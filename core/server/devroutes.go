@@ -0,0 +1,104 @@
+package server
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+//go:embed devroutes.html
+var devRoutesHTML string
+
+// devRoutesTemplate renders the table of routes served by WithDevRoutes.
+var devRoutesTemplate = template.Must(template.New("devroutes.html").Parse(devRoutesHTML))
+
+// devRoute is a registered route with the prefix of the group
+// it was registered in and the names of its middleware.
+type devRoute struct {
+	Method     string   `json:"method"`
+	Pattern    string   `json:"pattern"`
+	Host       string   `json:"host,omitempty"`
+	Group      string   `json:"group"`
+	Handler    string   `json:"handler"`
+	Middleware []string `json:"middleware"`
+	Source     string   `json:"source"`
+}
+
+// WithDevRoutes serves the table of the registered routes, with their
+// group and middleware, at /_leapkit/routes when running in development.
+// It responds with JSON when the request accepts it or has ?format=json.
+func WithDevRoutes() Option {
+	return func(m *mux) {
+		if cmp.Or(os.Getenv("GO_ENV"), "development") != "development" {
+			return
+		}
+
+		m.HandleFunc("GET /_leapkit/routes", func(w http.ResponseWriter, r *http.Request) {
+			routes := m.devRoutes()
+			if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(routes)
+
+				return
+			}
+
+			data := struct {
+				Routes   []devRoute
+				Features []devFeature
+			}{routes, m.devFeatures()}
+
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := devRoutesTemplate.Execute(w, data); err != nil {
+				Error(w, err, http.StatusInternalServerError)
+			}
+		})
+	}
+}
+
+// devRoutes returns the registered routes in the order they were registered.
+func (rg *router) devRoutes() []devRoute {
+	t := rg.current()
+	routes := make([]devRoute, 0, len(t.refs))
+	for _, ref := range t.refs {
+		route := t.routes[ref.index]
+
+		middleware := []string{}
+		for _, mw := range ref.middleware {
+			middleware = append(middleware, middlewareName(mw))
+		}
+
+		routes = append(routes, devRoute{
+			Method:     route.Method,
+			Pattern:    route.Pattern,
+			Host:       route.Host,
+			Group:      cmp.Or(ref.group, "/"),
+			Handler:    route.Handler,
+			Middleware: middleware,
+			Source:     route.Source,
+		})
+	}
+
+	return routes
+}
+
+// closureSuffix matches the suffix of the names of the
+// functions returned by a middleware constructor.
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// middlewareName returns the name of the function of the middleware,
+// for closures it returns the name of the function that built it.
+func middlewareName(mw Middleware) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(mw).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+
+	return closureSuffix.ReplaceAllString(fn.Name(), "")
+}
@@ -0,0 +1,77 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func requireAdmin(next http.Handler) http.Handler {
+	return next
+}
+
+func TestWithDevRoutes(t *testing.T) {
+	t.Setenv("GO_ENV", "development")
+
+	s := server.New(server.WithDevRoutes())
+	s.Group("/admin/", func(r server.Router) {
+		r.HandleFunc("GET /users", listUsers)
+	}, requireAdmin)
+
+	t.Run("json", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/_leapkit/routes", nil)
+		req.Header.Set("Accept", "application/json")
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		var routes []struct {
+			Pattern    string   `json:"pattern"`
+			Group      string   `json:"group"`
+			Middleware []string `json:"middleware"`
+		}
+
+		if err := json.NewDecoder(res.Body).Decode(&routes); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(routes) < 2 || routes[1].Pattern != "/admin/users" || routes[1].Group != "/admin" {
+			t.Fatalf("Expected the /admin/users route in the /admin group, got %+v", routes)
+		}
+
+		if !slices.Contains(routes[1].Middleware, "github.com/leapkit/leapkit/core/server_test.requireAdmin") {
+			t.Errorf("Expected the middleware of the group, got %v", routes[1].Middleware)
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/_leapkit/routes", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if ct := res.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+			t.Errorf("Expected html, got %q", ct)
+		}
+
+		if !strings.Contains(res.Body.String(), "/admin/users") {
+			t.Errorf("Expected the routes in the table, got %s", res.Body.String())
+		}
+	})
+
+	t.Run("production", func(t *testing.T) {
+		t.Setenv("GO_ENV", "production")
+
+		s := server.New(server.WithDevRoutes())
+		req := httptest.NewRequest(http.MethodGet, "/_leapkit/routes", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, res.Code)
+		}
+	})
+}
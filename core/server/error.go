@@ -2,14 +2,21 @@ package server
 
 import (
 	"cmp"
+	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"html/template"
-	"log/slog"
 	"net/http"
 	"strings"
+
+	"github.com/leapkit/leapkit/core/server/internal/response"
 )
 
+// ErrorHandlerFn is a function that writes the response for an error,
+// it can be registered for an HTTP status with the WithErrorHandler option.
+type ErrorHandlerFn func(w http.ResponseWriter, r *http.Request, err error)
+
 var (
 	//go:embed error.html
 	htmlTemplate string
@@ -53,8 +60,30 @@ func errorTemplate(status int, title, description string) string {
 // Unlike http.Error, this function determines the Content-Type dynamically
 // depending on whether a message is found in the errorMessageMap for the given HTTPStatus.
 // If no error message is registered, it defaults to the error's message content type.
+// When an error handler is registered for the HTTPStatus it takes care of writing the response.
 func Error(w http.ResponseWriter, err error, HTTPStatus int) {
-	slog.Error(err.Error())
+	// bodies exceeding the limit of MaxBody are reported
+	// as such, whatever the error they caused in the handler.
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		err, HTTPStatus = tooLarge(mbe), http.StatusRequestEntityTooLarge
+	}
+
+	// there is no one to show the error to when the client went
+	// away, the logger records the request as closed by the client.
+	rw := response.Root(w)
+	if errors.Is(err, context.Canceled) && rw != nil && rw.Request != nil && errors.Is(rw.Request.Context().Err(), context.Canceled) {
+		return
+	}
+
+	writerLogger(w).Error(err.Error())
+
+	if rw != nil && rw.ErrorHandler != nil {
+		if fn := rw.ErrorHandler(HTTPStatus); fn != nil {
+			fn(w, rw.Request, err)
+			return
+		}
+	}
 
 	content := []byte(cmp.Or(errorMessageMap[HTTPStatus], err.Error()))
 
@@ -73,3 +102,16 @@ func Error(w http.ResponseWriter, err error, HTTPStatus int) {
 func Errorf(w http.ResponseWriter, HTTPStatus int, message string, args ...any) {
 	Error(w, fmt.Errorf(message, args...), HTTPStatus)
 }
+
+// keepRequest stores the request passed to the handler in the server
+// writer so error handlers receive it with the values that the middleware
+// have set in its context.
+func keepRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rw := response.Root(w); rw != nil {
+			rw.Request = r
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -25,3 +25,44 @@ func TestErrorf(t *testing.T) {
 		t.Errorf("expected status %d; got %d", http.StatusInternalServerError, rec.Code)
 	}
 }
+
+func TestWithErrorHandler(t *testing.T) {
+	s := server.New(
+		server.WithErrorHandler(http.StatusTeapot, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte(r.Context().Value("customValue").(string) + ": " + err.Error()))
+		}),
+		server.WithErrorHandler(http.StatusNotFound, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("nothing at " + r.URL.Path))
+		}),
+	)
+
+	s.Use(server.InCtxMiddleware("customValue", "from context"))
+	s.HandleFunc("GET /teapot/{$}", func(w http.ResponseWriter, r *http.Request) {
+		server.Errorf(w, http.StatusTeapot, "short and stout")
+	})
+
+	testCases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/teapot/", http.StatusTeapot, "from context: short and stout"},
+		{"/missing", http.StatusNotFound, "nothing at /missing"},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+
+		if rec.Code != tc.code {
+			t.Errorf("expected status %d; got %d", tc.code, rec.Code)
+		}
+
+		if rec.Body.String() != tc.body {
+			t.Errorf("expected body %q; got %q", tc.body, rec.Body.String())
+		}
+	}
+}
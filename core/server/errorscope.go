@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/leapkit/leapkit/core/server/internal/response"
+)
+
+// errorScope holds the error handlers registered in a group, the
+// statuses it has no handler for are looked up in the parent group.
+type errorScope struct {
+	handlers map[int]ErrorHandlerFn
+	parent   *errorScope
+}
+
+// lookup returns the handler for the status in the
+// scope or its parents, nil when there is none.
+func (es *errorScope) lookup(status int) ErrorHandlerFn {
+	for s := es; s != nil; s = s.parent {
+		if fn, ok := s.handlers[status]; ok {
+			return fn
+		}
+	}
+
+	return nil
+}
+
+// empty returns whether neither the scope
+// nor its parents have any handler.
+func (es *errorScope) empty() bool {
+	for s := es; s != nil; s = s.parent {
+		if len(s.handlers) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ErrorHandler allows to register the function that writes the response for
+// the status in the routes of the group, like panics and server.Error calls
+// in their handlers. Groups fall back to the handlers of their parent groups
+// and then to the ones of the server. The 404 handler is also used for the
+// requests that don't match any route under the prefix, like with NotFound.
+func (rg *router) ErrorHandler(status int, fn ErrorHandlerFn) {
+	if rg.errors.handlers == nil {
+		rg.errors.handlers = map[int]ErrorHandlerFn{}
+	}
+
+	rg.errors.handlers[status] = fn
+	if status == http.StatusNotFound {
+		rg.NotFound(fn)
+	}
+}
+
+// withErrorScope makes the errors written by the handler go through the
+// handlers of the scope before the ones the response writer already has.
+func withErrorScope(w http.ResponseWriter, scope *errorScope) {
+	rw := response.Root(w)
+	if rw == nil || scope.empty() {
+		return
+	}
+
+	next := rw.ErrorHandler
+	rw.ErrorHandler = func(status int) func(http.ResponseWriter, *http.Request, error) {
+		if fn := scope.lookup(status); fn != nil {
+			return fn
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		return next(status)
+	}
+}
@@ -0,0 +1,65 @@
+package server_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestGroupErrorHandler(t *testing.T) {
+	writer := func(body string) server.ErrorHandlerFn {
+		return func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte(body + ": " + err.Error()))
+		}
+	}
+
+	failing := func(w http.ResponseWriter, r *http.Request) {
+		server.Error(w, errors.New("boom"), http.StatusInternalServerError)
+	}
+
+	s := server.New(server.WithErrorHandler(http.StatusInternalServerError, writer("server")))
+	s.Get("/web", failing)
+	s.Group("/api/", func(r server.Router) {
+		r.ErrorHandler(http.StatusInternalServerError, writer("api"))
+		r.ErrorHandler(http.StatusNotFound, writer("api not found"))
+
+		r.Get("/users", failing)
+		r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("oops")
+		})
+
+		r.Group("/v1/", func(r server.Router) {
+			r.Get("/users", failing)
+		})
+
+		r.Group("/v2/", func(r server.Router) {
+			r.ErrorHandler(http.StatusInternalServerError, writer("v2"))
+			r.Get("/users", failing)
+		})
+	})
+
+	cases := map[string]string{
+		"/web":          "server: boom",
+		"/api/users":    "api: boom",
+		"/api/panic":    "api: oops",
+		"/api/v1/users": "api: boom",
+		"/api/v2/users": "v2: boom",
+		"/api/missing":  "api not found: 404 page not found",
+	}
+
+	for path, body := range cases {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != http.StatusTeapot || res.Body.String() != body {
+				t.Errorf("Expected %q, got %d %q", body, res.Code, res.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// features are the names of the feature flags of the
+// Feature middleware created, listed by the dev routes.
+var features struct {
+	sync.Mutex
+	names []string
+}
+
+// Feature returns a middleware that serves the routes only when the feature
+// flag is on, which allows to ship them dark and enable them for some users
+// or environments. The flag is on when enabled returns true for the request,
+// or when it's nil, when the flag was enabled with EnableFeature. When it's
+// off the requests get the same 404 as the paths without routes.
+func Feature(name string, enabled func(*http.Request) bool) Middleware {
+	features.Lock()
+	if !slices.Contains(features.names, name) {
+		features.names = append(features.names, name)
+	}
+
+	features.Unlock()
+
+	if enabled == nil {
+		enabled = func(r *http.Request) bool {
+			return FeatureEnabled(r, name)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if enabled(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ref, ok := r.Context().Value(routeKey).(*RouteRef)
+			if !ok {
+				Error(w, fmt.Errorf("404 page not found"), http.StatusNotFound)
+				return
+			}
+
+			ref.registry.writeNotFound(w, r)
+		})
+	}
+}
+
+// FeatureEnabled returns whether the feature flag was enabled with
+// EnableFeature in the server serving the request, custom predicates
+// of Feature can use it to combine the flag with their own checks.
+func FeatureEnabled(r *http.Request, name string) bool {
+	ref, ok := r.Context().Value(routeKey).(*RouteRef)
+	if !ok {
+		return false
+	}
+
+	on, _ := ref.registry.features.Load(name)
+	return on == true
+}
+
+// EnableFeature turns on the feature flag for the Feature middleware
+// without a predicate, it's safe to call it while the server is serving.
+func (s *mux) EnableFeature(name string) {
+	s.features.Store(name, true)
+}
+
+// DisableFeature turns off the feature flag for the Feature middleware
+// without a predicate, it's safe to call it while the server is serving.
+func (s *mux) DisableFeature(name string) {
+	s.features.Store(name, false)
+}
+
+// devFeature is a feature flag listed by the dev routes.
+type devFeature struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// devFeatures returns the feature flags of the Feature middleware and
+// the ones set in the server, with whether they're enabled in it.
+func (rr *registry) devFeatures() []devFeature {
+	features.Lock()
+	names := slices.Clone(features.names)
+	features.Unlock()
+
+	rr.features.Range(func(key, value any) bool {
+		if name := key.(string); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+
+		return true
+	})
+
+	slices.Sort(names)
+
+	list := make([]devFeature, 0, len(names))
+	for _, name := range names {
+		on, _ := rr.features.Load(name)
+		list = append(list, devFeature{Name: name, Enabled: on == true})
+	}
+
+	return list
+}
@@ -0,0 +1,127 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestFeature(t *testing.T) {
+	t.Setenv("GO_ENV", "development")
+
+	s := server.New(
+		server.WithDevRoutes(),
+		server.WithErrorHandler(http.StatusNotFound, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("custom not found"))
+		}),
+	)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+
+	s.Group("/billing/", func(r server.Router) {
+		r.Use(server.Feature("new-billing", nil))
+		r.HandleFunc("GET /invoices", ok)
+	})
+
+	s.Group("/beta/", func(r server.Router) {
+		r.Use(server.Feature("beta", func(r *http.Request) bool {
+			return r.Header.Get("X-Beta") == "1" || server.FeatureEnabled(r, "beta")
+		}))
+
+		r.HandleFunc("GET /dashboard", ok)
+	})
+
+	h := s.Handler()
+	serve := func(path string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("off", func(t *testing.T) {
+		res := serve("/billing/invoices")
+		missing := serve("/billing/missing")
+
+		if res.Code != http.StatusNotFound || res.Body.String() != missing.Body.String() {
+			t.Errorf("Expected the same 404 as a missing route, got %d %q", res.Code, res.Body.String())
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		s.EnableFeature("new-billing")
+		if res := serve("/billing/invoices"); res.Code != http.StatusOK {
+			t.Errorf("Expected the route to be served, got %d", res.Code)
+		}
+
+		s.DisableFeature("new-billing")
+		if res := serve("/billing/invoices"); res.Code != http.StatusNotFound {
+			t.Errorf("Expected the route to be hidden again, got %d", res.Code)
+		}
+	})
+
+	t.Run("predicate", func(t *testing.T) {
+		if res := serve("/beta/dashboard"); res.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 without the header, got %d", res.Code)
+		}
+
+		if res := serve("/beta/dashboard", "X-Beta", "1"); res.Code != http.StatusOK {
+			t.Errorf("Expected the route for the beta users, got %d", res.Code)
+		}
+
+		s.EnableFeature("beta")
+		defer s.DisableFeature("beta")
+
+		if res := serve("/beta/dashboard"); res.Code != http.StatusOK {
+			t.Errorf("Expected the route for everyone, got %d", res.Code)
+		}
+	})
+
+	t.Run("flipped while serving", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if res := serve("/billing/invoices"); res.Code != http.StatusOK && res.Code != http.StatusNotFound {
+						t.Errorf("Unexpected status %d", res.Code)
+					}
+				}
+			}()
+		}
+
+		for i := 0; i < 50; i++ {
+			if i%2 == 0 {
+				s.EnableFeature("new-billing")
+			} else {
+				s.DisableFeature("new-billing")
+			}
+		}
+
+		wg.Wait()
+	})
+
+	t.Run("dev routes", func(t *testing.T) {
+		s.EnableFeature("new-billing")
+
+		body := serve("/_leapkit/routes").Body.String()
+		for _, exp := range []string{">new-billing<", ">beta<", ">true<", ">false<"} {
+			if !strings.Contains(body, exp) {
+				t.Errorf("Expected %q in the dev routes", exp)
+			}
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+// FlashMessage is a message shown once to the user, usually on the page
+// the user is redirected to after submitting a form.
+type FlashMessage = session.FlashMessage
+
+// Flash adds a message of the kind to the flash messages of the session,
+// it's saved with the session when the response is written, so it must
+// be called before writing it. It requires the session of WithSession.
+func Flash(w http.ResponseWriter, r *http.Request, kind, message string) {
+	session.Flash(r.Context(), kind, message)
+}
+
+// Flashes returns the flash messages of the session in the order they
+// were added and clears them, so they are shown only once. It requires
+// the session of WithSession.
+func Flashes(r *http.Request) []FlashMessage {
+	return session.Flashes(r.Context())
+}
@@ -0,0 +1,71 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestFlash(t *testing.T) {
+	s := server.New(server.WithSession("secret", "app"))
+	s.HandleFunc("POST /profile", func(w http.ResponseWriter, r *http.Request) {
+		server.Flash(w, r, "success", "Profile updated")
+		server.Flash(w, r, "info", "Your email must be confirmed")
+		http.Redirect(w, r, "/profile", http.StatusSeeOther)
+	})
+
+	s.HandleFunc("POST /profile/fail", func(w http.ResponseWriter, r *http.Request) {
+		server.Flash(w, r, "error", "Name can't be blank")
+		http.Redirect(w, r, "/profile", http.StatusSeeOther)
+	})
+
+	s.HandleFunc("GET /profile", func(w http.ResponseWriter, r *http.Request) {
+		for _, f := range server.Flashes(r) {
+			fmt.Fprintf(w, "%s: %s\n", f.Kind, f.Message)
+		}
+	})
+
+	h := s.Handler()
+	cookies := map[string]*http.Cookie{}
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		for _, c := range res.Result().Cookies() {
+			cookies[c.Name] = c
+		}
+
+		return res
+	}
+
+	t.Run("survive one redirect", func(t *testing.T) {
+		if res := serve(http.MethodPost, "/profile"); res.Code != http.StatusSeeOther {
+			t.Fatalf("Expected a redirect, got %d", res.Code)
+		}
+
+		expected := "success: Profile updated\ninfo: Your email must be confirmed\n"
+		if body := serve(http.MethodGet, "/profile").Body.String(); body != expected {
+			t.Errorf("Expected the flashes in order, got %q", body)
+		}
+
+		if body := serve(http.MethodGet, "/profile").Body.String(); body != "" {
+			t.Errorf("Expected the flashes to be cleared, got %q", body)
+		}
+	})
+
+	t.Run("kinds", func(t *testing.T) {
+		serve(http.MethodPost, "/profile/fail")
+		if body := serve(http.MethodGet, "/profile").Body.String(); !strings.HasPrefix(body, "error: ") {
+			t.Errorf("Expected the error flash, got %q", body)
+		}
+	})
+}
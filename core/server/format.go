@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// formatKey is the context key for the format requested
+// with the extension of the path, like json in /reports/1.json.
+const formatKey contextKey = "format"
+
+// defaultFormat is the format of the requests without extension.
+const defaultFormat = "html"
+
+// Format returns the format requested with the extension of the path for
+// routes like GET /reports/{id}.{format}. When the request has no extension
+// it returns the default of the route, which is html unless the route was
+// registered with WithDefaultFormat.
+func Format(r *http.Request) string {
+	if format, ok := r.Context().Value(formatKey).(string); ok && format != "" {
+		return format
+	}
+
+	return defaultFormat
+}
+
+// WithDefaultFormat sets the format Format returns for the
+// requests to the route that don't have an extension.
+func WithDefaultFormat(format string) RouteOption {
+	return func(rf *RouteRef) {
+		rf.middleware = append(rf.middleware, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if f, _ := r.Context().Value(formatKey).(string); f == "" {
+					r = r.WithContext(context.WithValue(r.Context(), formatKey, format))
+				}
+
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
+
+// parseFormat returns the pattern without the format wildcard of its last
+// segment, like {id}.{format}, and the wildcard. The wildcard is empty when
+// the pattern doesn't have one.
+func parseFormat(pattern string) (string, string) {
+	i := strings.LastIndex(pattern, ".{")
+	if i < 0 || !strings.HasSuffix(pattern, "}") || strings.Contains(pattern[i:], "/") || pattern[i-1] != '}' {
+		return pattern, ""
+	}
+
+	return pattern[:i], pattern[i+1:]
+}
+
+// splitFormat splits the value of the last wildcard
+// of the path in the value and the extension.
+func splitFormat(value string) (string, string) {
+	i := strings.LastIndex(value, ".")
+	if i < 0 {
+		return value, ""
+	}
+
+	return value[:i], value[i+1:]
+}
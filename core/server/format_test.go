@@ -0,0 +1,58 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestFormat(t *testing.T) {
+	s := server.New()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.PathValue("id") + " " + server.Format(r)))
+	}
+
+	s.HandleFunc("GET /reports/{id}.{format}", handler)
+	s.HandleFunc("GET /invoices/{id:int}.{format:json|csv}", handler, server.WithDefaultFormat("json"))
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/reports/7.json", http.StatusOK, "7 json"},
+		{"/reports/7.csv", http.StatusOK, "7 csv"},
+		{"/reports/7", http.StatusOK, "7 html"},
+		{"/reports/v1.2.csv", http.StatusOK, "v1.2 csv"},
+		{"/invoices/3.csv", http.StatusOK, "3 csv"},
+		{"/invoices/3", http.StatusOK, "3 json"},
+		{"/invoices/3.xml", http.StatusNotFound, ""},
+		{"/invoices/abc.json", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code {
+				t.Errorf("Expected status %d, got %d", c.code, res.Code)
+			}
+
+			if c.body != "" && res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+		})
+	}
+
+	t.Run("url", func(t *testing.T) {
+		url, err := s.Routes()[0].URL("id", "7", "format", "json")
+		if err != nil || url != "/reports/7.json" {
+			t.Errorf("Expected /reports/7.json, got %q (%v)", url, err)
+		}
+	})
+}
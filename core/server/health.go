@@ -0,0 +1,124 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// healthCheck is a check of the health endpoint, like a
+// ping to the database, registered with WithHealthCheckFunc.
+type healthCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+// healthStatus is the status of a check in the body of the health endpoint.
+type healthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// WithHealthCheck allows to register a health endpoint at the path that runs
+// the checks of WithHealthCheckFunc concurrently and responds 200 with the
+// status of each of them in a JSON body, or 503 when any of them fails. The
+// endpoint is served without the session middleware and during the
+// maintenance mode, it can be left out of the access logs with WithLogSkip.
+func WithHealthCheck(path string) Option {
+	return func(m *mux) {
+		// the health of the app doesn't change with the maintenance mode.
+		options := []RouteOption{SkipMiddleware("maintenance")}
+		if slices.Contains(m.names, "session") {
+			options = append(options, SkipMiddleware("session"))
+		}
+
+		m.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+			checks := m.healthChecks
+			results := make([]healthStatus, len(checks))
+			done := make(chan struct{}, len(checks))
+			for i, check := range checks {
+				go func() {
+					defer func() { done <- struct{}{} }()
+
+					if err := runCheck(r.Context(), check.check, m.healthTimeout); err != nil {
+						results[i] = healthStatus{Status: "error", Error: err.Error()}
+						return
+					}
+
+					results[i] = healthStatus{Status: "ok"}
+				}()
+			}
+
+			for range checks {
+				<-done
+			}
+
+			body := struct {
+				Status string                  `json:"status"`
+				Checks map[string]healthStatus `json:"checks"`
+			}{Status: "ok", Checks: make(map[string]healthStatus, len(checks))}
+
+			status := http.StatusOK
+			for i, check := range checks {
+				body.Checks[check.name] = results[i]
+				if results[i].Status != "ok" {
+					body.Status, status = "error", http.StatusServiceUnavailable
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(body)
+		}, options...)
+	}
+}
+
+// WithHealthCheckFunc allows to add a check with the name to the health
+// endpoint of WithHealthCheck, like a ping to the database, that fails
+// when it returns an error or doesn't return before the timeout.
+func WithHealthCheckFunc(name string, check func(context.Context) error) Option {
+	return func(m *mux) {
+		m.healthChecks = append(m.healthChecks, healthCheck{name: name, check: check})
+	}
+}
+
+// WithHealthCheckTimeout sets how long each check of the
+// health endpoint can take, it defaults to 5 seconds.
+func WithHealthCheckTimeout(timeout time.Duration) Option {
+	return func(m *mux) {
+		m.healthTimeout = timeout
+	}
+}
+
+// runCheck runs the check, returning an error when it
+// doesn't return before the timeout or panics.
+func runCheck(ctx context.Context, check func(context.Context) error, timeout time.Duration) error {
+	timeout = cmp.Or(timeout, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// the result is buffered so a check that doesn't stop
+	// when the context is canceled doesn't leak the goroutine.
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				result <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+
+		result <- check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+}
@@ -0,0 +1,100 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestWithHealthCheck(t *testing.T) {
+	type body struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		} `json:"checks"`
+	}
+
+	serve := func(s http.Handler) (*httptest.ResponseRecorder, body) {
+		res := httptest.NewRecorder()
+		s.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		var b body
+		if err := json.Unmarshal(res.Body.Bytes(), &b); err != nil {
+			t.Fatalf("Expected a JSON body, got %q: %v", res.Body.String(), err)
+		}
+
+		return res, b
+	}
+
+	ok := func(context.Context) error { return nil }
+
+	t.Run("healthy", func(t *testing.T) {
+		s := server.New(
+			server.WithSession("secret", "app"),
+			server.WithHealthCheck("/healthz"),
+			server.WithHealthCheckFunc("database", ok),
+			server.WithHealthCheckFunc("cache", ok),
+		)
+
+		s.HandleFunc("GET /{path...}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("page"))
+		})
+
+		res, b := serve(s.Handler())
+		if res.Code != http.StatusOK || b.Status != "ok" {
+			t.Errorf("Expected 200 ok, got %d %q", res.Code, b.Status)
+		}
+
+		if b.Checks["database"].Status != "ok" || b.Checks["cache"].Status != "ok" {
+			t.Errorf("Expected the status of each check, got %v", b.Checks)
+		}
+
+		if err := s.Check(); err != nil {
+			t.Errorf("Expected the session to be skipped without errors, got %v", err)
+		}
+	})
+
+	t.Run("failing and hung checks", func(t *testing.T) {
+		s := server.New(
+			server.WithHealthCheck("/healthz"),
+			server.WithHealthCheckTimeout(20*time.Millisecond),
+			server.WithHealthCheckFunc("database", ok),
+			server.WithHealthCheckFunc("cache", func(context.Context) error {
+				return errors.New("connection refused")
+			}),
+			server.WithHealthCheckFunc("queue", func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			}),
+		)
+
+		start := time.Now()
+		res, b := serve(s.Handler())
+		if time.Since(start) > 500*time.Millisecond {
+			t.Errorf("Expected the hung check to time out, took %v", time.Since(start))
+		}
+
+		if res.Code != http.StatusServiceUnavailable || b.Status != "error" {
+			t.Errorf("Expected 503 error, got %d %q", res.Code, b.Status)
+		}
+
+		if c := b.Checks["cache"]; c.Status != "error" || c.Error != "connection refused" {
+			t.Errorf("Expected the error of the cache check, got %v", c)
+		}
+
+		if c := b.Checks["queue"]; c.Status != "error" || c.Error != "timed out after 20ms" {
+			t.Errorf("Expected the queue check to time out, got %v", c)
+		}
+
+		if b.Checks["database"].Status != "ok" {
+			t.Errorf("Expected the database check to pass, got %v", b.Checks["database"])
+		}
+	})
+}
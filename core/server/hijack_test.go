@@ -0,0 +1,103 @@
+package server_test
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+// lineWriter sends the lines written to it through a
+// channel, dropping them when the channel is full.
+type lineWriter chan string
+
+func (lw lineWriter) Write(p []byte) (int, error) {
+	select {
+	case lw <- string(p):
+	default:
+	}
+
+	return len(p), nil
+}
+
+func TestHijack(t *testing.T) {
+	lines := make(lineWriter, 10)
+	log.SetOutput(lines)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	s := server.New(server.WithSession("hijack_secret", "hijack"))
+	s.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Expected no error hijacking, got %v", err)
+			return
+		}
+
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+
+		line, _ := rw.ReadString('\n')
+		rw.WriteString("echo: " + line)
+		rw.Flush()
+
+		// writes after the hijack must not reach the connection.
+		w.Header().Set("X-After", "hijack")
+	})
+
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: example.com\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\nhello\n"))
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Expected status %d, got %d", http.StatusSwitchingProtocols, res.StatusCode)
+	}
+
+	if c := res.Header.Get("Set-Cookie"); c != "" {
+		t.Errorf("Expected no session cookie on the upgrade, got %q", c)
+	}
+
+	echo, err := br.ReadString('\n')
+	if err != nil || echo != "echo: hello\n" {
+		t.Errorf("Expected echo: hello, got %q (%v)", echo, err)
+	}
+
+	for {
+		select {
+		case line := <-lines:
+			if !strings.Contains(line, "url=/ws") {
+				continue
+			}
+
+			if !strings.Contains(line, "hijacked=true") || strings.Contains(line, "status=") {
+				t.Errorf("Expected the hijacked connection to be logged without status, got %q", line)
+			}
+
+			return
+		case <-time.After(time.Second):
+			t.Fatal("Expected the request to be logged")
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// hostKey is the context key for the host matched by a host group.
+var hostKey contextKey = "host"
+
+// contextKey is the type of the keys the server package
+// uses to store values in the request context.
+type contextKey string
+
+// MatchedHost returns the host of the request when it was served by
+// a route registered in a host group, empty otherwise. It doesn't
+// include the port so it can be used to generate links.
+func MatchedHost(r *http.Request) string {
+	host, _ := r.Context().Value(hostKey).(string)
+	return host
+}
+
+// setHost is the middleware that host groups use to store
+// the host of the request in its context.
+func setHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), hostKey, requestHost(r)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestHost returns the lowercased host of the request without the port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(host)
+}
+
+// hostMatch returns the host pattern that matches the host passed, exact
+// hosts take precedence over the wildcards, and the longest wildcard wins.
+func hostMatch(hosts map[string]Matcher, host string) string {
+	if _, ok := hosts[host]; ok {
+		return host
+	}
+
+	match := ""
+	for pattern := range hosts {
+		suffix, ok := strings.CutPrefix(pattern, "*")
+		if !ok || !strings.HasSuffix(host, suffix) || len(pattern) <= len(match) {
+			continue
+		}
+
+		match = pattern
+	}
+
+	return match
+}
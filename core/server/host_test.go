@@ -0,0 +1,77 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestHost(t *testing.T) {
+	s := server.New()
+	s.Use(server.InCtxMiddleware("customValue", "shared"))
+
+	s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("app home"))
+	})
+
+	s.HandleFunc("GET /about", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("about"))
+	})
+
+	s.Host("api.example.com", func(r server.Router) {
+		r.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			v := r.Context().Value("customValue").(string)
+			w.Write([]byte("api home " + v + " " + server.MatchedHost(r)))
+		})
+
+		r.Group("/users/", func(r server.Router) {
+			r.HandleFunc("GET /{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("api user " + r.PathValue("id")))
+			})
+		})
+	})
+
+	s.Host("*.example.com", func(r server.Router) {
+		r.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("tenant " + server.MatchedHost(r)))
+		})
+	})
+
+	testCases := []struct {
+		method string
+		host   string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "app.example.org", "/", http.StatusOK, "app home"},
+		{http.MethodGet, "api.example.com", "/", http.StatusOK, "api home shared api.example.com"},
+		{http.MethodGet, "API.example.com:3000", "/", http.StatusOK, "api home shared api.example.com"},
+		{http.MethodGet, "api.example.com", "/users/1", http.StatusOK, "api user 1"},
+		{http.MethodGet, "app.example.org", "/users/1", http.StatusNotFound, ""},
+		{http.MethodGet, "api.example.com", "/about", http.StatusOK, "about"},
+		{http.MethodPost, "api.example.com", "/users/1", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "acme.example.com", "/", http.StatusOK, "tenant acme.example.com"},
+		{http.MethodGet, "example.com", "/", http.StatusOK, "app home"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method+" "+tc.host+tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Host = tc.host
+
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != tc.code {
+				t.Errorf("Expected status %d, got %d", tc.code, res.Code)
+			}
+
+			if tc.body != "" && res.Body.String() != tc.body {
+				t.Errorf("Expected body %q, got %q", tc.body, res.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+// IdempotencyStore keeps the responses recorded by the Idempotency
+// middleware, the memory store is used by default and others, like one
+// backed by Redis or a SQL database, can be passed to it with
+// WithIdempotencyStore.
+type IdempotencyStore interface {
+	// Begin reserves the key for a request. It returns the response
+	// recorded for the key when there is one, and false when there isn't
+	// but the key is reserved by a request that is still in flight.
+	Begin(key string, ttl time.Duration) (*IdempotentResponse, bool, error)
+
+	// Save records the response of the request that reserved the key,
+	// which is replayed for the key until the ttl passes.
+	Save(key string, response *IdempotentResponse, ttl time.Duration) error
+
+	// Release frees the key reserved by a request whose
+	// response was not recorded, so it can be retried.
+	Release(key string) error
+}
+
+// IdempotentResponse is a response recorded by the Idempotency middleware.
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyOption allows to configure the Idempotency middleware.
+type IdempotencyOption func(*idempotency)
+
+// WithIdempotencyStore sets the store that keeps the recorded responses.
+func WithIdempotencyStore(store IdempotencyStore) IdempotencyOption {
+	return func(id *idempotency) {
+		id.store = store
+	}
+}
+
+// WithIdempotencyMaxBody sets the size of the largest body that is
+// recorded, requests with larger responses can be retried. It defaults
+// to 1MB.
+func WithIdempotencyMaxBody(size int) IdempotencyOption {
+	return func(id *idempotency) {
+		id.maxBody = size
+	}
+}
+
+// WithIdempotencyScope sets the function that returns who makes the
+// request, like the ID of the user, the keys of a caller are only replayed
+// to the same caller. It defaults to the Authorization header, the session
+// cookie or the IP of the client, in that order.
+func WithIdempotencyScope(scope func(*http.Request) string) IdempotencyOption {
+	return func(id *idempotency) {
+		id.scope = scope
+	}
+}
+
+// idempotency is the configuration of the Idempotency middleware.
+type idempotency struct {
+	store   IdempotencyStore
+	maxBody int
+	scope   func(*http.Request) string
+}
+
+// idempotencyScope returns the credentials the request is made with, the
+// Authorization header or the session cookie, or the IP of the client.
+func idempotencyScope(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		return "authorization " + auth
+	}
+
+	if cookie := session.Cookie(r.Context()); cookie != "" {
+		return "session " + cookie
+	}
+
+	return "ip " + ClientIP(r)
+}
+
+// Idempotency returns a middleware that makes the POST, PUT and PATCH
+// requests with an Idempotency-Key header safe to retry. The response to
+// the first request with a key is recorded, with its status, headers and
+// body, and replayed with an Idempotent-Replayed header to the requests
+// of the same caller with the same key, method and path until the ttl
+// passes, see WithIdempotencyScope. Requests made
+// while the first one is in flight get a 409 through the error handlers.
+// Responses with a 5xx status or a body larger than the limit are not
+// recorded, so the requests can be retried, and requests are let through
+// when the store fails.
+func Idempotency(ttl time.Duration, options ...IdempotencyOption) Middleware {
+	id := &idempotency{store: NewMemoryIdempotencyStore(), maxBody: 1 << 20, scope: idempotencyScope}
+	for _, option := range options {
+		option(id)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Idempotency-Key")
+			if header == "" || (r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// the scope is hashed so the credentials are not kept in the store.
+			scope := sha256.Sum256([]byte(id.scope(r)))
+			key := r.Method + " " + r.URL.Path + " " + hex.EncodeToString(scope[:]) + " " + header
+			recorded, ok, err := id.store.Begin(key, ttl)
+			if err != nil {
+				Logger(r).Error("idempotency store failed", "error", err)
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if recorded != nil {
+				h := w.Header()
+				for k, v := range recorded.Header {
+					h[k] = v
+				}
+
+				h.Set("Idempotent-Replayed", "true")
+				w.WriteHeader(recorded.Status)
+				w.Write(recorded.Body)
+
+				return
+			}
+
+			if !ok {
+				Error(w, fmt.Errorf("409 a request with the idempotency key %q is in progress", header), http.StatusConflict)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, max: id.maxBody}
+			saved := false
+			defer func() {
+				// the key is freed when the handler panics too.
+				if !saved {
+					if err := id.store.Release(key); err != nil {
+						Logger(r).Error("idempotency store failed", "error", err)
+					}
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			status := cmp.Or(rec.status, http.StatusOK)
+			if !rec.whole() || status >= http.StatusInternalServerError {
+				return
+			}
+
+			// the cookies, like the session one, belong to the first request.
+			rec.header.Del("Set-Cookie")
+
+			err = id.store.Save(key, &IdempotentResponse{Status: status, Header: rec.header, Body: rec.body.Bytes()}, ttl)
+			if err != nil {
+				Logger(r).Error("idempotency store failed", "error", err)
+				return
+			}
+
+			saved = true
+		})
+	}
+}
+
+// NewMemoryIdempotencyStore returns an IdempotencyStore that keeps the
+// responses in memory. The expired ones are removed as new keys come.
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotency{entries: map[string]*idempotencyEntry{}}
+}
+
+// memoryIdempotency is the in memory IdempotencyStore.
+type memoryIdempotency struct {
+	moot    sync.Mutex
+	entries map[string]*idempotencyEntry
+
+	// swept is when the expired entries were last removed.
+	swept time.Time
+}
+
+// idempotencyEntry is a key reserved by a request in flight,
+// without response, or the response recorded for it.
+type idempotencyEntry struct {
+	response *IdempotentResponse
+	expires  time.Time
+}
+
+func (m *memoryIdempotency) Begin(key string, ttl time.Duration) (*IdempotentResponse, bool, error) {
+	m.moot.Lock()
+	defer m.moot.Unlock()
+
+	now := time.Now()
+	m.sweep(now, ttl)
+
+	if e, ok := m.entries[key]; ok && now.Before(e.expires) {
+		return e.response, false, nil
+	}
+
+	// requests that never finish, like the ones of a
+	// process that crashed, hold the key for the ttl.
+	m.entries[key] = &idempotencyEntry{expires: now.Add(ttl)}
+
+	return nil, true, nil
+}
+
+func (m *memoryIdempotency) Save(key string, response *IdempotentResponse, ttl time.Duration) error {
+	m.moot.Lock()
+	defer m.moot.Unlock()
+
+	m.entries[key] = &idempotencyEntry{response: response, expires: time.Now().Add(ttl)}
+
+	return nil
+}
+
+func (m *memoryIdempotency) Release(key string) error {
+	m.moot.Lock()
+	defer m.moot.Unlock()
+
+	delete(m.entries, key)
+
+	return nil
+}
+
+// sweep removes the expired entries at most once every ttl.
+func (m *memoryIdempotency) sweep(now time.Time, ttl time.Duration) {
+	if now.Sub(m.swept) < ttl {
+		return
+	}
+
+	m.swept = now
+	for key, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, key)
+		}
+	}
+}
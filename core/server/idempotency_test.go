@@ -0,0 +1,212 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestIdempotency(t *testing.T) {
+	var orders atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+
+	s := server.New()
+	s.Use(server.Idempotency(time.Minute, server.WithIdempotencyMaxBody(64)))
+	s.HandleFunc("POST /orders", func(w http.ResponseWriter, r *http.Request) {
+		n := orders.Add(1)
+		w.Header().Set("Location", fmt.Sprintf("/orders/%d", n))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "order %d", n)
+	})
+
+	s.HandleFunc("POST /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	s.HandleFunc("POST /large", func(w http.ResponseWriter, r *http.Request) {
+		orders.Add(1)
+		w.Write([]byte(strings.Repeat("x", 65)))
+	})
+
+	s.HandleFunc("POST /failing", func(w http.ResponseWriter, r *http.Request) {
+		orders.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	h := s.Handler()
+	serve := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("replayed", func(t *testing.T) {
+		first := serve(http.MethodPost, "/orders", "key-1")
+		second := serve(http.MethodPost, "/orders", "key-1")
+
+		if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() || second.Header().Get("Location") != first.Header().Get("Location") {
+			t.Errorf("Expected the first response replayed, got %d %q %q", second.Code, second.Body.String(), second.Header().Get("Location"))
+		}
+
+		if second.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+			t.Error("Expected only the replayed response to be marked")
+		}
+
+		if orders.Load() != 1 {
+			t.Errorf("Expected one order, got %d", orders.Load())
+		}
+	})
+
+	t.Run("other keys and requests", func(t *testing.T) {
+		orders.Store(0)
+		serve(http.MethodPost, "/orders", "key-2")
+		serve(http.MethodPost, "/orders", "key-3")
+		serve(http.MethodPost, "/orders", "")
+
+		if orders.Load() != 3 {
+			t.Errorf("Expected three orders, got %d", orders.Load())
+		}
+	})
+
+	t.Run("in flight", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- serve(http.MethodPost, "/slow", "key-4") }()
+
+		<-started
+		res := serve(http.MethodPost, "/slow", "key-4")
+		close(release)
+
+		if res.Code != http.StatusConflict {
+			t.Errorf("Expected 409 while the request is in flight, got %d", res.Code)
+		}
+
+		if first := <-done; first.Body.String() != "done" {
+			t.Errorf("Expected the first request to finish, got %q", first.Body.String())
+		}
+	})
+
+	t.Run("other callers", func(t *testing.T) {
+		orders.Store(0)
+		as := func(auth string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			req.Header.Set("Idempotency-Key", "key-6")
+			req.Header.Set("Authorization", auth)
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			return res
+		}
+
+		alice := as("Bearer alice")
+		bob := as("Bearer bob")
+		if bob.Header().Get("Idempotent-Replayed") != "" || bob.Body.String() == alice.Body.String() {
+			t.Errorf("Expected the response of another user not to be replayed, got %q", bob.Body.String())
+		}
+
+		if again := as("Bearer alice"); again.Body.String() != alice.Body.String() {
+			t.Errorf("Expected the response replayed to the same user, got %q", again.Body.String())
+		}
+
+		if orders.Load() != 2 {
+			t.Errorf("Expected two orders, got %d", orders.Load())
+		}
+	})
+
+	t.Run("not recorded", func(t *testing.T) {
+		orders.Store(0)
+		for _, path := range []string{"/large", "/failing"} {
+			serve(http.MethodPost, path, "key-5")
+			serve(http.MethodPost, path, "key-5")
+		}
+
+		if orders.Load() != 4 {
+			t.Errorf("Expected the requests to be retried, got %d", orders.Load())
+		}
+	})
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	var orders atomic.Int32
+	order := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "order %d", orders.Add(1))
+	}
+
+	login := func(h http.Handler, user string) *http.Cookie {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/login?user="+user, nil))
+
+		return res.Result().Cookies()[0]
+	}
+
+	serve := func(h http.Handler, c *http.Cookie) string {
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set("Idempotency-Key", "key-1")
+		if c != nil {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res.Body.String()
+	}
+
+	t.Run("session", func(t *testing.T) {
+		s := server.New(server.WithSession("secret", "app"))
+		s.Use(server.Idempotency(time.Minute))
+		s.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+			session.Set(r.Context(), "user", r.URL.Query().Get("user"))
+		})
+
+		s.HandleFunc("POST /orders", order)
+		h := s.Handler()
+
+		alice, bob := login(h, "alice"), login(h, "bob")
+		first := serve(h, alice)
+		if serve(h, bob) == first || serve(h, alice) != first {
+			t.Error("Expected the responses replayed to the same session only")
+		}
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.Idempotency(time.Minute, server.WithIdempotencyScope(func(r *http.Request) string {
+			return r.Header.Get("X-Tenant")
+		})))
+
+		s.HandleFunc("POST /orders", order)
+		h := s.Handler()
+
+		serve := func(tenant string) string {
+			req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			req.Header.Set("Idempotency-Key", "key-1")
+			req.Header.Set("X-Tenant", tenant)
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			return res.Body.String()
+		}
+
+		first := serve("acme")
+		if serve("globex") == first || serve("acme") != first {
+			t.Error("Expected the responses replayed to the same scope only")
+		}
+	})
+}
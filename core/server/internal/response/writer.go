@@ -3,6 +3,8 @@ package response
 import (
 	"bufio"
 	"errors"
+	"log/slog"
+	"maps"
 	"net"
 	"net/http"
 )
@@ -12,17 +14,110 @@ import (
 type Writer struct {
 	http.ResponseWriter
 	Status int
+
+	// Bytes is the number of bytes of the body written so far.
+	Bytes int
+
+	// Request is the latest version of the request being served, it's
+	// passed to the error handlers when an error response is written.
+	Request *http.Request
+
+	// ErrorHandler returns the function registered to write the
+	// response for the passed error status, nil if there is none.
+	ErrorHandler func(status int) func(http.ResponseWriter, *http.Request, error)
+
+	// Hijacked is set when the connection has been hijacked, like
+	// for websockets, nothing can be written to the response after it.
+	Hijacked bool
+
+	// Aborted is set when the handler panicked with http.ErrAbortHandler,
+	// net/http drops the connection without sending the response.
+	Aborted bool
+
+	// Attrs are the attributes the middleware add
+	// to the access log line of the request.
+	Attrs []slog.Attr
+
+	// HeaderHooks are called once right before the headers are sent, in
+	// the reverse order they were added like deferred calls, so the ones
+	// of the middleware closer to the handler run first.
+	HeaderHooks []func(http.Header)
+
+	// Failure is set by the header hooks that fail, like when the session
+	// can't be saved, to write an error response instead of the one of the
+	// handler, whose writes are discarded. The response of the handler is
+	// sent when it doesn't write one.
+	Failure func(http.ResponseWriter)
+
+	// failed is set once the response of the Failure has been written.
+	failed bool
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, it allows the
+// http.ResponseController and the server to reach the original writer.
+func (w *Writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Root returns the *Writer closest to the original http.ResponseWriter
+// in the chain of wrapped writers, which is the one created by the server
+// when it started serving the request. It returns nil if there is none.
+func Root(w http.ResponseWriter) *Writer {
+	var root *Writer
+	for w != nil {
+		if rw, ok := w.(*Writer); ok {
+			root = rw
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+
+		w = u.Unwrap()
+	}
+
+	return root
 }
 
 // WriteHeader sets the status code and calls the WriteHeader() method of http.ResponseWriter.
 func (w *Writer) WriteHeader(statusCode int) {
+	// informational responses are sent before the final headers.
+	if statusCode >= http.StatusOK {
+		w.RunHeaderHooks()
+	}
+
+	if w.failed {
+		return
+	}
+
 	w.Status = statusCode
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Write writes the data to the wrapped http.ResponseWriter counting the bytes written.
+func (w *Writer) Write(b []byte) (int, error) {
+	if w.Status == 0 {
+		w.RunHeaderHooks()
+	}
+
+	if w.failed {
+		return len(b), nil
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.Bytes += n
+
+	return n, err
+}
+
 // Flush method is the http.Flusher implementation of this wrapper.
 // The Flush() method will be called if the wrapped http.ResponseWriter supports flushing.
 func (w *Writer) Flush() {
+	if w.Status == 0 {
+		w.RunHeaderHooks()
+	}
+
 	f, ok := w.ResponseWriter.(http.Flusher)
 	if !ok {
 		return
@@ -41,5 +136,51 @@ func (w *Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 		return nil, nil, errors.New("Hijack not supported")
 	}
 
-	return h.Hijack()
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.Hijacked = true
+	}
+
+	return conn, rw, err
+}
+
+// RunHeaderHooks calls the header hooks, only the first time, and writes
+// the response of the Failure when one of them failed. The server calls
+// it for the responses the handler didn't write.
+func (w *Writer) RunHeaderHooks() {
+	if len(w.HeaderHooks) == 0 {
+		return
+	}
+
+	hooks := w.HeaderHooks
+	w.HeaderHooks = nil
+
+	h := w.Header()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i](h)
+	}
+
+	if w.Failure == nil {
+		return
+	}
+
+	// the headers about the body or the redirect of the
+	// handler don't apply to the error response.
+	kept := h.Clone()
+	for _, k := range []string{"Content-Length", "Content-Encoding", "Location", "Set-Cookie"} {
+		h.Del(k)
+	}
+
+	failure := w.Failure
+	w.Failure = nil
+	failure(w)
+
+	if w.Status == 0 && w.Bytes == 0 {
+		clear(h)
+		maps.Copy(h, kept)
+
+		return
+	}
+
+	w.failed = true
 }
@@ -0,0 +1,431 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwtClaimsKey is the context key for the claims of the verified token.
+const jwtClaimsKey contextKey = "jwtClaims"
+
+// JWTOptions are the options of the JWT middleware. One of Secret,
+// PublicKey or JWKSURL must be set, the algorithm of the tokens must
+// match the kind of key they're verified with.
+type JWTOptions struct {
+	// Secret is the key of the tokens signed with HMAC, HS256,
+	// HS384 or HS512.
+	Secret []byte
+
+	// PublicKey is the *rsa.PublicKey of the tokens signed with RS256,
+	// RS384, RS512, PS256, PS384 or PS512, or the *ecdsa.PublicKey of
+	// the ones signed with ES256, ES384 or ES512.
+	PublicKey crypto.PublicKey
+
+	// JWKSURL is the URL of the JSON Web Key Set with the public keys
+	// of the tokens, which are picked by the kid of their header.
+	JWKSURL string
+
+	// JWKSRefresh is how often the key set is fetched again, it's fetched
+	// before that when a token has an unknown kid, at most once a minute.
+	// It defaults to an hour.
+	JWKSRefresh time.Duration
+
+	// Issuer is the iss the tokens must have, any when it's empty.
+	Issuer string
+
+	// Audience is the value the aud of the tokens must have,
+	// or contain when it's a list, any when it's empty.
+	Audience string
+
+	// Leeway is the clock skew allowed checking exp and nbf.
+	Leeway time.Duration
+}
+
+// JWT returns a middleware that requires the requests to have a JSON Web
+// Token in the Authorization header, as a bearer token, verifying its
+// signature with the key of the options and its exp, nbf, iss and aud
+// claims. The claims of the valid tokens are available to the handlers
+// with Claims, the requests with no token or an invalid one get a 401
+// with a WWW-Authenticate challenge through the error handlers. It panics
+// when none of the keys is set.
+func JWT(options JWTOptions) Middleware {
+	if options.Secret == nil && options.PublicKey == nil && options.JWKSURL == "" {
+		panic("server: JWT requires a Secret, a PublicKey or a JWKSURL")
+	}
+
+	var set *jwks
+	if options.JWKSURL != "" {
+		set = &jwks{url: options.JWKSURL, refresh: cmp.Or(options.JWKSRefresh, time.Hour)}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") || token == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				Error(w, fmt.Errorf("401 unauthorized"), http.StatusUnauthorized)
+
+				return
+			}
+
+			claims, err := verifyJWT(r.Context(), strings.TrimSpace(token), &options, set)
+			if err != nil {
+				// the reason is only logged, it would tell the
+				// clients what to change to forge a valid token.
+				Logger(r).Warn("invalid token", "error", err)
+
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description="invalid token"`)
+				Error(w, fmt.Errorf("401 invalid token"), http.StatusUnauthorized)
+
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), jwtClaimsKey, claims))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Claims returns the claims of the token the request was
+// verified with by the JWT middleware, nil when it wasn't.
+func Claims(r *http.Request) map[string]any {
+	claims, _ := r.Context().Value(jwtClaimsKey).(map[string]any)
+	return claims
+}
+
+// jwtHashes are the hashes of the algorithms by their size.
+var jwtHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// jwtCurves are the curves of the ECDSA algorithms.
+var jwtCurves = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+}
+
+// verifyJWT verifies the signature and the claims of the token,
+// it returns its claims when it's valid.
+func verifyJWT(ctx context.Context, token string, options *JWTOptions, set *jwks) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, errors.New("malformed header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+
+	// the algorithm picks the kind of key, so a token signed with
+	// a public key as HMAC secret doesn't pass, and none is rejected.
+	if len(header.Alg) != 5 {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	hash, ok := jwtHashes[header.Alg[2:]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	var key any = options.PublicKey
+	if set != nil && !strings.HasPrefix(header.Alg, "HS") {
+		if key, err = set.key(ctx, header.Kid); err != nil {
+			return nil, err
+		}
+	}
+
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	digest := h.Sum(nil)
+
+	valid := false
+	switch header.Alg[:2] {
+	case "HS":
+		if options.Secret == nil {
+			return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+		}
+
+		mac := hmac.New(hash.New, options.Secret)
+		mac.Write([]byte(parts[0] + "." + parts[1]))
+		valid = hmac.Equal(mac.Sum(nil), signature)
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+		}
+
+		if header.Alg[0] == 'R' {
+			valid = rsa.VerifyPKCS1v15(pub, hash, digest, signature) == nil
+		} else {
+			valid = rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Params().Name != jwtCurves[header.Alg] {
+			return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+		}
+
+		size := (pub.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return nil, errors.New("invalid signature")
+		}
+
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		valid = ecdsa.Verify(pub, digest, r, s)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	if !valid {
+		return nil, errors.New("invalid signature")
+	}
+
+	var claims map[string]any
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, errors.New("malformed claims")
+	}
+
+	return claims, checkJWTClaims(claims, options)
+}
+
+// checkJWTClaims checks the registered claims of the token.
+func checkJWTClaims(claims map[string]any, options *JWTOptions) error {
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); ok && now.After(jwtTime(exp).Add(options.Leeway)) {
+		return errors.New("token is expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(options.Leeway).Before(jwtTime(nbf)) {
+		return errors.New("token is not valid yet")
+	}
+
+	if options.Issuer != "" && claims["iss"] != options.Issuer {
+		return errors.New("invalid issuer")
+	}
+
+	if options.Audience == "" {
+		return nil
+	}
+
+	switch aud := claims["aud"].(type) {
+	case string:
+		if aud == options.Audience {
+			return nil
+		}
+	case []any:
+		if slices.Contains(aud, any(options.Audience)) {
+			return nil
+		}
+	}
+
+	return errors.New("invalid audience")
+}
+
+// jwtTime returns the time of a NumericDate claim.
+func jwtTime(seconds float64) time.Time {
+	return time.UnixMilli(int64(seconds * 1000))
+}
+
+// decodeJWTPart decodes the base64url encoded JSON of a part of a token.
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// jwks is a JSON Web Key Set fetched from its URL, the
+// keys are cached and fetched again after the refresh.
+type jwks struct {
+	url     string
+	refresh time.Duration
+
+	moot    sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+
+	// err is the error of the last fetch.
+	err error
+
+	// fetching is closed once the fetch in flight is
+	// done, it's nil when the set is not being fetched.
+	fetching chan struct{}
+}
+
+// key returns the key with the id, fetching the set when it hasn't
+// been or it's due, or when the key is not in it, like when the keys
+// have been rotated. The cached keys are used when fetching fails, and
+// while the set is fetched again, so only the requests that need the
+// new keys wait for it.
+func (j *jwks) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	j.moot.Lock()
+	key, ok := j.lookup(kid)
+	if j.keys != nil && time.Since(j.fetched) <= j.refresh && (ok || time.Since(j.fetched) <= time.Minute) {
+		j.moot.Unlock()
+		return knownKey(key, ok, kid)
+	}
+
+	// the set is fetched once for all the requests, and not
+	// canceled with the request that started fetching it.
+	fetching := j.fetching
+	if fetching == nil {
+		fetching = make(chan struct{})
+		j.fetching = fetching
+		go j.fetch(context.WithoutCancel(ctx), fetching)
+	}
+
+	j.moot.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	select {
+	case <-fetching:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	j.moot.Lock()
+	defer j.moot.Unlock()
+
+	if j.keys == nil {
+		return nil, j.err
+	}
+
+	key, ok = j.lookup(kid)
+
+	return knownKey(key, ok, kid)
+}
+
+// fetch fetches the set and closes done once it has.
+func (j *jwks) fetch(ctx context.Context, done chan struct{}) {
+	keys, err := fetchJWKS(ctx, j.url)
+
+	j.moot.Lock()
+	defer j.moot.Unlock()
+
+	// failures are retried once a minute for the unknown keys.
+	j.fetched = time.Now()
+	j.err = err
+	if err == nil {
+		j.keys = keys
+	}
+
+	j.fetching = nil
+	close(done)
+}
+
+// knownKey returns the key when it was found, an error otherwise.
+func knownKey(key crypto.PublicKey, ok bool, kid string) (crypto.PublicKey, error) {
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return key, nil
+}
+
+// lookup returns the key with the id, the tokens without
+// one are verified with the key when there is only one.
+func (j *jwks) lookup(kid string) (crypto.PublicKey, bool) {
+	if key, ok := j.keys[kid]; ok {
+		return key, true
+	}
+
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+// fetchJWKS fetches the key set from the URL and parses its RSA and EC
+// keys by their id, the keys of other types are left out.
+func fetchJWKS(ctx context.Context, url string) (map[string]crypto.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching the JWKS: %w", err)
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching the JWKS: %s", res.Status)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding the JWKS: %w", err)
+	}
+
+	curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+	number := func(s string) *big.Int {
+		b, _ := base64.RawURLEncoding.DecodeString(s)
+		return new(big.Int).SetBytes(b)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		switch k.Kty {
+		case "RSA":
+			keys[k.Kid] = &rsa.PublicKey{N: number(k.N), E: int(number(k.E).Int64())}
+		case "EC":
+			if curve, ok := curves[k.Crv]; ok {
+				keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: number(k.X), Y: number(k.Y)}
+			}
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,237 @@
+package server_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte("secret")
+	encode := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	sign := func(alg, kid string, claims map[string]any) string {
+		header := map[string]any{"alg": alg, "typ": "JWT"}
+		if kid != "" {
+			header["kid"] = kid
+		}
+
+		input := encode(header) + "." + encode(claims)
+		digest := sha256.Sum256([]byte(input))
+
+		var sig []byte
+		switch alg {
+		case "HS256":
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte(input))
+			sig = mac.Sum(nil)
+		case "RS256":
+			sig, _ = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		case "PS256":
+			sig, _ = rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		case "ES256":
+			r, s, _ := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+
+		return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+
+	valid := func() map[string]any {
+		return map[string]any{
+			"sub": "user-1",
+			"iss": "https://auth.example.com",
+			"aud": []string{"api", "web"},
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	var fetches atomic.Int32
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "n": base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), "e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()), "y": base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes())},
+		}})
+	}))
+
+	defer jwksServer.Close()
+
+	whoami := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(server.Claims(r)["sub"].(string)))
+	}
+
+	s := server.New()
+	s.Group("/hmac/", func(r server.Router) {
+		r.Use(server.JWT(server.JWTOptions{
+			Secret:   secret,
+			Issuer:   "https://auth.example.com",
+			Audience: "api",
+			Leeway:   time.Minute,
+		}))
+
+		r.HandleFunc("GET /whoami", whoami)
+	})
+
+	s.Group("/rsa/", func(r server.Router) {
+		r.Use(server.JWT(server.JWTOptions{PublicKey: &rsaKey.PublicKey}))
+		r.HandleFunc("GET /whoami", whoami)
+	})
+
+	s.Group("/jwks/", func(r server.Router) {
+		r.Use(server.JWT(server.JWTOptions{JWKSURL: jwksServer.URL}))
+		r.HandleFunc("GET /whoami", whoami)
+	})
+
+	h := s.Handler()
+	serve := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		cases := []struct{ path, token string }{
+			{"/hmac/whoami", sign("HS256", "", valid())},
+			{"/rsa/whoami", sign("RS256", "", valid())},
+			{"/rsa/whoami", sign("PS256", "", valid())},
+			{"/jwks/whoami", sign("RS256", "rsa-1", valid())},
+			{"/jwks/whoami", sign("ES256", "ec-1", valid())},
+		}
+
+		for _, tc := range cases {
+			res := serve(tc.path, tc.token)
+			if res.Code != http.StatusOK || res.Body.String() != "user-1" {
+				t.Errorf("Expected the claims of the token on %s, got %d %q", tc.path, res.Code, res.Body.String())
+			}
+		}
+
+		if fetches.Load() != 1 {
+			t.Errorf("Expected the key set fetched once, got %d", fetches.Load())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		claims := func(key string, value any) map[string]any {
+			c := valid()
+			c[key] = value
+			return c
+		}
+
+		cases := map[string]struct{ path, token string }{
+			"missing":          {"/hmac/whoami", ""},
+			"malformed":        {"/hmac/whoami", "not-a-token"},
+			"tampered":         {"/hmac/whoami", sign("HS256", "", valid())[:20] + "x" + sign("HS256", "", valid())[21:]},
+			"expired":          {"/hmac/whoami", sign("HS256", "", claims("exp", time.Now().Add(-2*time.Minute).Unix()))},
+			"not yet valid":    {"/hmac/whoami", sign("HS256", "", claims("nbf", time.Now().Add(2*time.Minute).Unix()))},
+			"issuer":           {"/hmac/whoami", sign("HS256", "", claims("iss", "https://evil.example.com"))},
+			"audience":         {"/hmac/whoami", sign("HS256", "", claims("aud", "admin"))},
+			"none":             {"/hmac/whoami", encode(map[string]string{"alg": "none"}) + "." + encode(valid()) + "."},
+			"hmac with rsa":    {"/rsa/whoami", sign("HS256", "", valid())},
+			"unknown key":      {"/jwks/whoami", sign("RS256", "rsa-2", valid())},
+			"wrong key for id": {"/jwks/whoami", sign("RS256", "ec-1", valid())},
+		}
+
+		for name, tc := range cases {
+			res := serve(tc.path, tc.token)
+			if res.Code != http.StatusUnauthorized || !strings.HasPrefix(res.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("Expected 401 with the challenge for the %s token, got %d %q", name, res.Code, res.Header().Get("WWW-Authenticate"))
+			}
+
+			if tc.token != "" && (res.Header().Get("WWW-Authenticate") != `Bearer error="invalid_token", error_description="invalid token"` || res.Body.String() != "401 invalid token") {
+				t.Errorf("Expected the %s token to get no details of the reason, got %q %q", name, res.Header().Get("WWW-Authenticate"), res.Body.String())
+			}
+		}
+
+		if res := serve("/hmac/whoami", sign("HS256", "", claims("exp", time.Now().Add(-30*time.Second).Unix()))); res.Code != http.StatusOK {
+			t.Errorf("Expected the token expired within the leeway to pass, got %d", res.Code)
+		}
+	})
+
+	t.Run("slow key set", func(t *testing.T) {
+		var fetches atomic.Int32
+		release := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the set is fetched right away the first time
+			// and blocks when it's fetched again.
+			if fetches.Add(1) > 1 {
+				<-release
+			}
+
+			json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rsa-1", "n": base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), "e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+			}})
+		}))
+
+		defer slow.Close()
+		defer close(release)
+
+		s := server.New()
+		s.Use(server.JWT(server.JWTOptions{JWKSURL: slow.URL, JWKSRefresh: time.Millisecond}))
+		s.HandleFunc("GET /whoami", whoami)
+		h := s.Handler()
+
+		for i := 0; i < 3; i++ {
+			if i > 0 {
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			req.Header.Set("Authorization", "Bearer "+sign("RS256", "rsa-1", valid()))
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			if res.Code != http.StatusOK {
+				t.Fatalf("Expected the cached key used while the set is fetched, got %d", res.Code)
+			}
+		}
+
+		if fetches.Load() != 2 {
+			t.Errorf("Expected the set fetched again once, got %d", fetches.Load())
+		}
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected JWT without keys to panic")
+			}
+		}()
+
+		server.JWT(server.JWTOptions{})
+	})
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/leapkit/leapkit/core/server/internal/response"
+)
+
+// loggerKey is the context key for the logger set with WithLogger.
+const loggerKey contextKey = "logger"
+
+// WithLogger allows to set the logger the server writes its logs with,
+// the access logs, the panics recovered and the errors written with
+// Error, instead of slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *mux) {
+		m.logger = logger
+	}
+}
+
+// Logger returns the logger of the server with the ID of the request,
+// so handlers can log with the same logger and attributes as the server.
+func Logger(r *http.Request) *slog.Logger {
+	logger := serverLogger(r)
+	if id := RequestID(r); id != "" {
+		return logger.With("request_id", id)
+	}
+
+	return logger
+}
+
+// serverLogger returns the logger set with WithLogger,
+// or slog.Default() when there is none.
+func serverLogger(r *http.Request) *slog.Logger {
+	if logger, ok := r.Context().Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
+
+// writerLogger returns the logger for the request
+// being served with the writer, see Logger.
+func writerLogger(w http.ResponseWriter) *slog.Logger {
+	if rw := response.Root(w); rw != nil && rw.Request != nil {
+		return Logger(rw.Request)
+	}
+
+	return slog.Default()
+}
@@ -0,0 +1,408 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestWithLogger(t *testing.T) {
+	var global bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&global, nil)))
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil)).With("app", "billing")
+
+	s := server.New(server.WithLogger(logger))
+	s.HandleFunc("GET /invoices", func(w http.ResponseWriter, r *http.Request) {
+		server.Logger(r).Info("listing invoices")
+	})
+
+	s.HandleFunc("GET /broken", func(w http.ResponseWriter, r *http.Request) {
+		server.Error(w, errors.New("database is down"), http.StatusInternalServerError)
+	})
+
+	s.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	h := s.Handler()
+	serve := func(path string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("handlers log with the request attributes", func(t *testing.T) {
+		logs.Reset()
+		serve("/invoices")
+
+		lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected the handler and access log lines, got %q", logs.String())
+		}
+
+		for _, expected := range []string{"msg=\"listing invoices\"", "app=billing", "request_id=req-1"} {
+			if !strings.Contains(lines[0], expected) {
+				t.Errorf("Expected %s in the handler log, got %q", expected, lines[0])
+			}
+		}
+
+		if !strings.Contains(lines[1], "route=/invoices") || !strings.Contains(lines[1], "app=billing") {
+			t.Errorf("Expected the access log with the logger attributes, got %q", lines[1])
+		}
+	})
+
+	t.Run("errors and panics", func(t *testing.T) {
+		logs.Reset()
+		serve("/broken")
+		serve("/panic")
+
+		for _, expected := range []string{"msg=\"database is down\" app=billing request_id=req-1", "msg=panic app=billing error=boom"} {
+			if !strings.Contains(logs.String(), expected) {
+				t.Errorf("Expected %q in the logs, got %q", expected, logs.String())
+			}
+		}
+	})
+
+	t.Run("nothing goes to the default logger", func(t *testing.T) {
+		if global.Len() > 0 {
+			t.Errorf("Expected no logs in the default logger, got %q", global.String())
+		}
+	})
+
+	t.Run("default logger without the option", func(t *testing.T) {
+		global.Reset()
+
+		s := server.New()
+		s.HandleFunc("GET /invoices", func(w http.ResponseWriter, r *http.Request) {
+			server.Logger(r).Info("listing invoices")
+		})
+
+		s.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/invoices", nil))
+		if !strings.Contains(global.String(), "listing invoices") {
+			t.Errorf("Expected the handler log in the default logger, got %q", global.String())
+		}
+	})
+}
+
+func TestWithLogFormat(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	s := server.New(server.WithLogger(logger), server.WithLogFormat(server.LogJSON))
+	s.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Jane"))
+	})
+
+	s.Group("/admin", func(r server.Router) {
+		r.ResetMiddleware()
+		r.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.Header.Set("Referer", "https://example.com/users")
+	req.Header.Set("X-Request-ID", "req-1")
+	s.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a JSON object per request, got %q: %v", logs.String(), err)
+	}
+
+	expected := map[string]any{
+		"msg":        "request",
+		"method":     "GET",
+		"path":       "/users/1",
+		"route":      "/users/{id}",
+		"status":     float64(200),
+		"bytes":      float64(4),
+		"remote_ip":  "192.0.2.1",
+		"user_agent": "curl/8.0",
+		"referer":    "https://example.com/users",
+		"request_id": "req-1",
+	}
+
+	for key, value := range expected {
+		if line[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, line[key])
+		}
+	}
+
+	if _, ok := line["duration_ms"].(float64); !ok {
+		t.Errorf("Expected duration_ms to be a number, got %v", line["duration_ms"])
+	}
+
+	t.Run("groups that reset the middleware", func(t *testing.T) {
+		logs.Reset()
+		s.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/stats", nil))
+
+		var line map[string]any
+		if err := json.Unmarshal(logs.Bytes(), &line); err != nil || line["path"] != "/admin/stats" {
+			t.Errorf("Expected the request logged as JSON, got %q", logs.String())
+		}
+	})
+}
+
+func TestAccessLogFields(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	s := server.New(server.WithLogger(logger))
+	s.Use(server.Compress())
+	s.HandleFunc("GET /report", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("all work and no play ", 500)))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64)")
+	req.Header.Set("Referer", "https://example.com/")
+
+	res := httptest.NewRecorder()
+	s.Handler().ServeHTTP(res, req)
+
+	line := logs.String()
+	expected := []string{
+		"bytes=" + strconv.Itoa(res.Body.Len()),
+		`ua="Mozilla/5.0 (X11; Linux x86_64)"`,
+		"referer=https://example.com/",
+		"ip=192.0.2.1",
+	}
+
+	for _, exp := range expected {
+		if !strings.Contains(line, exp) {
+			t.Errorf("Expected %q in the log, got %q", exp, line)
+		}
+	}
+
+	if res.Body.Len() >= 500*len("all work and no play ") {
+		t.Errorf("Expected the response to be compressed, got %d bytes", res.Body.Len())
+	}
+
+	duration := regexp.MustCompile(`duration=(\S+)`).FindStringSubmatch(line)
+	if duration == nil {
+		t.Fatalf("Expected the duration in the log, got %q", line)
+	}
+
+	if d, err := time.ParseDuration(duration[1]); err != nil || d != d.Round(time.Microsecond) {
+		t.Errorf("Expected the duration to the microsecond, got %q", duration[1])
+	}
+
+	t.Run("without referer", func(t *testing.T) {
+		logs.Reset()
+		s.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/report", nil))
+
+		if strings.Contains(logs.String(), "referer=") {
+			t.Errorf("Expected no referer in the log, got %q", logs.String())
+		}
+	})
+}
+
+func TestWithSlowRequestThreshold(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	s := server.New(
+		server.WithLogger(logger),
+		server.WithLogSkip("/skipped"),
+		server.WithSlowRequestThreshold(20*time.Millisecond),
+	)
+
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+	}
+
+	s.HandleFunc("GET /reports/{id}", slow)
+	s.HandleFunc("GET /skipped", slow)
+	s.HandleFunc("GET /fast", func(w http.ResponseWriter, r *http.Request) {})
+	s.HandleFunc("GET /export", slow).Meta("longRunning", true)
+	s.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		slow(w, r)
+	})
+
+	h := s.Handler()
+	warned := func(path string) string {
+		logs.Reset()
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		for _, line := range strings.Split(logs.String(), "\n") {
+			if strings.Contains(line, "level=WARN") {
+				return line
+			}
+		}
+
+		return ""
+	}
+
+	line := warned("/reports/1")
+	for _, exp := range []string{`msg="slow request"`, "route=/reports/{id}", "request_id=req-1", "duration="} {
+		if !strings.Contains(line, exp) {
+			t.Errorf("Expected %q in the warning, got %q", exp, line)
+		}
+	}
+
+	if !strings.Contains(logs.String(), "level=INFO") {
+		t.Errorf("Expected the access log too, got %q", logs.String())
+	}
+
+	if warned("/skipped") == "" {
+		t.Error("Expected the requests left out of the access logs to be warned about")
+	}
+
+	for _, path := range []string{"/fast", "/export", "/events"} {
+		if line := warned(path); line != "" {
+			t.Errorf("Expected no warning for %s, got %q", path, line)
+		}
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	notified := false
+	s := server.New(
+		server.WithLogger(logger),
+		server.WithPanicHandler(func(ctx context.Context, err error, stack []byte, r *http.Request) {
+			notified = true
+		}),
+		server.WithErrorHandler(http.StatusInternalServerError, func(w http.ResponseWriter, r *http.Request, err error) {
+			t.Error("Expected the error handler not to be called")
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.HandleFunc("GET /report", func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		if err := r.Context().Err(); err != nil {
+			server.Error(w, fmt.Errorf("loading report: %w", err), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	s.HandleFunc("GET /proxy", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	h := s.Handler()
+
+	t.Run("canceled", func(t *testing.T) {
+		logs.Reset()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/report", nil).WithContext(ctx))
+
+		if !strings.Contains(logs.String(), "status=499") || !strings.Contains(logs.String(), "client_closed=true") {
+			t.Errorf("Expected the request marked as closed by the client, got %q", logs.String())
+		}
+
+		if strings.Contains(logs.String(), "level=ERROR") {
+			t.Errorf("Expected no errors logged, got %q", logs.String())
+		}
+	})
+
+	t.Run("aborted", func(t *testing.T) {
+		logs.Reset()
+		defer func() {
+			if p := recover(); p != http.ErrAbortHandler {
+				t.Errorf("Expected http.ErrAbortHandler to reach net/http, got %v", p)
+			}
+
+			if notified || strings.Contains(logs.String(), "level=ERROR") {
+				t.Errorf("Expected the abort not to be handled as a panic, got %q", logs.String())
+			}
+
+			if !strings.Contains(logs.String(), "status=499") || !strings.Contains(logs.String(), "client_closed=true") {
+				t.Errorf("Expected the request marked as closed by the client, got %q", logs.String())
+			}
+		}()
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/proxy", nil))
+	})
+}
+
+func TestWithLogSkip(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	healthy := true
+	s := server.New(
+		server.WithLogger(logger),
+		server.WithLogSkip("/healthz", "/metrics/"),
+		server.WithLogSkipFunc(func(r *http.Request) bool {
+			return r.Header.Get("User-Agent") == "kube-probe/1.29"
+		}),
+	)
+
+	s.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	for _, pattern := range []string{"GET /metrics/cpu", "GET /healthzone", "GET /ready"} {
+		s.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {})
+	}
+
+	s.Group("/admin", func(r server.Router) {
+		r.ResetMiddleware()
+		r.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	h := s.Handler()
+	logged := func(path, userAgent string) bool {
+		logs.Reset()
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("User-Agent", userAgent)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		return strings.Contains(logs.String(), "url="+path)
+	}
+
+	cases := []struct {
+		path      string
+		userAgent string
+		logged    bool
+	}{
+		{"/healthz", "", false},
+		{"/metrics/cpu", "", false},
+		{"/healthzone", "", true},
+		{"/ready", "", true},
+		{"/ready", "kube-probe/1.29", false},
+	}
+
+	for _, c := range cases {
+		if logged(c.path, c.userAgent) != c.logged {
+			t.Errorf("Expected %s with %q to be logged: %v", c.path, c.userAgent, c.logged)
+		}
+	}
+
+	if logged("/admin/healthz", "kube-probe/1.29") {
+		t.Error("Expected the requests of groups that reset the middleware to be skipped")
+	}
+
+	healthy = false
+	if !logged("/healthz", "") {
+		t.Error("Expected the failing health check to be logged")
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server/internal/response"
+)
+
+// MaintenanceOptions configures the responses of the maintenance mode.
+type MaintenanceOptions struct {
+	// RetryAfter is sent in the Retry-After header when it's set.
+	RetryAfter time.Duration
+
+	// HTML is the body of the responses, when it's empty the
+	// response is written through the error handler for 503.
+	HTML string
+
+	// JSON is the body of the responses to the requests
+	// that accept JSON, HTML is used when it's empty.
+	JSON string
+
+	// Allow are the paths served during the maintenance, and the ones
+	// under them, like the health checks or the endpoint that turns
+	// the maintenance mode off.
+	Allow []string
+}
+
+// Maintenance is a maintenance mode that can be turned on and off while
+// serving requests, its Middleware responds 503 to the requests while it's
+// on. The server has one for all its routes that SetMaintenance turns on,
+// others can be created with NewMaintenance for groups of routes.
+type Maintenance struct {
+	options atomic.Pointer[MaintenanceOptions]
+}
+
+// NewMaintenance returns a maintenance mode that is off.
+func NewMaintenance() *Maintenance {
+	return &Maintenance{}
+}
+
+// Set turns the maintenance mode on or off, it's safe
+// to call it while the requests are being served.
+func (mt *Maintenance) Set(on bool, options MaintenanceOptions) {
+	if !on {
+		mt.options.Store(nil)
+		return
+	}
+
+	mt.options.Store(&options)
+}
+
+// On returns whether the maintenance mode is on.
+func (mt *Maintenance) On() bool {
+	return mt.options.Load() != nil
+}
+
+// Middleware responds 503 to the requests while the maintenance mode
+// is on, except to the allowed paths. The responses are logged with
+// maintenance=true so they can be told apart from the errors.
+func (mt *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		options := mt.options.Load()
+		if options == nil || underPaths(r.URL.Path, options.Allow) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if rw := response.Root(w); rw != nil {
+			rw.Attrs = append(rw.Attrs, slog.Bool("maintenance", true))
+		}
+
+		h := w.Header()
+		h.Set("Cache-Control", "no-store")
+		if options.RetryAfter > 0 {
+			h.Set("Retry-After", strconv.Itoa(int(math.Ceil(options.RetryAfter.Seconds()))))
+		}
+
+		switch {
+		case options.JSON != "" && strings.Contains(r.Header.Get("Accept"), "application/json"):
+			h.Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(options.JSON))
+		case options.HTML != "":
+			h.Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(options.HTML))
+		default:
+			Error(w, errors.New("503 service under maintenance"), http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// SetMaintenance turns the maintenance mode of the server on or off,
+// while it's on the routes respond 503 except the allowed paths. It's
+// safe to call it while the requests are being served. The middleware
+// of the maintenance mode is named maintenance so groups and routes can
+// skip it.
+func (s *mux) SetMaintenance(on bool, options MaintenanceOptions) {
+	s.maintenance.Set(on, options)
+}
@@ -0,0 +1,147 @@
+package server_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestMaintenance(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	s := server.New(
+		server.WithErrorHandler(http.StatusServiceUnavailable, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("back soon"))
+		}),
+		server.WithHealthCheck("/healthz"),
+	)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+
+	s.HandleFunc("GET /{$}", ok)
+	s.HandleFunc("POST /admin/maintenance", ok)
+
+	reports := server.NewMaintenance()
+	s.Group("/reports", func(r server.Router) {
+		r.Use(reports.Middleware)
+		r.HandleFunc("GET /sales", ok)
+	})
+
+	h := s.Handler()
+	serve := func(method, path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Accept", accept)
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("off", func(t *testing.T) {
+		if res := serve(http.MethodGet, "/", ""); res.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d", res.Code)
+		}
+	})
+
+	t.Run("on", func(t *testing.T) {
+		s.SetMaintenance(true, server.MaintenanceOptions{
+			RetryAfter: 90 * time.Second,
+			Allow:      []string{"/admin/maintenance"},
+		})
+
+		defer s.SetMaintenance(false, server.MaintenanceOptions{})
+
+		logs.Reset()
+		res := serve(http.MethodGet, "/", "")
+		if res.Code != http.StatusServiceUnavailable || res.Body.String() != "back soon" {
+			t.Errorf("Expected the error handler for 503, got %d %q", res.Code, res.Body.String())
+		}
+
+		if res.Header().Get("Retry-After") != "90" {
+			t.Errorf("Expected Retry-After 90, got %q", res.Header().Get("Retry-After"))
+		}
+
+		if !strings.Contains(logs.String(), "maintenance=true") {
+			t.Errorf("Expected the response to be marked in the log, got %q", logs.String())
+		}
+
+		for _, path := range []string{"/admin/maintenance", "/healthz"} {
+			method := http.MethodPost
+			if path == "/healthz" {
+				method = http.MethodGet
+			}
+
+			if res := serve(method, path, ""); res.Code != http.StatusOK {
+				t.Errorf("Expected %s to be allowed, got %d", path, res.Code)
+			}
+		}
+	})
+
+	t.Run("bodies", func(t *testing.T) {
+		s.SetMaintenance(true, server.MaintenanceOptions{
+			HTML: "<h1>Down for maintenance</h1>",
+			JSON: `{"error":"maintenance"}`,
+		})
+
+		defer s.SetMaintenance(false, server.MaintenanceOptions{})
+
+		res := serve(http.MethodGet, "/", "text/html")
+		if res.Body.String() != "<h1>Down for maintenance</h1>" || !strings.HasPrefix(res.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("Expected the HTML body, got %q %q", res.Header().Get("Content-Type"), res.Body.String())
+		}
+
+		res = serve(http.MethodGet, "/", "application/json")
+		if res.Body.String() != `{"error":"maintenance"}` || res.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected the JSON body, got %q %q", res.Header().Get("Content-Type"), res.Body.String())
+		}
+	})
+
+	t.Run("group", func(t *testing.T) {
+		reports.Set(true, server.MaintenanceOptions{})
+		defer reports.Set(false, server.MaintenanceOptions{})
+
+		if res := serve(http.MethodGet, "/reports/sales", ""); res.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 in the group, got %d", res.Code)
+		}
+
+		if res := serve(http.MethodGet, "/", ""); res.Code != http.StatusOK {
+			t.Errorf("Expected 200 outside the group, got %d", res.Code)
+		}
+	})
+
+	t.Run("toggled while serving", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					res := serve(http.MethodGet, "/", "")
+					if res.Code != http.StatusOK && res.Code != http.StatusServiceUnavailable {
+						t.Errorf("Unexpected status %d", res.Code)
+					}
+				}
+			}()
+		}
+
+		for i := 0; i < 50; i++ {
+			s.SetMaintenance(i%2 == 0, server.MaintenanceOptions{})
+		}
+
+		wg.Wait()
+		s.SetMaintenance(false, server.MaintenanceOptions{})
+	})
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Matcher matches the requests with the handlers registered for
+// the route patterns, *http.ServeMux is the default one. Alternative
+// implementations must support the Go 1.22 pattern syntax.
+type Matcher interface {
+	// Handle registers the handler for the pattern, it panics when
+	// the pattern is invalid or conflicts with a registered one.
+	Handle(pattern string, handler http.Handler)
+
+	// Handler returns the handler for the request and the pattern
+	// it was registered with, without serving the request.
+	Handler(r *http.Request) (http.Handler, string)
+
+	// ServeHTTP serves the request with the matching handler,
+	// setting the wildcards of its pattern with SetPathValue.
+	ServeHTTP(w http.ResponseWriter, r *http.Request)
+}
+
+// WithMatcher allows to replace the http.ServeMux that matches the routes
+// with the matchers returned by the function, one for the server and one
+// for each of the host groups. It must be passed before the options that
+// register routes, like WithOpenAPI.
+func WithMatcher(fn func() Matcher) Option {
+	return func(m *mux) {
+		if len(m.routes) > 0 {
+			m.errs = append(m.errs, errors.New("WithMatcher must be passed before the options that register routes"))
+			return
+		}
+
+		m.newMatcher = fn
+		m.mux = fn()
+	}
+}
+
+// newServeMux returns a new http.ServeMux as a Matcher.
+func newServeMux() Matcher {
+	return http.NewServeMux()
+}
@@ -0,0 +1,49 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/matchertest"
+)
+
+// countingMatcher is a Matcher that counts the
+// requests it serves with a http.ServeMux.
+type countingMatcher struct {
+	*http.ServeMux
+	served *int
+}
+
+func (m countingMatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	*m.served++
+	m.ServeMux.ServeHTTP(w, r)
+}
+
+func TestWithMatcher(t *testing.T) {
+	matchertest.Run(t, func() server.Matcher {
+		return http.NewServeMux()
+	})
+
+	t.Run("custom matcher", func(t *testing.T) {
+		var served int
+		matchertest.Run(t, func() server.Matcher {
+			return countingMatcher{ServeMux: http.NewServeMux(), served: &served}
+		})
+
+		if served == 0 {
+			t.Error("Expected the requests to be served by the matcher")
+		}
+	})
+
+	t.Run("after registering routes", func(t *testing.T) {
+		s := server.New(server.WithOpenAPI(), server.WithMatcher(func() server.Matcher {
+			return http.NewServeMux()
+		}))
+
+		if err := s.Check(); err == nil || !strings.Contains(err.Error(), "WithMatcher must be passed before") {
+			t.Errorf("Expected an error, got %v", err)
+		}
+	})
+}
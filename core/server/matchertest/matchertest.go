@@ -0,0 +1,93 @@
+// Package matchertest provides a conformance suite for the implementations
+// of server.Matcher, it runs the routing features of the server with them.
+package matchertest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+// Run runs the routing conformance cases against a server
+// that matches its routes with the matchers returned by fn.
+func Run(t *testing.T, fn func() server.Matcher) {
+	t.Helper()
+
+	echo := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + r.PathValue("id") + r.PathValue("path")))
+		}
+	}
+
+	s := server.New(server.WithMatcher(fn))
+	s.HandleFunc("GET /{$}", echo("home"))
+	s.HandleFunc("GET /users", echo("users"))
+	s.HandleFunc("POST /users", echo("create"))
+	s.HandleFunc("GET /users/{id}", echo("user"))
+	s.HandleFunc("GET /users/new", echo("new"))
+	s.HandleFunc("GET /posts/{id:int}", echo("post"))
+	s.HandleFunc("GET /posts/{slug}", echo("slug"))
+	s.HandleFunc("GET /files/{path...}", echo("file"))
+	s.Group("/admin/", func(r server.Router) {
+		r.HandleFunc("GET /users/{id}", echo("admin"))
+	})
+
+	s.Host("api.example.com", func(r server.Router) {
+		r.HandleFunc("GET /users", echo("api"))
+	})
+
+	if err := s.Check(); err != nil {
+		t.Fatalf("Expected no errors registering the routes, got %v", err)
+	}
+
+	cases := []struct {
+		method string
+		target string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/", http.StatusOK, "home "},
+		{http.MethodGet, "/users", http.StatusOK, "users "},
+		{http.MethodHead, "/users", http.StatusOK, ""},
+		{http.MethodPost, "/users", http.StatusOK, "create "},
+		{http.MethodDelete, "/users", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/users/7", http.StatusOK, "user 7"},
+		{http.MethodGet, "/users/new", http.StatusOK, "new "},
+		{http.MethodGet, "/users/7/posts", http.StatusNotFound, ""},
+		{http.MethodGet, "/posts/7", http.StatusOK, "post 7"},
+		{http.MethodGet, "/posts/hello", http.StatusOK, "slug "},
+		{http.MethodGet, "/files/docs/readme.md", http.StatusOK, "file docs/readme.md"},
+		{http.MethodGet, "/admin/users/7", http.StatusOK, "admin 7"},
+		{http.MethodGet, "http://api.example.com/users", http.StatusOK, "api "},
+		{http.MethodGet, "http://api.example.com/users/7", http.StatusOK, "user 7"},
+		{http.MethodGet, "/missing", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.method+" "+c.target, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.target, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code {
+				t.Errorf("Expected status %d, got %d", c.code, res.Code)
+			}
+
+			if c.body != "" && res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+		})
+	}
+
+	t.Run("conflicts", func(t *testing.T) {
+		s := server.New(server.WithMatcher(fn))
+		s.HandleFunc("GET /users/{id}", echo("a"))
+		s.HandleFunc("GET /users/{name}", echo("b"))
+
+		if err := s.Check(); err == nil {
+			t.Error("Expected a conflict error, got nil")
+		}
+	})
+}
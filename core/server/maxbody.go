@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// WithMaxBody limits the size of the body of the requests to the
+// route, it takes the place of the MaxBody middleware of the groups.
+func WithMaxBody(n int64) RouteOption {
+	return func(rf *RouteRef) {
+		rf.middleware = append(rf.middleware, MaxBody(n))
+	}
+}
+
+// MaxBody returns a middleware that limits the size of the body of the
+// requests to n bytes. Requests with a larger Content-Length get a 413
+// through the error handlers without calling the handler, and reading
+// more than n bytes of the others fails with an *http.MaxBytesError,
+// which Error writes as a 413 whatever the status passed. The MaxBody
+// of a group takes the place of the one of its parents, and WithMaxBody
+// the one of the groups, so the closest one wins.
+func MaxBody(n int64) Middleware {
+	return maxBodyMiddleware(n).wrap
+}
+
+// maxBodyMiddleware is the limit of the MaxBody middleware, which is
+// its wrap method so closest can find it like it does for timeout.
+type maxBodyMiddleware int64
+
+func (limit maxBodyMiddleware) wrap(next http.Handler) http.Handler {
+	n := int64(limit)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > n {
+			Error(w, &http.MaxBytesError{Limit: n}, http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// maxBodyPC is the code pointer of the middleware returned by MaxBody.
+var maxBodyPC = reflect.ValueOf(maxBodyMiddleware(0).wrap).Pointer()
+
+// tooLarge returns the error for the body that exceeded the limit.
+func tooLarge(err *http.MaxBytesError) error {
+	return fmt.Errorf("413 request body too large, the limit is %d bytes", err.Limit)
+}
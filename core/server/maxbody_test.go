@@ -0,0 +1,142 @@
+package server_test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestMaxBody(t *testing.T) {
+	read := func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			server.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(body)
+	}
+
+	s := server.New()
+	s.Use(server.MaxBody(8))
+	s.HandleFunc("POST /notes", read)
+	s.HandleFunc("POST /avatars", read, server.WithMaxBody(64))
+	s.HandleFunc("POST /profile", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			server.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte(r.FormValue("name")))
+	})
+
+	s.Group("/uploads", func(r server.Router) {
+		r.Use(server.MaxBody(32))
+		r.HandleFunc("POST /files", read)
+		r.HandleFunc("POST /videos", read, server.WithMaxBody(128))
+	})
+
+	h := s.Handler()
+
+	serve := func(path string, body io.Reader, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, body)
+		if chunked {
+			req.ContentLength = -1
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("bodies within the limit", func(t *testing.T) {
+		res := serve("/notes", strings.NewReader("12345678"), false)
+		if res.Code != http.StatusOK || res.Body.String() != "12345678" {
+			t.Errorf("Expected 200 with the body, got %d %q", res.Code, res.Body.String())
+		}
+	})
+
+	t.Run("content length over the limit", func(t *testing.T) {
+		res := serve("/notes", strings.NewReader("123456789"), false)
+		if res.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413, got %d", res.Code)
+		}
+
+		if !strings.Contains(res.Body.String(), "the limit is 8 bytes") {
+			t.Errorf("Expected the limit in the message, got %q", res.Body.String())
+		}
+	})
+
+	t.Run("body read over the limit", func(t *testing.T) {
+		res := serve("/notes", strings.NewReader("123456789"), true)
+		if res.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413 instead of 500, got %d", res.Code)
+		}
+	})
+
+	t.Run("multipart over the limit", func(t *testing.T) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("name", "Jane Doe")
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/profile", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.ContentLength = -1
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if res.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413 instead of 500, got %d", res.Code)
+		}
+	})
+
+	t.Run("closest limit wins", func(t *testing.T) {
+		cases := []struct {
+			path string
+			size int
+			code int
+		}{
+			{"/avatars", 64, http.StatusOK},
+			{"/avatars", 65, http.StatusRequestEntityTooLarge},
+			{"/uploads/files", 32, http.StatusOK},
+			{"/uploads/files", 33, http.StatusRequestEntityTooLarge},
+			{"/uploads/videos", 128, http.StatusOK},
+			{"/uploads/videos", 129, http.StatusRequestEntityTooLarge},
+		}
+
+		for _, c := range cases {
+			for _, chunked := range []bool{false, true} {
+				res := serve(c.path, strings.NewReader(strings.Repeat("a", c.size)), chunked)
+				if res.Code != c.code {
+					t.Errorf("Expected %d for %d bytes to %s (chunked %v), got %d", c.code, c.size, c.path, chunked, res.Code)
+				}
+			}
+		}
+	})
+
+	t.Run("error handler", func(t *testing.T) {
+		s := server.New(
+			server.WithErrorHandler(http.StatusRequestEntityTooLarge, func(w http.ResponseWriter, r *http.Request, err error) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				w.Write([]byte("too big: " + err.Error()))
+			}),
+		)
+
+		s.Use(server.MaxBody(4))
+		s.HandleFunc("POST /notes", read)
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader("12345")))
+		if res.Code != http.StatusRequestEntityTooLarge || !strings.HasPrefix(res.Body.String(), "too big: ") {
+			t.Errorf("Expected the error handler for 413, got %d %q", res.Code, res.Body.String())
+		}
+	})
+}
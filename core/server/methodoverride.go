@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// methodOverrideHeader is the header that overrides the method of a POST request.
+const methodOverrideHeader = "X-HTTP-Method-Override"
+
+// WithMethodOverride makes the POST requests with a _method form field or
+// an X-HTTP-Method-Override header be routed with the method they set, so
+// plain HTML forms can reach the routes for PUT, PATCH and DELETE. Only
+// those three methods can be set, and the request is served and logged
+// with the overridden method.
+func WithMethodOverride() Option {
+	return func(m *mux) {
+		m.methodOverride = true
+	}
+}
+
+// overrideMethod returns the request with the method it overrides,
+// or nil when it's not a POST request overriding a valid method.
+func overrideMethod(r *http.Request) *http.Request {
+	if r.Method != http.MethodPost {
+		return nil
+	}
+
+	method := r.Header.Get(methodOverrideHeader)
+	if method == "" {
+		method = formMethod(r)
+	}
+
+	switch method = strings.ToUpper(method); method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		req := r.WithContext(r.Context())
+		req.Method = method
+
+		return req
+	}
+
+	return nil
+}
+
+// peekSize is how much of a form body is read looking for the
+// _method field, which forms usually send before the other fields.
+const peekSize = 4 << 10
+
+// formMethod returns the _method field of the form sent in the request.
+// The bodies are peeked instead of parsed, and put back as they were, so
+// the handler can still parse them with the limits of its route.
+func formMethod(r *http.Request) string {
+	ct, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if r.Body == nil || (ct != "application/x-www-form-urlencoded" && ct != "multipart/form-data") {
+		return ""
+	}
+
+	// one more byte is read to tell whether the body was cut.
+	peeked, _ := io.ReadAll(io.LimitReader(r.Body, peekSize+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), r.Body), r.Body}
+
+	if ct == "application/x-www-form-urlencoded" {
+		// the forms that were read whole are kept in the request, so the
+		// handlers of DELETE, whose body net/http doesn't parse, get them.
+		if len(peeked) <= peekSize {
+			if form, err := url.ParseQuery(string(peeked)); err == nil {
+				r.PostForm = form
+				return form.Get("_method")
+			}
+		}
+
+		pairs := strings.Split(string(peeked), "&")
+		if len(peeked) > peekSize {
+			// the last field may be cut.
+			pairs = pairs[:len(pairs)-1]
+		}
+
+		for _, pair := range pairs {
+			key, value, _ := strings.Cut(pair, "=")
+			if key, _ := url.QueryUnescape(key); key == "_method" {
+				value, _ = url.QueryUnescape(value)
+				return value
+			}
+		}
+
+		return ""
+	}
+
+	if params["boundary"] == "" {
+		return ""
+	}
+
+	mr := multipart.NewReader(bytes.NewReader(peeked), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return ""
+		}
+
+		if part.FormName() != "_method" {
+			continue
+		}
+
+		value, _ := io.ReadAll(io.LimitReader(part, 16))
+		return string(value)
+	}
+}
@@ -0,0 +1,138 @@
+package server_test
+
+import (
+	"bytes"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestMethodOverride(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	s := server.New(server.WithMethodOverride())
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		s.HandleFunc(method+" /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Method + " " + r.FormValue("name")))
+		})
+	}
+
+	s.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	})
+
+	s.HandleFunc("PUT /avatars/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		w.Write([]byte(r.Method + " " + r.FormValue("name")))
+	}, server.WithMaxBody(1<<10))
+
+	h := s.Handler()
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	form := func(method string, values url.Values) *http.Request {
+		req := httptest.NewRequest(method, "/users/1", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		return req
+	}
+
+	t.Run("form field", func(t *testing.T) {
+		logs.Reset()
+
+		res := serve(form(http.MethodPost, url.Values{"_method": {"delete"}, "name": {"Jane"}}))
+		if res.Body.String() != "DELETE Jane" {
+			t.Errorf("Expected the DELETE route with the form, got %q", res.Body.String())
+		}
+
+		if !strings.Contains(logs.String(), "method=DELETE") {
+			t.Errorf("Expected the overridden method in the log, got %q", logs.String())
+		}
+	})
+
+	t.Run("form limits of the route", func(t *testing.T) {
+		values := url.Values{"_method": {"put"}, "name": {"Jane"}}
+		req := httptest.NewRequest(http.MethodPost, "/avatars/1", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if res := serve(req); res.Body.String() != "PUT Jane" {
+			t.Errorf("Expected the PUT route with the form, got %q", res.Body.String())
+		}
+
+		values.Set("photo", strings.Repeat("x", 8<<10))
+		req = httptest.NewRequest(http.MethodPost, "/avatars/1", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if res := serve(req); res.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected the form over the limit of the route to be rejected, got %d", res.Code)
+		}
+	})
+
+	t.Run("header", func(t *testing.T) {
+		req := form(http.MethodPost, url.Values{"name": {"Jane"}})
+		req.Header.Set("X-HTTP-Method-Override", "PATCH")
+
+		if res := serve(req); res.Body.String() != "PATCH Jane" {
+			t.Errorf("Expected the PATCH route, got %q", res.Body.String())
+		}
+	})
+
+	t.Run("multipart form", func(t *testing.T) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("_method", "PUT")
+		mw.WriteField("name", "Jane")
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/users/1", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+
+		if res := serve(req); res.Body.String() != "PUT Jane" {
+			t.Errorf("Expected the PUT route with the whole form, got %q", res.Body.String())
+		}
+	})
+
+	t.Run("only PUT, PATCH and DELETE", func(t *testing.T) {
+		for _, method := range []string{"GET", "CONNECT", "TRACE", "bogus"} {
+			res := serve(form(http.MethodPost, url.Values{"_method": {method}, "name": {"Jane"}}))
+			if res.Body.String() != "POST Jane" {
+				t.Errorf("Expected %s not to override the method, got %q", method, res.Body.String())
+			}
+		}
+	})
+
+	t.Run("only from POST", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set("X-HTTP-Method-Override", "DELETE")
+
+		if res := serve(req); res.Body.String() != "GET" {
+			t.Errorf("Expected GET requests not to be overridden, got %q", res.Body.String())
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("DELETE /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, form(http.MethodPost, url.Values{"_method": {"DELETE"}}))
+		if res.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected 405 without the option, got %d", res.Code)
+		}
+	})
+}
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server/internal/response"
+)
+
+// Metrics returns a middleware that records the metrics of the requests:
+// their count, duration and response size, labeled by method, route pattern
+// and status class, and the requests in flight. The route pattern keeps the
+// number of series bounded, requests that don't match any route are labeled
+// with the 404 route. The panics recovered by the server are counted too.
+// The metrics are exposed in the Prometheus text format by the endpoint of
+// WithMetricsEndpoint.
+func Metrics() Middleware {
+	defaultMetrics.enabled.Store(true)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			rw := response.Root(w)
+			if rw == nil {
+				rw = &response.Writer{ResponseWriter: w}
+				w = rw
+			}
+
+			defaultMetrics.inFlight.Add(1)
+			completed := false
+			defer func() {
+				defaultMetrics.inFlight.Add(-1)
+
+				// the recoverer writes a 500 for the handlers that panic.
+				status := cmp.Or(rw.Status, http.StatusOK)
+				if !completed {
+					status = http.StatusInternalServerError
+				}
+
+				if clientClosed(r, rw) {
+					status = StatusClientClosed
+				}
+
+				route, _ := loggedRoute(r)
+				defaultMetrics.observe(r.Method, route, status, time.Since(start), rw.Bytes)
+			}()
+
+			next.ServeHTTP(w, r)
+			completed = true
+		})
+	}
+}
+
+// WithMetricsEndpoint allows to expose the metrics recorded by the
+// Metrics middleware in the Prometheus text format at the path.
+func WithMetricsEndpoint(path string) Option {
+	return func(m *mux) {
+		m.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+			defaultMetrics.write(w)
+		})
+	}
+}
+
+var (
+	// durationBuckets are the upper bounds of the buckets
+	// of the request duration histogram, in seconds.
+	durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+	// sizeBuckets are the upper bounds of the buckets
+	// of the response size histogram, in bytes.
+	sizeBuckets = []float64{100, 1000, 10_000, 100_000, 1_000_000, 10_000_000}
+)
+
+// defaultMetrics are the metrics recorded by the Metrics middleware.
+var defaultMetrics = &metrics{
+	series:      map[seriesKey]*series{},
+	panics:      map[seriesKey]uint64{},
+	concurrency: map[string]*concurrencyGauges{},
+	blocked:     map[string]uint64{},
+}
+
+// metrics are the metrics of the requests served.
+type metrics struct {
+	// enabled is set when the Metrics middleware is used,
+	// so the recoverer only counts the panics then.
+	enabled atomic.Bool
+
+	inFlight atomic.Int64
+
+	mu     sync.Mutex
+	series map[seriesKey]*series
+
+	// panics are counted by method and route, the status is empty.
+	panics map[seriesKey]uint64
+
+	// concurrency are the gauges of LimitConcurrency by route.
+	concurrency map[string]*concurrencyGauges
+
+	// blocked are the requests denied by FilterUserAgents by route.
+	blocked map[string]uint64
+}
+
+// concurrencyGauges are the requests to a route
+// being served and waiting in LimitConcurrency.
+type concurrencyGauges struct {
+	inFlight atomic.Int64
+	queued   atomic.Int64
+}
+
+// limited returns the concurrency gauges of the route of the request,
+// they're not kept for the endpoint unless the Metrics middleware is used.
+func (m *metrics) limited(r *http.Request) *concurrencyGauges {
+	if !m.enabled.Load() {
+		return &concurrencyGauges{}
+	}
+
+	route, _ := loggedRoute(r)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, ok := m.concurrency[route]
+	if !ok {
+		g = &concurrencyGauges{}
+		m.concurrency[route] = g
+	}
+
+	return g
+}
+
+// seriesKey are the labels of a series.
+type seriesKey struct {
+	method string
+	route  string
+	status string
+}
+
+// series are the metrics of the requests with the same labels.
+type series struct {
+	count    uint64
+	duration histogram
+	size     histogram
+}
+
+// histogram counts the observations in each bucket, the last count
+// is for the observations greater than the upper bounds.
+type histogram struct {
+	counts []uint64
+	sum    float64
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(bounds)+1)
+	}
+
+	i, _ := slices.BinarySearch(bounds, v)
+	h.counts[i]++
+	h.sum += v
+}
+
+// observe records a request that has been served.
+func (m *metrics) observe(method, route string, status int, took time.Duration, size int) {
+	key := seriesKey{metricMethod(method), route, strconv.Itoa(status/100) + "xx"}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{}
+		m.series[key] = s
+	}
+
+	s.count++
+	s.duration.observe(durationBuckets, took.Seconds())
+	s.size.observe(sizeBuckets, float64(size))
+}
+
+// panicked counts a panic recovered while serving the request.
+func (m *metrics) panicked(r *http.Request) {
+	if !m.enabled.Load() {
+		return
+	}
+
+	route, _ := loggedRoute(r)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.panics[seriesKey{method: metricMethod(r.Method), route: route}]++
+}
+
+// blockedUserAgent counts a request denied for its user agent.
+func (m *metrics) blockedUserAgent(r *http.Request) {
+	if !m.enabled.Load() {
+		return
+	}
+
+	route, _ := loggedRoute(r)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.blocked[route]++
+}
+
+// write writes the metrics in the Prometheus text format.
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]seriesKey, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, compareSeries)
+
+	fmt.Fprintln(w, "# HELP http_requests_total Total number of HTTP requests served.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "http_requests_total%s %d\n", key.labels(""), m.series[key].count)
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds Time it took to serve the HTTP requests.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	for _, key := range keys {
+		s := m.series[key]
+		writeHistogram(w, "http_request_duration_seconds", key, durationBuckets, &s.duration, s.count)
+	}
+
+	fmt.Fprintln(w, "# HELP http_response_size_bytes Size of the bodies of the HTTP responses.")
+	fmt.Fprintln(w, "# TYPE http_response_size_bytes histogram")
+	for _, key := range keys {
+		s := m.series[key]
+		writeHistogram(w, "http_response_size_bytes", key, sizeBuckets, &s.size, s.count)
+	}
+
+	fmt.Fprintln(w, "# HELP http_requests_in_flight Number of HTTP requests being served.")
+	fmt.Fprintln(w, "# TYPE http_requests_in_flight gauge")
+	fmt.Fprintf(w, "http_requests_in_flight %d\n", m.inFlight.Load())
+
+	keys = keys[:0]
+	for key := range m.panics {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, compareSeries)
+
+	fmt.Fprintln(w, "# HELP http_panics_total Total number of panics recovered while serving HTTP requests.")
+	fmt.Fprintln(w, "# TYPE http_panics_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "http_panics_total%s %d\n", key.labels(""), m.panics[key])
+	}
+
+	if len(m.blocked) > 0 {
+		routes := make([]string, 0, len(m.blocked))
+		for route := range m.blocked {
+			routes = append(routes, route)
+		}
+
+		slices.Sort(routes)
+
+		fmt.Fprintln(w, "# HELP http_user_agents_blocked_total Total number of HTTP requests denied for their User-Agent.")
+		fmt.Fprintln(w, "# TYPE http_user_agents_blocked_total counter")
+		for _, route := range routes {
+			fmt.Fprintf(w, "http_user_agents_blocked_total{route=%s} %d\n", labelValue(route), m.blocked[route])
+		}
+	}
+
+	if len(m.concurrency) == 0 {
+		return
+	}
+
+	routes := make([]string, 0, len(m.concurrency))
+	for route := range m.concurrency {
+		routes = append(routes, route)
+	}
+
+	slices.Sort(routes)
+
+	fmt.Fprintln(w, "# HELP http_concurrency_limited_in_flight Number of HTTP requests being served by LimitConcurrency.")
+	fmt.Fprintln(w, "# TYPE http_concurrency_limited_in_flight gauge")
+	for _, route := range routes {
+		fmt.Fprintf(w, "http_concurrency_limited_in_flight{route=%s} %d\n", labelValue(route), m.concurrency[route].inFlight.Load())
+	}
+
+	fmt.Fprintln(w, "# HELP http_concurrency_queued Number of HTTP requests waiting in LimitConcurrency.")
+	fmt.Fprintln(w, "# TYPE http_concurrency_queued gauge")
+	for _, route := range routes {
+		fmt.Fprintf(w, "http_concurrency_queued{route=%s} %d\n", labelValue(route), m.concurrency[route].queued.Load())
+	}
+}
+
+// writeHistogram writes the buckets, sum and count of the histogram.
+func writeHistogram(w io.Writer, name string, key seriesKey, bounds []float64, h *histogram, count uint64) {
+	var cumulative uint64
+	for i, bound := range bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, key.labels(strconv.FormatFloat(bound, 'g', -1, 64)), cumulative)
+	}
+
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, key.labels("+Inf"), count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, key.labels(""), strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, key.labels(""), count)
+}
+
+// labels returns the labels of the series, with the
+// le label of the histogram buckets when it's passed.
+func (k seriesKey) labels(le string) string {
+	pairs := []string{"method=" + labelValue(k.method), "route=" + labelValue(k.route)}
+	if k.status != "" {
+		pairs = append(pairs, "status="+labelValue(k.status))
+	}
+
+	if le != "" {
+		pairs = append(pairs, "le="+labelValue(le))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelValue quotes the value of a label escaping
+// the backslashes, double quotes and line feeds.
+func labelValue(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
+
+// compareSeries sorts the series by route, method and status.
+func compareSeries(a, b seriesKey) int {
+	return cmp.Or(
+		cmp.Compare(a.route, b.route),
+		cmp.Compare(a.method, b.method),
+		cmp.Compare(a.status, b.status),
+	)
+}
+
+// metricMethod returns the method for the labels, the
+// methods that are not standard are labeled as OTHER.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+
+	return "OTHER"
+}
@@ -0,0 +1,74 @@
+package server_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestMetrics(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	s := server.New(server.WithMetricsEndpoint("/metrics"))
+	s.Use(server.Metrics())
+
+	s.HandleFunc("GET /metered/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+
+	s.HandleFunc("POST /metered/{id}", func(w http.ResponseWriter, r *http.Request) {
+		server.Error(w, io.ErrUnexpectedEOF, http.StatusBadRequest)
+	})
+
+	s.HandleFunc("GET /metered-panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	h := s.Handler()
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+
+		return res
+	}
+
+	for _, path := range []string{"/metered/1", "/metered/2", "/metered/3"} {
+		serve(http.MethodGet, path)
+	}
+
+	serve(http.MethodPost, "/metered/1")
+	serve(http.MethodGet, "/metered-panic")
+
+	res := serve(http.MethodGet, "/metrics")
+	if res.Code != http.StatusOK || !strings.HasPrefix(res.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("Expected the metrics in the text format, got %d %q", res.Code, res.Header().Get("Content-Type"))
+	}
+
+	body := res.Body.String()
+	for _, expected := range []string{
+		`http_requests_total{method="GET",route="/metered/{id}",status="2xx"} 3`,
+		`http_requests_total{method="POST",route="/metered/{id}",status="4xx"} 1`,
+		`http_requests_total{method="GET",route="/metered-panic",status="5xx"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/metered/{id}",status="2xx",le="+Inf"} 3`,
+		`http_request_duration_seconds_count{method="GET",route="/metered/{id}",status="2xx"} 3`,
+		`http_response_size_bytes_bucket{method="GET",route="/metered/{id}",status="2xx",le="100"} 3`,
+		`http_response_size_bytes_sum{method="GET",route="/metered/{id}",status="2xx"} 15`,
+		`http_requests_in_flight 0`,
+		`http_panics_total{method="GET",route="/metered-panic"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %q in the metrics, got:\n%s", expected, body)
+		}
+	}
+
+	if strings.Contains(body, `route="/metered/1"`) {
+		t.Error("Expected the requests to be labeled with the route pattern, not the path")
+	}
+}
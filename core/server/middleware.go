@@ -3,11 +3,15 @@ package server
 import (
 	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"reflect"
 	"runtime/debug"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/leapkit/leapkit/core/server/internal/response"
@@ -22,24 +26,105 @@ var baseMiddleware = []Middleware{
 	recoverer,
 }
 
+// baseNames are the names of the baseMiddleware, so
+// groups and routes can skip them.
+var baseNames = []string{"valuer", "requestID", "logger", "recoverer"}
+
 // Middleware is a function that receives a http.Handler and returns a http.Handler
 // that can be used to wrap the original handler with some functionality.
 type Middleware func(http.Handler) http.Handler
 
-func requestID(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		r = r.WithContext(context.WithValue(r.Context(), "requestID", time.Now().UnixNano()))
-		next.ServeHTTP(w, r)
+// LogFormat is the format of the access logs of the server.
+type LogFormat int
+
+const (
+	// LogText logs the requests with the fields method, status, url, ip,
+	// request_id, route, handler, duration, bytes, ua and referer, when
+	// the request has one, it's the default.
+	LogText LogFormat = iota
+
+	// LogJSON logs each request as a JSON object with the fields method,
+	// path, route, status, bytes, duration_ms, remote_ip, user_agent,
+	// referer and request_id, which are kept stable for log pipelines to
+	// rely on.
+	LogJSON
+)
+
+// WithLogFormat allows to set the format of the access logs, the logs
+// are written with the logger of WithLogger when it's set.
+func WithLogFormat(format LogFormat) Option {
+	return func(m *mux) {
+		m.logging().format = format
+	}
+}
+
+// WithLogSkip allows to leave out of the access logs the requests to the
+// paths, like health checks, and the ones under them. Requests that fail
+// with a 5xx status are logged anyway.
+func WithLogSkip(paths ...string) Option {
+	return WithLogSkipFunc(func(r *http.Request) bool {
+		return underPaths(r.URL.Path, paths)
 	})
 }
 
-// logger is a middleware that logs the request method and URL
-// and the time it took to process the request.
-func logger(next http.Handler) http.Handler {
-	logger := slog.Default()
-	if os.Getenv("GO_ENV") == "production" {
+// underPaths returns whether the path is one of the
+// paths or is under one of them.
+func underPaths(path string, paths []string) bool {
+	for _, p := range paths {
+		if path == p || strings.HasPrefix(path, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithLogSkipFunc allows to leave out of the access logs the requests for
+// which skip returns true. Requests that fail with a 5xx status are
+// logged anyway.
+func WithLogSkipFunc(skip func(*http.Request) bool) Option {
+	return func(m *mux) {
+		al := m.logging()
+		al.skip = append(al.skip, skip)
+	}
+}
+
+// logging returns the configuration of the access logger of the server,
+// the first time it puts its logger in the place of the default one.
+func (m *mux) logging() *accessLog {
+	if m.accessLog != nil {
+		return m.accessLog
+	}
+
+	m.accessLog = &accessLog{}
+	m.replaceBase("logger", m.accessLog.middleware)
+
+	return m.accessLog
+}
+
+// logger is a middleware that logs the request method and URL, the route
+// and handler that served it, the time it took to process the request to
+// the microsecond and the bytes sent, compressed when they were.
+var logger = (&accessLog{}).middleware
+
+// accessLog is the configuration of the access logger.
+type accessLog struct {
+	format LogFormat
+
+	// slow is the duration over which the requests are warned
+	// about, they aren't when it's zero.
+	slow time.Duration
+
+	// skip are the functions that tell the requests left out of the logs.
+	skip []func(*http.Request) bool
+}
+
+// middleware logs the requests with the configuration of the access log.
+func (al *accessLog) middleware(next http.Handler) http.Handler {
+	fallback := slog.Default()
+	if al.format == LogJSON || os.Getenv("GO_ENV") == "production" {
 		// Using json logger in production
-		logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+		fallback = slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -51,37 +136,248 @@ func logger(next http.Handler) http.Handler {
 		}
 
 		defer func() {
-			status := cmp.Or(lw.Status, http.StatusOK)
-			logLevel := slog.LevelInfo
+			logger := fallback
+			if l, ok := r.Context().Value(loggerKey).(*slog.Logger); ok {
+				logger = l
+			}
+
+			duration := time.Since(start).Round(time.Microsecond)
+			route, handler := loggedRoute(r)
+
+			// the request passed to the handler has the IP set by RealIP.
+			ip := ClientIP(cmp.Or(lw.Request, r))
+
+			// the status of hijacked connections, like
+			// websockets, is not known by the server.
+			status, level := cmp.Or(lw.Status, http.StatusOK), slog.LevelInfo
+			closed := lw.Aborted || clientClosed(r, lw)
+			if closed {
+				status = StatusClientClosed
+			}
+
+			if status >= http.StatusInternalServerError && !lw.Hijacked {
+				level = slog.LevelError
+			}
+
+			if al.slow > 0 && duration > al.slow && !lw.Hijacked && !longRunning(r, lw) {
+				logger.LogAttrs(r.Context(), slog.LevelWarn, "slow request",
+					slog.String("method", r.Method),
+					slog.String("route", route),
+					slog.Duration("duration", duration),
+					slog.String("request_id", RequestID(r)),
+				)
+			}
+
+			if level != slog.LevelError && al.skipped(r) {
+				return
+			}
+
+			if al.format == LogJSON {
+				attrs := []slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("route", route),
+					slog.Int("status", status),
+					slog.Int("bytes", lw.Bytes),
+					slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+					slog.String("remote_ip", ip),
+					slog.String("user_agent", r.UserAgent()),
+					slog.String("referer", r.Referer()),
+					slog.String("request_id", RequestID(r)),
+				}
+
+				if lw.Hijacked {
+					attrs[3] = slog.Bool("hijacked", true)
+				}
+
+				if closed {
+					attrs = append(attrs, slog.Bool("client_closed", true))
+				}
+
+				logger.LogAttrs(r.Context(), level, "request", append(attrs, lw.Attrs...)...)
+				return
+			}
+
+			args := []any{"method", r.Method, "status", status, "url", r.URL.Path, "ip", ip, "request_id", RequestID(r), "route", route, "handler", handler, "duration", duration, "bytes", lw.Bytes, "ua", r.UserAgent()}
+			if lw.Hijacked {
+				args = []any{"method", r.Method, "hijacked", true, "url", r.URL.Path, "ip", ip, "request_id", RequestID(r), "route", route, "handler", handler, "duration", duration, "ua", r.UserAgent()}
+			}
+
+			if referer := r.Referer(); referer != "" {
+				args = append(args, "referer", referer)
+			}
+
+			if closed {
+				args = append(args, "client_closed", true)
+			}
 
-			if status >= http.StatusInternalServerError {
-				logLevel = slog.LevelError
+			for _, attr := range lw.Attrs {
+				args = append(args, attr)
 			}
 
-			logger.Log(r.Context(), logLevel, "", "method", r.Method, "status", status, "url", r.URL.Path, "took", time.Since(start))
+			logger.Log(r.Context(), level, "", args...)
 		}()
 
 		next.ServeHTTP(lw, r)
 	})
 }
 
+// StatusClientClosed is the status the requests whose client went away
+// before they were answered are logged and measured with, like nginx does.
+const StatusClientClosed = 499
+
+// clientClosed returns whether the client of the request went away before
+// it was answered, the handler either wrote nothing or bailed with an error.
+func clientClosed(r *http.Request, lw *response.Writer) bool {
+	if lw.Hijacked || (lw.Status != 0 && lw.Status < http.StatusInternalServerError) {
+		return false
+	}
+
+	return errors.Is(r.Context().Err(), context.Canceled)
+}
+
+// WithSlowRequestThreshold allows to log a warning, besides the access log,
+// for the requests that take longer than the threshold to be served. Routes
+// that run long on purpose, like streams, are left out by setting their
+// longRunning metadata to true, and server-sent events are left out always.
+func WithSlowRequestThreshold(threshold time.Duration) Option {
+	return func(m *mux) {
+		m.logging().slow = threshold
+	}
+}
+
+// longRunning returns whether the request was served by a route that runs
+// long on purpose, marked with the longRunning metadata, or is an event stream.
+func longRunning(r *http.Request, lw *response.Writer) bool {
+	if route, ok := CurrentRoute(cmp.Or(lw.Request, r)); ok && route.Meta["longRunning"] == true {
+		return true
+	}
+
+	return strings.HasPrefix(lw.Header().Get("Content-Type"), "text/event-stream")
+}
+
+// skipped returns whether the request is left out of the logs.
+func (al *accessLog) skipped(r *http.Request) bool {
+	for _, skip := range al.skip {
+		if skip(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loggedRoute returns the pattern and handler name of the route that
+// served the request, the pattern is 404 when no route matched it.
+func loggedRoute(r *http.Request) (string, string) {
+	ref, ok := r.Context().Value(routeKey).(*RouteRef)
+	if !ok || ref.unmatched || ref.index < 0 {
+		return "404", ""
+	}
+
+	route := ref.registry.current().routes[ref.index]
+
+	return route.Pattern, route.Handler
+}
+
 // recoverer is a middleware that recovers from panics and logs the error.
 // The error stack trace is printed only when the application is in 'development' mode.
-func recoverer(next http.Handler) http.Handler {
+var recoverer = (&recovery{}).middleware
+
+// recovery is the configuration of the recoverer.
+type recovery struct {
+	// hooks are the functions set with WithPanicHandler.
+	hooks []PanicHandlerFn
+}
+
+// middleware recovers from the panics of the handler, logs them
+// and calls the hooks before writing the 500 response.
+func (rc *recovery) middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
-			if err := recover(); err != nil {
-				slog.Error("panic", "error", err, "method", r.Method, "url", r.URL.Path)
+			if p := recover(); p != nil {
+				// net/http drops the connection of the handlers
+				// aborted with it, without logging the panic.
+				if p == http.ErrAbortHandler {
+					if rw := response.Root(w); rw != nil {
+						rw.Aborted = true
+					}
+
+					panic(p)
+				}
+
+				stack := debug.Stack()
+				err := &PanicError{Value: p}
+
+				defaultMetrics.panicked(r)
+				serverLogger(r).Error("panic", "error", err.Error(), "method", r.Method, "url", r.URL.Path, "request_id", RequestID(r))
 
 				if cmp.Or(os.Getenv("GO_ENV"), "development") == "development" {
-					os.Stderr.WriteString(fmt.Sprint(err, "\n"))
-					os.Stderr.Write(debug.Stack())
+					os.Stderr.WriteString(fmt.Sprint(err.Error(), " request_id=", RequestID(r), "\n"))
+					os.Stderr.Write(stack)
 				}
 
-				Error(w, fmt.Errorf("%v", err), http.StatusInternalServerError)
+				rc.notify(r, err, stack)
+				Error(w, err, http.StatusInternalServerError)
 			}
 		}()
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// SkipMiddleware omits the middleware registered with the names from the
+// route, the names must have been registered with UseNamed.
+func SkipMiddleware(names ...string) RouteOption {
+	return func(rf *RouteRef) {
+		rf.skip = append(rf.skip, names...)
+	}
+}
+
+// skipNamed returns the middleware without the ones whose name is skipped.
+func skipNamed(middleware []Middleware, names, skip []string) []Middleware {
+	if len(skip) == 0 {
+		return middleware
+	}
+
+	var kept []Middleware
+	for i, mw := range middleware {
+		if i < len(names) && slices.Contains(skip, names[i]) {
+			continue
+		}
+
+		kept = append(kept, mw)
+	}
+
+	return slices.Clip(kept)
+}
+
+// overriding are the code pointers of the middleware that take the place
+// of the ones of the same kind that come before them, like Timeout.
+var overriding = []uintptr{timeoutPC, maxBodyPC}
+
+// closest returns the middleware without the overriding ones
+// that are followed by another of the same kind.
+func closest(middleware []Middleware) []Middleware {
+	last := make(map[uintptr]int)
+	for i, mw := range middleware {
+		if pc := reflect.ValueOf(mw).Pointer(); slices.Contains(overriding, pc) {
+			last[pc] = i
+		}
+	}
+
+	var kept []Middleware
+	for i, mw := range middleware {
+		if j, ok := last[reflect.ValueOf(mw).Pointer()]; ok && i != j {
+			continue
+		}
+
+		kept = append(kept, mw)
+	}
+
+	if len(kept) == len(middleware) {
+		return middleware
+	}
+
+	return kept
+}
@@ -1,18 +1,20 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
-)
-
-// defaultCatchAllHandler to log and return a 404 for all routes except the root route.
-var defaultCatchAllHandler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == "/" {
-		return
-	}
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
-	Error(w, fmt.Errorf("404 page not found"), http.StatusNotFound)
-})
+	"github.com/leapkit/leapkit/core/server/internal/response"
+	"github.com/leapkit/leapkit/core/server/session"
+)
 
 // Rood routeGroup is a group of routes with a common prefix and middleware
 // it also has a host and port as well as a Start method as it is the root of the server
@@ -22,6 +24,77 @@ type mux struct {
 
 	host string
 	port string
+
+	// errorHandlers registered in the server by HTTP status.
+	errorHandlers map[int]ErrorHandlerFn
+
+	// autoOptions enables the automatic responses for
+	// OPTIONS requests on the registered paths.
+	autoOptions bool
+
+	// trailingSlash determines how requests that only match
+	// a route with or without the trailing slash are handled.
+	trailingSlash TrailingSlash
+
+	// caseInsensitive enables matching the literal segments of the
+	// path regardless of their case, redirecting when caseRedirect is set.
+	caseInsensitive bool
+	caseRedirect    bool
+
+	// cleanRedirect redirects the GET and HEAD requests whose path
+	// is not clean instead of serving them with the clean path.
+	cleanRedirect bool
+
+	// accessLog is the configuration of the access logger, set
+	// by the options that change it, nil when it's the default.
+	accessLog *accessLog
+
+	// maintenance is the maintenance mode of the server.
+	maintenance *Maintenance
+
+	// panics is the configuration of the recoverer, set by
+	// WithPanicHandler, nil when it's the default.
+	panics *recovery
+
+	// logger is the logger set with WithLogger, the
+	// requests are served with it in their context.
+	logger *slog.Logger
+
+	// methodOverride routes the POST requests with the method
+	// set by their _method field or X-HTTP-Method-Override header.
+	methodOverride bool
+
+	// session is the session set with WithSession, sessionStore the
+	// store set with WithSessionStore and sessionErrorHandler the one
+	// set with WithSessionErrorHandler for it.
+	session interface {
+		SetStore(session.Store)
+		SetErrorHandler(func(http.ResponseWriter, *http.Request, error))
+	}
+
+	sessionStore        session.Store
+	sessionErrorHandler ErrorHandlerFn
+
+	// namedSessions are the sessions set with WithNamedSession.
+	namedSessions []interface {
+		SetErrorHandler(func(http.ResponseWriter, *http.Request, error))
+	}
+
+	// healthChecks are the checks of the health endpoint set with
+	// WithHealthCheckFunc, healthTimeout how long each one can take.
+	healthChecks  []healthCheck
+	healthTimeout time.Duration
+
+	// fallback serves the requests that don't match any route.
+	fallback http.Handler
+
+	// errorHandlerFn is the errorHandler method value, kept so
+	// it's not allocated for every request.
+	errorHandlerFn func(status int) func(http.ResponseWriter, *http.Request, error)
+
+	// start sets up the catch-all routes and
+	// publishes the table on the first Handler call.
+	start sync.Once
 }
 
 // New creates a new server with the given options and default middleware.
@@ -29,12 +102,31 @@ func New(options ...Option) *mux {
 	ss := &mux{
 		router: &router{
 			prefix:     "",
-			mux:        http.NewServeMux(),
 			middleware: baseMiddleware,
+			names:      baseNames,
+			errors:     &errorScope{},
+			registry: &registry{
+				mux:        newServeMux(),
+				hosts:      map[string]Matcher{},
+				newMatcher: newServeMux,
+				base:       baseMiddleware,
+				baseNames:  baseNames,
+			},
 		},
 
 		host: "0.0.0.0",
 		port: "3000",
+
+		errorHandlers: map[int]ErrorHandlerFn{},
+		maintenance:   NewMaintenance(),
+	}
+
+	ss.UseNamed("maintenance", ss.maintenance.Middleware)
+	ss.UseNamed("devNoCache", devNoCache(os.Getenv("GO_ENV") == "development"))
+
+	ss.errorHandlerFn = ss.errorHandler
+	ss.matches = func(r *http.Request) bool {
+		return len(ss.allowedMethods(r)) > 0
 	}
 
 	for _, option := range options {
@@ -48,16 +140,300 @@ func (s *mux) Router() Router {
 	return s.router
 }
 
+// Handler returns the http.Handler of the server, it panics with the
+// errors returned by Check if there were problems registering the routes.
+// The catch-all routes are set up on the first call and the server is
+// returned as is on the next ones, so it's cheap to call it per request.
+// Routes registered after the first call are served as they're added,
+// each registration publishes the new routes atomically and the requests
+// in flight finish with the routes they started with.
 func (s *mux) Handler() http.Handler {
-	// if no catch-all or root route has been set
-	// we use the default one
-	if !s.rootSet {
-		s.Handle("/", defaultCatchAllHandler)
+	if err := s.Check(); err != nil {
+		panic(err)
 	}
 
+	s.start.Do(func() {
+		// if no catch-all or root route has been set
+		// we use the default one
+		if !s.rootSet("") {
+			s.CatchAll(s.catchAll, func(rf *RouteRef) { rf.unmatched = true })
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// hosts without a catch-all fall back to the host-less routes.
+		s.fallbackHosts()
+		s.publish()
+		s.live = true
+	})
+
 	return s
 }
 
+// writers pools the response writers the server wraps the
+// http.ResponseWriter of the requests with.
+var writers = sync.Pool{
+	New: func() any { return new(response.Writer) },
+}
+
+func (s *mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.logger != nil {
+		r = r.WithContext(context.WithValue(r.Context(), loggerKey, s.logger))
+	}
+
+	// the writers are reused, handlers must not keep
+	// them once they return as http.Handler requires.
+	rw := writers.Get().(*response.Writer)
+	*rw = response.Writer{ResponseWriter: w, Request: r, ErrorHandler: s.errorHandlerFn}
+	defer func() {
+		// the headers of the responses that weren't
+		// written are sent by net/http after returning.
+		if rw.Status == 0 && !rw.Hijacked {
+			rw.RunHeaderHooks()
+		}
+
+		*rw = response.Writer{}
+		writers.Put(rw)
+	}()
+
+	w = rw
+
+	// paths are cleaned before matching them so prefixes like
+	// /admin/ can't be bypassed with //admin/ or /x/../admin/.
+	clean, ok := cleanPath(r.URL.EscapedPath())
+	if !ok {
+		Error(w, fmt.Errorf("path %q goes above the root", r.URL.Path), http.StatusBadRequest)
+		return
+	}
+
+	if clean != r.URL.EscapedPath() {
+		req := cleanAlternative(r, clean)
+		if s.cleanRedirect && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			redirectPermanent(w, r, req.URL.RequestURI())
+			return
+		}
+
+		r = req
+	}
+
+	if s.caseInsensitive {
+		if req := s.caseAlternative(r); req != nil {
+			if s.caseRedirect {
+				redirectPermanent(w, r, req.URL.RequestURI())
+				return
+			}
+
+			r = req.WithContext(context.WithValue(req.Context(), originalURLKey, r.URL))
+		}
+	}
+
+	if s.trailingSlash != Strict {
+		if req := s.slashAlternative(r); req != nil {
+			if s.trailingSlash == Redirect {
+				redirectPermanent(w, r, req.URL.RequestURI())
+				return
+			}
+
+			r = req
+		}
+	}
+
+	if s.methodOverride {
+		if req := overrideMethod(r); req != nil {
+			r = req
+		}
+	}
+
+	t := s.current()
+	if len(t.hosts) > 0 {
+		if host := hostMatch(t.hosts, requestHost(r)); host != "" {
+			t.hosts[host].ServeHTTP(w, r)
+			return
+		}
+	}
+
+	t.mux.ServeHTTP(w, r)
+}
+
+// catchAll handles the requests that did not match any of the registered
+// routes, it returns a 405 when the path is registered for other methods
+// and a 404 for all other routes except the root route. When automatic
+// OPTIONS are enabled it answers those with the allowed methods. When
+// a fallback is set the request is passed to it instead.
+func (s *mux) catchAll(w http.ResponseWriter, r *http.Request) {
+	if h := s.preflight(r); h != nil {
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	if s.fallback != nil {
+		s.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	s.unmatched(w, r)
+}
+
+// unmatched writes the response for the requests that don't match any
+// route, the 405 for the paths with routes for other methods or a 404.
+func (s *mux) unmatched(w http.ResponseWriter, r *http.Request) {
+	if allowed := s.allowedMethods(r); len(allowed) > 0 {
+		if s.autoOptions && r.Method == http.MethodOptions {
+			w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		Error(w, fmt.Errorf("405 method not allowed"), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if r.URL.Path == "/" {
+		return
+	}
+
+	s.writeNotFound(w, r)
+}
+
+// writeNotFound writes the 404 response with the not found handler
+// of the group of the path or the error handler for 404.
+func (rr *registry) writeNotFound(w http.ResponseWriter, r *http.Request) {
+	err := fmt.Errorf("404 page not found")
+	if fn := rr.notFoundFor(r); fn != nil {
+		Logger(r).Error(err.Error())
+		fn(w, r, err)
+
+		return
+	}
+
+	Error(w, err, http.StatusNotFound)
+}
+
+// preflight returns the handler for a CORS preflight request, which runs
+// the middleware of the route for the requested method, so the CORS
+// middleware of a group can answer it. It returns nil for other requests.
+func (s *mux) preflight(r *http.Request) http.Handler {
+	method := r.Header.Get("Access-Control-Request-Method")
+	if r.Method != http.MethodOptions || method == "" || r.Header.Get("Origin") == "" {
+		return nil
+	}
+
+	req := r.Clone(r.Context())
+	req.Method = method
+
+	hm, pattern := s.lookup(req)
+	if pattern == "" {
+		return nil
+	}
+
+	h, _ := hm.Handler(req)
+	d, ok := h.(*dispatcher)
+	if !ok {
+		return nil
+	}
+
+	c := d.match(d.pathValues(req), req.URL)
+	if c == nil || c.ref == nil {
+		return nil
+	}
+
+	return wrap(c.ref.middleware, http.HandlerFunc(s.unmatched))
+}
+
+// allowedMethods returns the methods that have a route registered
+// for the path of the request, looking it up with each of the methods
+// of the table instead of going through all the routes.
+func (s *mux) allowedMethods(r *http.Request) []string {
+	var allowed []string
+	req := r.Clone(r.Context())
+	for _, method := range s.current().methods {
+		if slices.Contains(allowed, method) {
+			continue
+		}
+
+		req.Method = method
+		if _, pattern := s.lookup(req); !strings.HasPrefix(pattern, method+" ") {
+			continue
+		}
+
+		allowed = append(allowed, method)
+		if method == http.MethodGet && !slices.Contains(allowed, http.MethodHead) {
+			allowed = append(allowed, http.MethodHead)
+		}
+	}
+
+	return allowed
+}
+
+// redirectHandlerType is the type of the handler the mux
+// returns for the requests it redirects.
+var redirectHandlerType = reflect.TypeOf(http.RedirectHandler("/", http.StatusMovedPermanently))
+
+// lookup returns the pattern of the route that handles the request and
+// the mux where it's registered, or an empty pattern when there is none.
+// Catch-all routes are not taken into account, routes for the root path
+// only count when the request is for the root path itself. Neither do
+// the redirects the mux does to add the trailing slash to the path.
+func (s *mux) lookup(r *http.Request) (Matcher, string) {
+	t := s.current()
+	muxes := []Matcher{t.mux}
+	if host := hostMatch(t.hosts, requestHost(r)); host != "" {
+		muxes = []Matcher{t.hosts[host], t.mux}
+	}
+
+	for _, hm := range muxes {
+		h, pattern := hm.Handler(r)
+		if reflect.TypeOf(h) == redirectHandlerType {
+			continue
+		}
+
+		// the constraints of the routes with the pattern aren't
+		// met or the route is a fallback for the unmatched paths.
+		if d, ok := h.(*dispatcher); ok {
+			if c := d.match(d.pathValues(r), r.URL); c == nil || c.isFallback() {
+				continue
+			}
+		}
+
+		method, path, found := strings.Cut(pattern, " ")
+		if !found {
+			method, path = "", pattern
+		}
+
+		if pattern == "" || (path == "/" && (method == "" || r.URL.Path != "/")) {
+			continue
+		}
+
+		return hm, pattern
+	}
+
+	return nil, ""
+}
+
+// replaceBase puts the middleware in the place of the base middleware
+// with the name, in the server and in the base the groups get back
+// with ResetMiddleware.
+func (m *mux) replaceBase(name string, mw Middleware) {
+	if i := slices.Index(m.names, name); i >= 0 {
+		m.middleware = slices.Clone(m.middleware)
+		m.middleware[i] = mw
+	}
+
+	if i := slices.Index(m.baseNames, name); i >= 0 {
+		m.base = slices.Clone(m.base)
+		m.base[i] = mw
+	}
+}
+
+// errorHandler returns the handler registered for the status.
+func (s *mux) errorHandler(status int) func(http.ResponseWriter, *http.Request, error) {
+	return s.errorHandlers[status]
+}
+
 func (s *mux) Addr() string {
 	return s.host + ":" + s.port
 }
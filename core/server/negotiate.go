@@ -0,0 +1,133 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Offers are the media types a handler can respond with,
+// with the functions that write the response in each of them.
+type Offers map[string]http.HandlerFunc
+
+// preferred are the media types chosen first when the client
+// accepts several offers equally, like browsers sending */*.
+var preferred = []string{"text/html", "application/json"}
+
+// Negotiate responds with the offer that best matches the Accept header
+// of the request, taking its q-values into account, after setting the
+// Content-Type to the media type of the offer and adding Accept to Vary.
+// When the client accepts several offers equally, the ones it names are
+// chosen over the ones matched by wildcards, like */*, and then text/html
+// is preferred, then application/json and then the rest alphabetically.
+// Requests without Accept header accept any offer, and the ones that
+// don't accept any get a 406 through the error handlers.
+func Negotiate(w http.ResponseWriter, r *http.Request, offers Offers) {
+	w.Header().Add("Vary", "Accept")
+
+	types := make([]string, 0, len(offers))
+	for t := range offers {
+		types = append(types, t)
+	}
+
+	slices.SortFunc(types, func(a, b string) int {
+		ia, ib := slices.Index(preferred, a), slices.Index(preferred, b)
+		switch {
+		case ia >= 0 && ib >= 0:
+			return ia - ib
+		case ia >= 0:
+			return -1
+		case ib >= 0:
+			return 1
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	accepted := parseAccept(cmp.Or(r.Header.Get("Accept"), "*/*"))
+
+	best, bestQ, bestSpecificity := "", 0.0, -1
+	for _, t := range types {
+		// the offers named in the header win over the ones matched by
+		// a wildcard, and the rest of the ties go to the first offer,
+		// as they're sorted by preference.
+		q, specificity := accepted.quality(t)
+		if q > bestQ || (q > 0 && q == bestQ && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = t, q, specificity
+		}
+	}
+
+	if best == "" {
+		Error(w, fmt.Errorf("406 not acceptable, the response can be %s", strings.Join(types, ", ")), http.StatusNotAcceptable)
+		return
+	}
+
+	w.Header().Set("Content-Type", best)
+	offers[best](w, r)
+}
+
+// mediaRange is a media range of the Accept header, like text/* or
+// application/json, with its q-value.
+type mediaRange struct {
+	kind, subtype string
+	q             float64
+}
+
+// acceptRanges are the media ranges of an Accept header.
+type acceptRanges []mediaRange
+
+// parseAccept returns the media ranges of the Accept header, the ones
+// that can't be parsed are left out.
+func parseAccept(header string) acceptRanges {
+	var ranges acceptRanges
+	for _, part := range strings.Split(header, ",") {
+		media, params, _ := strings.Cut(part, ";")
+		kind, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(media)), "/")
+		if !ok || kind == "" || subtype == "" {
+			continue
+		}
+
+		mr := mediaRange{kind: kind, subtype: subtype, q: 1}
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					mr.q = q
+				}
+			}
+		}
+
+		ranges = append(ranges, mr)
+	}
+
+	return ranges
+}
+
+// quality returns the q-value of the media type, the one of the most
+// specific range that matches it, and how specific the range is, 2 for
+// the media type, 1 for a wildcard subtype and 0 for */*. It returns 0
+// and -1 when none of the ranges match it.
+func (ar acceptRanges) quality(mediaType string) (float64, int) {
+	kind, subtype, _ := strings.Cut(strings.ToLower(mediaType), "/")
+
+	q, specificity := 0.0, -1
+	for _, mr := range ar {
+		s := -1
+		switch {
+		case mr.kind == kind && mr.subtype == subtype:
+			s = 2
+		case mr.kind == kind && mr.subtype == "*":
+			s = 1
+		case mr.kind == "*" && mr.subtype == "*":
+			s = 0
+		}
+
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+
+	return q, specificity
+}
@@ -0,0 +1,75 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestNegotiate(t *testing.T) {
+	write := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}
+	}
+
+	s := server.New(
+		server.WithErrorHandler(http.StatusNotAcceptable, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusNotAcceptable)
+			w.Write([]byte("not acceptable"))
+		}),
+	)
+
+	s.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {
+		server.Negotiate(w, r, server.Offers{
+			"application/json": write("json"),
+			"text/html":        write("html"),
+			"text/csv":         write("csv"),
+		})
+	})
+
+	h := s.Handler()
+	cases := []struct {
+		name   string
+		accept string
+		code   int
+		body   string
+	}{
+		{"browser", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8", http.StatusOK, "html"},
+		{"API client", "application/json", http.StatusOK, "json"},
+		{"no header", "", http.StatusOK, "html"},
+		{"any", "*/*", http.StatusOK, "html"},
+		{"fetch", "application/json, text/plain, */*", http.StatusOK, "json"},
+		{"text wildcard", "text/*", http.StatusOK, "html"},
+		{"q-values", "text/html;q=0.5, text/csv;q=0.9", http.StatusOK, "csv"},
+		{"refused", "*/*, text/html;q=0", http.StatusOK, "json"},
+		{"parameters", "application/json; charset=utf-8; q=0.8, text/html;level=1;q=0.2", http.StatusOK, "json"},
+		{"nothing matches", "image/png", http.StatusNotAcceptable, "not acceptable"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			if c.accept != "" {
+				req.Header.Set("Accept", c.accept)
+			}
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			if res.Code != c.code || res.Body.String() != c.body {
+				t.Errorf("Expected %d %q, got %d %q", c.code, c.body, res.Code, res.Body.String())
+			}
+
+			if res.Header().Get("Vary") != "Accept" {
+				t.Errorf("Expected Vary: Accept, got %q", res.Header().Get("Vary"))
+			}
+
+			if c.code == http.StatusOK && res.Header().Get("Content-Type") != map[string]string{"html": "text/html", "json": "application/json", "csv": "text/csv"}[c.body] {
+				t.Errorf("Expected the Content-Type of the offer, got %q", res.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
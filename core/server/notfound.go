@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// notFoundHandler is a handler for the unmatched
+// paths under the prefix of a group.
+type notFoundHandler struct {
+	host   string
+	prefix string
+	fn     ErrorHandlerFn
+}
+
+// NotFound allows to set the handler for the requests that don't match any
+// route under the prefix of the router. When groups are nested the handler
+// of the most specific one is used, falling back to the server handlers.
+func (rg *router) NotFound(fn ErrorHandlerFn) {
+	rg.update(func() {
+		rg.notFound = append(rg.notFound, notFoundHandler{
+			host:   rg.host,
+			prefix: strings.TrimSuffix(rg.prefix, "/"),
+			fn:     fn,
+		})
+	})
+}
+
+// notFoundFor returns the not found handler of the group with the
+// longest prefix that matches the path of the request, nil if none.
+func (rr *registry) notFoundFor(r *http.Request) ErrorHandlerFn {
+	t := rr.current()
+	host := hostMatch(t.hosts, requestHost(r))
+
+	var match *notFoundHandler
+	for i, nf := range t.notFound {
+		if nf.host != "" && nf.host != host {
+			continue
+		}
+
+		if !matchPrefix(nf.prefix, r.URL.Path) {
+			continue
+		}
+
+		if match == nil || len(nf.prefix) > len(match.prefix) || (len(nf.prefix) == len(match.prefix) && nf.host != "") {
+			match = &t.notFound[i]
+		}
+	}
+
+	if match == nil {
+		return nil
+	}
+
+	return match.fn
+}
@@ -0,0 +1,73 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestNotFound(t *testing.T) {
+	notFound := func(name string) server.ErrorHandlerFn {
+		return func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(name + ": " + err.Error()))
+		}
+	}
+
+	s := server.New(
+		server.WithErrorHandler(http.StatusNotFound, notFound("server")),
+	)
+
+	s.Group("/api", func(r server.Router) {
+		r.NotFound(notFound("api"))
+		r.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("users"))
+		})
+
+		r.Group("/v2/", func(r server.Router) {
+			r.NotFound(notFound("v2"))
+		})
+	})
+
+	s.Host("admin.example.com", func(r server.Router) {
+		r.NotFound(notFound("admin"))
+	})
+
+	cases := []struct {
+		host string
+		path string
+		code int
+		body string
+	}{
+		{"", "/api/users", http.StatusOK, "users"},
+		{"", "/api/unknown", http.StatusNotFound, "api: 404 page not found"},
+		{"", "/api", http.StatusNotFound, "api: 404 page not found"},
+		{"", "/api/v2/users", http.StatusNotFound, "v2: 404 page not found"},
+		{"", "/apix", http.StatusNotFound, "server: 404 page not found"},
+		{"", "/other", http.StatusNotFound, "server: 404 page not found"},
+		{"admin.example.com", "/other", http.StatusNotFound, "admin: 404 page not found"},
+		{"admin.example.com", "/api/unknown", http.StatusNotFound, "api: 404 page not found"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.host+c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			if c.host != "" {
+				req.Host = c.host
+			}
+
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code {
+				t.Errorf("Expected status %d, got %d", c.code, res.Code)
+			}
+
+			if res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+		})
+	}
+}
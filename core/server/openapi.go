@@ -0,0 +1,289 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OpenAPI is a minimal OpenAPI 3 document built from the registered
+// routes, it's meant to be a skeleton to enrich with schemas and
+// descriptions rather than a complete description of the API.
+type OpenAPI struct {
+	OpenAPI string                          `json:"openapi"`
+	Info    OpenAPIInfo                     `json:"info"`
+	Paths   map[string]map[string]Operation `json:"paths"`
+}
+
+// OpenAPIInfo is the metadata of the API in the document.
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Operation is a method on a path of the OpenAPI document.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter is a path parameter of an operation.
+type Parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Schema   Schema `json:"schema"`
+}
+
+// Schema is the type of a parameter.
+type Schema struct {
+	Type string `json:"type"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Description string `json:"description"`
+}
+
+// openAPIMethods are the methods supported by OpenAPI operations.
+var openAPIMethods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+// OpenAPISpec builds an OpenAPI 3 document with the routes registered in the
+// server. Routes without a method, like mounted handlers, are not included.
+// The operationId is taken from the "operationId" metadata of the route or
+// the name of the handler, and the summary from the "description" metadata.
+func (rg *router) OpenAPISpec() *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: "API", Version: "1.0.0"},
+		Paths:   map[string]map[string]Operation{},
+	}
+
+	ids := map[string]bool{}
+	for _, route := range rg.current().routes {
+		for _, method := range route.methods() {
+			if !slices.Contains(openAPIMethods, method) {
+				continue
+			}
+
+			route := route
+			route.Method = method
+			doc.add(route, ids)
+		}
+	}
+
+	return doc
+}
+
+// add adds the operation for the route, which has a single method.
+func (doc *OpenAPI) add(route Route, ids map[string]bool) {
+	path, params := openAPIPath(route.Pattern)
+	op := Operation{
+		OperationID: operationID(route, ids),
+		Responses:   map[string]Response{"default": {Description: "Default response"}},
+	}
+
+	if summary, ok := route.Meta["description"].(string); ok {
+		op.Summary = summary
+	}
+
+	for _, name := range params {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   Schema{Type: "string"},
+		})
+	}
+
+	if doc.Paths[path] == nil {
+		doc.Paths[path] = map[string]Operation{}
+	}
+
+	doc.Paths[path][strings.ToLower(route.Method)] = op
+}
+
+// openAPIPath converts the pattern of a route into an OpenAPI
+// path and returns the names of its parameters.
+func openAPIPath(pattern string) (string, []string) {
+	var params []string
+
+	pattern, format := parseFormat(pattern)
+	segs := strings.Split(strings.TrimSuffix(pattern, "{$}"), "/")
+	for i, seg := range segs {
+		if !strings.HasPrefix(seg, "{") {
+			continue
+		}
+
+		name, _, _ := strings.Cut(strings.Trim(seg, "{}"), ":")
+		name = strings.TrimSuffix(name, "...")
+		params = append(params, name)
+		segs[i] = "{" + name + "}"
+	}
+
+	path := strings.Join(segs, "/")
+	if format != "" {
+		name := wildcardNames(format)[0]
+		params = append(params, name)
+		path += ".{" + name + "}"
+	}
+
+	return path, params
+}
+
+// operationID returns a unique operationId for the route, using the
+// metadata or handler name and falling back to the method and path.
+func operationID(route Route, used map[string]bool) string {
+	id, _ := route.Meta["operationId"].(string)
+	if id == "" && !strings.Contains(route.Handler, ".func") {
+		id = route.Handler[strings.LastIndex(route.Handler, ".")+1:]
+	}
+
+	if id == "" || used[id] {
+		id = strings.ToLower(route.Method)
+		for _, seg := range strings.FieldsFunc(route.Pattern, func(r rune) bool {
+			return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+		}) {
+			id += strings.ToUpper(seg[:1]) + seg[1:]
+		}
+	}
+
+	used[id] = true
+
+	return id
+}
+
+// JSON encodes the document as indented JSON.
+func (o *OpenAPI) JSON() ([]byte, error) {
+	return json.MarshalIndent(o, "", "  ")
+}
+
+// YAML encodes the document as YAML.
+func (o *OpenAPI) YAML() ([]byte, error) {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	writeYAML(&b, v, 0)
+
+	return b.Bytes(), nil
+}
+
+// writeYAML writes the decoded JSON value as YAML in block style,
+// keys are sorted and string values are always quoted.
+func writeYAML(b *bytes.Buffer, v any, indent int) {
+	pad := strings.Repeat("  ", indent)
+
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		slices.Sort(keys)
+		for _, k := range keys {
+			b.WriteString(pad + yamlKey(k) + ":")
+			writeYAMLValue(b, v[k], indent)
+		}
+	case []any:
+		for _, item := range v {
+			if m, ok := item.(map[string]any); ok && len(m) > 0 {
+				// the first key of a map goes next to the dash.
+				var nested bytes.Buffer
+				writeYAML(&nested, m, indent+1)
+				b.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+
+				continue
+			}
+
+			b.WriteString(pad + "-")
+			writeYAMLValue(b, item, indent)
+		}
+	}
+}
+
+// yamlKey returns the key quoted unless it only
+// has characters that are safe in a plain key.
+func yamlKey(k string) string {
+	for _, r := range k {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_') {
+			return strconv.Quote(k)
+		}
+	}
+
+	return k
+}
+
+// writeYAMLValue writes the value of a key or list item, nesting
+// maps and lists and writing scalars on the same line.
+func writeYAMLValue(b *bytes.Buffer, v any, indent int) {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+
+		b.WriteString("\n")
+		writeYAML(b, v, indent+1)
+	case []any:
+		if len(v) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+
+		b.WriteString("\n")
+		writeYAML(b, v, indent+1)
+	case string:
+		b.WriteString(" " + strconv.Quote(v) + "\n")
+	case nil:
+		b.WriteString(" null\n")
+	default:
+		b.WriteString(fmt.Sprintf(" %v\n", v))
+	}
+}
+
+// WithOpenAPI serves the OpenAPI document of the server routes at
+// /openapi.json and /openapi.yaml when running in development.
+func WithOpenAPI() Option {
+	return func(m *mux) {
+		if cmp.Or(os.Getenv("GO_ENV"), "development") != "development" {
+			return
+		}
+
+		m.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+			serveOpenAPI(w, m.OpenAPISpec().JSON, "application/json")
+		})
+
+		m.HandleFunc("GET /openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+			serveOpenAPI(w, m.OpenAPISpec().YAML, "application/yaml")
+		})
+	}
+}
+
+func serveOpenAPI(w http.ResponseWriter, encode func() ([]byte, error), contentType string) {
+	data, err := encode()
+	if err != nil {
+		Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
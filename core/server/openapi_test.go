@@ -0,0 +1,131 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	s := server.New()
+	s.Group("/orgs/{org}/", func(r server.Router) {
+		r.HandleFunc("GET /users/{id}", listUsers).Meta("description", "Show a user")
+		r.HandleFunc("DELETE /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+		r.HandleFunc("GET /files/{path...}", listUsers)
+	})
+
+	s.Mount("/debug", http.NotFoundHandler())
+
+	doc := s.OpenAPISpec()
+	if doc.OpenAPI != "3.0.3" {
+		t.Errorf("Expected openapi 3.0.3, got %q", doc.OpenAPI)
+	}
+
+	if len(doc.Paths) != 2 {
+		t.Fatalf("Expected 2 paths, got %v", doc.Paths)
+	}
+
+	user := doc.Paths["/orgs/{org}/users/{id}"]
+	expected := server.Operation{
+		OperationID: "listUsers",
+		Summary:     "Show a user",
+		Parameters: []server.Parameter{
+			{Name: "org", In: "path", Required: true, Schema: server.Schema{Type: "string"}},
+			{Name: "id", In: "path", Required: true, Schema: server.Schema{Type: "string"}},
+		},
+		Responses: map[string]server.Response{"default": {Description: "Default response"}},
+	}
+
+	if !reflect.DeepEqual(user["get"], expected) {
+		t.Errorf("Expected operation %+v, got %+v", expected, user["get"])
+	}
+
+	if id := user["delete"].OperationID; id != "deleteOrgsOrgUsersId" {
+		t.Errorf("Expected operationId deleteOrgsOrgUsersId, got %q", id)
+	}
+
+	files := doc.Paths["/orgs/{org}/files/{path}"]["get"]
+	if files.OperationID != "getOrgsOrgFilesPath" {
+		t.Errorf("Expected a unique operationId, got %q", files.OperationID)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		data, err := doc.JSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded server.OpenAPI
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(&decoded, doc) {
+			t.Errorf("Expected the decoded document to match")
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		data, err := doc.YAML()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, line := range []string{
+			`openapi: "3.0.3"`,
+			`  "/orgs/{org}/users/{id}":`,
+			`      operationId: "listUsers"`,
+			`        - in: "path"`,
+			`          required: true`,
+		} {
+			if !strings.Contains(string(data), line+"\n") {
+				t.Errorf("Expected %q in:\n%s", line, data)
+			}
+		}
+	})
+}
+
+func TestWithOpenAPI(t *testing.T) {
+	t.Setenv("GO_ENV", "development")
+
+	s := server.New(server.WithOpenAPI())
+	s.HandleFunc("GET /users", listUsers)
+
+	for path, contentType := range map[string]string{"/openapi.json": "application/json", "/openapi.yaml": "application/yaml"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, res.Code)
+			}
+
+			if ct := res.Header().Get("Content-Type"); ct != contentType {
+				t.Errorf("Expected content type %q, got %q", contentType, ct)
+			}
+
+			if !strings.Contains(res.Body.String(), "/users") {
+				t.Errorf("Expected the routes in the document, got %s", res.Body.String())
+			}
+		})
+	}
+
+	t.Run("production", func(t *testing.T) {
+		t.Setenv("GO_ENV", "production")
+
+		s := server.New(server.WithOpenAPI())
+		req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, res.Code)
+		}
+	})
+}
@@ -27,17 +27,81 @@ func WithPort(port string) Option {
 	}
 }
 
-// WithSession allows to set the session within the application.
+// WithSession allows to set the session within the application, with the
+// middleware of session.Middleware, named session so groups and routes can
+// skip it. Errors saving the session go through the error handlers.
 func WithSession(secret, name string, options ...session.Option) Option {
 	sw := session.New(secret, name, options...)
+	sw.SetLogger(Logger)
+	sw.SetErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		Error(w, err, http.StatusInternalServerError)
+	})
+
 	return func(m *mux) {
-		m.Use(func(h http.Handler) http.Handler {
-			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				w, r = sw.Register(w, r)
+		m.session = sw
+		if m.sessionStore != nil {
+			sw.SetStore(m.sessionStore)
+		}
 
-				h.ServeHTTP(w, r)
-			})
-		})
+		if m.sessionErrorHandler != nil {
+			sw.SetErrorHandler(m.sessionErrorHandler)
+		}
+
+		m.UseNamed("session", sw.Wrap)
+	}
+}
+
+// WithNamedSession allows to add a session other than the one of
+// WithSession, with its own cookie, secret and options, like a long-lived
+// one for the preferences of the user. Handlers get it with session.Named,
+// while session.FromCtx keeps returning the one of WithSession.
+func WithNamedSession(name, secret string, options ...session.Option) Option {
+	sw := session.New(secret, name, options...)
+	sw.SetPrimary(false)
+	sw.SetLogger(Logger)
+	sw.SetErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		Error(w, err, http.StatusInternalServerError)
+	})
+
+	return func(m *mux) {
+		m.namedSessions = append(m.namedSessions, sw)
+		if m.sessionErrorHandler != nil {
+			sw.SetErrorHandler(m.sessionErrorHandler)
+		}
+
+		m.UseNamed("session:"+name, sw.Wrap)
+	}
+}
+
+// WithSessionStore keeps the values of the session in the store, and
+// only its signed ID in the cookie, instead of the whole session in the
+// cookie. It's used with WithSession, in any order.
+func WithSessionStore(store session.Store) Option {
+	return func(m *mux) {
+		m.sessionStore = store
+		if m.session != nil {
+			m.session.SetStore(store)
+		}
+	}
+}
+
+// WithSessionErrorHandler sets the function called when the sessions can't
+// be saved, like when the store is down, instead of responding with the
+// 500 error handler. The response it writes replaces the one of the
+// handler, which is sent when it doesn't write any, so the apps can go on
+// without saving the session. The changes to the session made after the
+// response was sent are logged as errors. It's used with WithSession, in
+// any order.
+func WithSessionErrorHandler(fn ErrorHandlerFn) Option {
+	return func(m *mux) {
+		m.sessionErrorHandler = fn
+		if m.session != nil {
+			m.session.SetErrorHandler(fn)
+		}
+
+		for _, sw := range m.namedSessions {
+			sw.SetErrorHandler(fn)
+		}
 	}
 }
 
@@ -63,3 +127,43 @@ func WithErrorMessage(status int, message string) Option {
 		errorMessageMap[status] = message
 	}
 }
+
+// WithErrorHandler allows to register a function that writes the response
+// for the passed HTTP status. It's called by server.Error and by the server
+// for unmatched routes (404), methods not allowed (405) and panics (500).
+func WithErrorHandler(status int, fn ErrorHandlerFn) Option {
+	return func(m *mux) {
+		m.errorHandlers[status] = fn
+	}
+}
+
+// WithAutoOptions enables automatic responses for OPTIONS requests on
+// the paths that have at least one route registered. These respond 204 with
+// the Allow header listing the registered methods, and go through the middleware
+// of the server. OPTIONS handlers registered explicitly take precedence.
+func WithAutoOptions() Option {
+	return func(m *mux) {
+		m.autoOptions = true
+	}
+}
+
+// WithFallback sets the handler for the requests that don't match any of
+// the routes, including the ones for paths registered for other methods,
+// which is useful to migrate from another router gradually. The fallback
+// runs after the middleware of the server and takes the place of the 404
+// and 405 responses and the NotFound handlers of the groups.
+func WithFallback(h http.Handler) Option {
+	return func(m *mux) {
+		m.fallback = h
+	}
+}
+
+// WithStrictRoot makes the root patterns, like "GET /" or "/", only match
+// the root path instead of every path that doesn't match another route, so
+// unknown paths get a 404 through the error handlers. Handlers for every path
+// under a prefix can still be registered with CatchAll.
+func WithStrictRoot() Option {
+	return func(m *mux) {
+		m.strictRoot = true
+	}
+}
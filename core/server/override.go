@@ -0,0 +1,41 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Override replaces the handler of the routes registered with the pattern,
+// like "POST /payments", which is useful to stub handlers in tests. The new
+// handler runs with the middleware of the original route. It returns an error
+// when no route has the pattern or Handler has already been called.
+func (s *mux) Override(pattern string, handler http.Handler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.live {
+		return errors.New("routes can't be overridden after Handler is called")
+	}
+
+	pattern = strings.Join(strings.Fields(pattern), " ")
+
+	var found bool
+	for _, ref := range s.refs {
+		route := &s.routes[ref.index]
+		if strings.TrimSpace(route.Method+" "+route.Pattern) != pattern {
+			continue
+		}
+
+		ref.handler = wrap(ref.middleware, handler)
+		route.Handler = handlerName(handler)
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("route %q is not registered", pattern)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestOverride(t *testing.T) {
+	routes := func(s server.Router) {
+		s.Use(server.InCtxMiddleware("customValue", "from middleware"))
+		s.Group("/api/", func(r server.Router) {
+			r.HandleFunc("POST /payments", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("charged"))
+			})
+		})
+	}
+
+	t.Run("replaces the handler", func(t *testing.T) {
+		s := server.New()
+		routes(s)
+
+		err := s.Override("POST /api/payments", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("stubbed " + r.Context().Value("customValue").(string)))
+		}))
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/payments", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Body.String() != "stubbed from middleware" {
+			t.Errorf("Expected body %q, got %q", "stubbed from middleware", res.Body.String())
+		}
+	})
+
+	t.Run("unknown pattern", func(t *testing.T) {
+		s := server.New()
+		routes(s)
+
+		err := s.Override("POST /api/payment", http.NotFoundHandler())
+		if err == nil || err.Error() != `route "POST /api/payment" is not registered` {
+			t.Errorf("Expected a not registered error, got %v", err)
+		}
+	})
+
+	t.Run("after Handler", func(t *testing.T) {
+		s := server.New()
+		routes(s)
+
+		s.Handler()
+
+		if err := s.Override("POST /api/payments", http.NotFoundHandler()); err == nil {
+			t.Errorf("Expected an error overriding after Handler")
+		}
+	})
+}
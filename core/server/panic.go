@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// PanicHandlerFn is a function that is notified of the panics recovered
+// by the server, it receives the panic as a *PanicError, the stack trace
+// of the goroutine that panicked and the request being served.
+type PanicHandlerFn func(ctx context.Context, err error, stack []byte, r *http.Request)
+
+// WithPanicHandler allows to register a function that is notified of the
+// panics recovered by the server, like to report them to an error tracker.
+// The functions are called in the order they were registered, after the
+// panic is logged and before the 500 response is written, and a panic in
+// one of them is logged without affecting the response.
+func WithPanicHandler(fn PanicHandlerFn) Option {
+	return func(m *mux) {
+		rc := m.recovery()
+		rc.hooks = append(rc.hooks, fn)
+	}
+}
+
+// recovery returns the configuration of the recoverer of the server,
+// the first time it puts its recoverer in the place of the default one.
+func (m *mux) recovery() *recovery {
+	if m.panics != nil {
+		return m.panics
+	}
+
+	m.panics = &recovery{}
+	m.replaceBase("recoverer", m.panics.middleware)
+
+	return m.panics
+}
+
+// notify calls the hooks with the panic, recovering from their panics.
+func (rc *recovery) notify(r *http.Request, err error, stack []byte) {
+	for _, hook := range rc.hooks {
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					serverLogger(r).Error("panic handler panicked", "error", p, "request_id", RequestID(r))
+				}
+			}()
+
+			hook(r.Context(), err, stack, r)
+		}()
+	}
+}
+
+// PanicError is the error the panics recovered by the server are turned
+// into, it's passed to the panic handlers and to the error handler for
+// 500, which can get the value the handler panicked with from Value.
+type PanicError struct {
+	Value any
+}
+
+// Error returns the message of the panic, the message of the error, the
+// string or the Stringer the handler panicked with, or its value formatted
+// with %v for the rest.
+func (pe *PanicError) Error() string {
+	switch v := pe.Value.(type) {
+	case error:
+		return v.Error()
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Errorf("%v", v).Error()
+	}
+}
+
+// Unwrap returns the error the handler panicked with, so errors.Is
+// and errors.As see through the panic, nil for the other values.
+func (pe *PanicError) Unwrap() error {
+	err, _ := pe.Value.(error)
+	return err
+}
@@ -0,0 +1,216 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestWithPanicHandler(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	type report struct {
+		err   error
+		stack string
+		path  string
+	}
+
+	var reports []report
+	errBoom := errors.New("boom")
+
+	s := server.New(
+		server.WithPanicHandler(func(ctx context.Context, err error, stack []byte, r *http.Request) {
+			panic("tracker is down")
+		}),
+		server.WithPanicHandler(func(ctx context.Context, err error, stack []byte, r *http.Request) {
+			if server.RequestID(r) == "" || ctx != r.Context() {
+				t.Error("Expected the request served with its context")
+			}
+
+			reports = append(reports, report{err, string(stack), r.URL.Path})
+		}),
+	)
+
+	s.HandleFunc("GET /error", func(w http.ResponseWriter, r *http.Request) {
+		panic(errBoom)
+	})
+
+	s.HandleFunc("GET /string", func(w http.ResponseWriter, r *http.Request) {
+		panic("something happened")
+	})
+
+	s.Group("/reset", func(r server.Router) {
+		r.ResetMiddleware()
+		r.HandleFunc("GET /error", func(w http.ResponseWriter, r *http.Request) {
+			panic(errBoom)
+		})
+	})
+
+	h := s.Handler()
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return res
+	}
+
+	t.Run("hooks receive the panic", func(t *testing.T) {
+		reports = nil
+		if res := serve("/error"); res.Code != http.StatusInternalServerError {
+			t.Errorf("Expected 500 even when a hook panics, got %d", res.Code)
+		}
+
+		if len(reports) != 1 {
+			t.Fatalf("Expected the panic to be reported once, got %d", len(reports))
+		}
+
+		if !errors.Is(reports[0].err, errBoom) || reports[0].path != "/error" {
+			t.Errorf("Expected the error and request of the panic, got %v %s", reports[0].err, reports[0].path)
+		}
+
+		if !strings.Contains(reports[0].stack, "panic_test.go") {
+			t.Errorf("Expected the stack of the panic, got %q", reports[0].stack)
+		}
+
+		if !strings.Contains(logs.String(), "panic handler panicked") {
+			t.Errorf("Expected the panic of the hook to be logged, got %q", logs.String())
+		}
+	})
+
+	t.Run("values that are not errors", func(t *testing.T) {
+		reports = nil
+		serve("/string")
+
+		if len(reports) != 1 || reports[0].err.Error() != "something happened" {
+			t.Errorf("Expected the panic converted to an error, got %v", reports)
+		}
+	})
+
+	t.Run("groups that reset the middleware", func(t *testing.T) {
+		reports = nil
+		serve("/reset/error")
+
+		if len(reports) != 1 || reports[0].path != "/reset/error" {
+			t.Errorf("Expected the panic to be reported once, got %v", reports)
+		}
+	})
+
+	t.Run("without hooks", func(t *testing.T) {
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		s := server.New()
+		s.HandleFunc("GET /error", func(w http.ResponseWriter, r *http.Request) {
+			panic(errBoom)
+		})
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/error", nil))
+		if res.Code != http.StatusInternalServerError {
+			t.Errorf("Expected 500, got %d", res.Code)
+		}
+	})
+}
+
+// stringer is a panic value that implements fmt.Stringer.
+type stringer struct{ name string }
+
+func (s stringer) String() string { return "stringer " + s.name }
+
+// customError is a panic value that implements error.
+type customError struct{ code int }
+
+func (e customError) Error() string { return fmt.Sprintf("custom error %d", e.code) }
+
+func TestPanicValues(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var handled, hooked error
+	s := server.New(
+		server.WithErrorHandler(http.StatusInternalServerError, func(w http.ResponseWriter, r *http.Request, err error) {
+			handled = err
+			w.WriteHeader(http.StatusInternalServerError)
+		}),
+		server.WithPanicHandler(func(ctx context.Context, err error, stack []byte, r *http.Request) {
+			hooked = err
+		}),
+	)
+
+	cases := []struct {
+		name    string
+		value   any
+		message string
+	}{
+		{"error", customError{42}, "custom error 42"},
+		{"string", "boom", "boom"},
+		{"stringer", stringer{"hello"}, "stringer hello"},
+		{"struct", struct{ ID int }{7}, "{7}"},
+		{"int", 500, "500"},
+	}
+
+	for _, c := range cases {
+		s.HandleFunc("GET /"+c.name, func(w http.ResponseWriter, r *http.Request) {
+			panic(c.value)
+		})
+	}
+
+	h := s.Handler()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			current := os.Stderr
+			r, w, _ := os.Pipe()
+			os.Stderr = w
+			t.Cleanup(func() { os.Stderr = current })
+			t.Setenv("GO_ENV", "development")
+
+			handled, hooked = nil, nil
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/"+c.name, nil))
+
+			w.Close()
+			var stderr bytes.Buffer
+			io.Copy(&stderr, r)
+
+			if res.Code != http.StatusInternalServerError {
+				t.Errorf("Expected 500, got %d", res.Code)
+			}
+
+			if handled == nil || handled.Error() != c.message {
+				t.Errorf("Expected the error handler to receive %q, got %v", c.message, handled)
+			}
+
+			var pe *server.PanicError
+			if !errors.As(hooked, &pe) || pe.Value != c.value {
+				t.Errorf("Expected the hook to receive the value %v, got %v", c.value, hooked)
+			}
+
+			if !strings.Contains(stderr.String(), c.message) || !strings.Contains(stderr.String(), "panic_test.go") {
+				t.Errorf("Expected the message and stack printed, got %q", stderr.String())
+			}
+		})
+	}
+
+	t.Run("errors are unwrapped", func(t *testing.T) {
+		var ce customError
+		err := &server.PanicError{Value: customError{42}}
+		if !errors.As(err, &ce) || ce.code != 42 {
+			t.Errorf("Expected the panicked error to be unwrapped, got %v", ce)
+		}
+
+		if (&server.PanicError{Value: "boom"}).Unwrap() != nil {
+			t.Error("Expected no error to unwrap for a string")
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ParamError is returned by the path helpers when the value
+// of a path parameter can't be parsed into the expected type.
+type ParamError struct {
+	// Name of the path parameter.
+	Name string
+	// Value received in the request path.
+	Value string
+	// Expected is the description of the expected type.
+	Expected string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("invalid path parameter %q: expected %s, got %q", e.Name, e.Expected, e.Value)
+}
+
+// PathInt returns the path parameter with the given name as an int.
+func PathInt(r *http.Request, name string) (int, error) {
+	v := r.PathValue(name)
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, &ParamError{Name: name, Value: v, Expected: "an integer"}
+	}
+
+	return i, nil
+}
+
+// PathInt64 returns the path parameter with the given name as an int64.
+func PathInt64(r *http.Request, name string) (int64, error) {
+	v := r.PathValue(name)
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, &ParamError{Name: name, Value: v, Expected: "a 64-bit integer"}
+	}
+
+	return i, nil
+}
+
+// PathBool returns the path parameter with the given name as a bool,
+// it accepts the values supported by strconv.ParseBool.
+func PathBool(r *http.Request, name string) (bool, error) {
+	v := r.PathValue(name)
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, &ParamError{Name: name, Value: v, Expected: "a boolean"}
+	}
+
+	return b, nil
+}
+
+// PathUUID returns the path parameter with the given name when it's a UUID
+// in its canonical xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx form, lowercased.
+func PathUUID(r *http.Request, name string) (string, error) {
+	v := r.PathValue(name)
+	if !isUUID(v) {
+		return "", &ParamError{Name: name, Value: v, Expected: "a UUID"}
+	}
+
+	return strings.ToLower(v), nil
+}
+
+// PathParam parses the path parameter with the given name using one of
+// the path helpers, when the value is invalid it writes a 400 response
+// through the registered error handler and returns false.
+//
+//	id, ok := server.PathParam(w, r, "id", server.PathInt)
+//	if !ok {
+//		return
+//	}
+func PathParam[T any](w http.ResponseWriter, r *http.Request, name string, parse func(*http.Request, string) (T, error)) (T, bool) {
+	v, err := parse(r, name)
+	if err != nil {
+		Error(w, err, http.StatusBadRequest)
+		return v, false
+	}
+
+	return v, true
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,102 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestPathHelpers(t *testing.T) {
+	s := server.New()
+	s.HandleFunc("GET /int/{v}", func(w http.ResponseWriter, r *http.Request) {
+		v, err := server.PathInt(r, "v")
+		fmt.Fprintf(w, "%v %v", v, err)
+	})
+
+	s.HandleFunc("GET /int64/{v}", func(w http.ResponseWriter, r *http.Request) {
+		v, err := server.PathInt64(r, "v")
+		fmt.Fprintf(w, "%v %v", v, err)
+	})
+
+	s.HandleFunc("GET /bool/{v}", func(w http.ResponseWriter, r *http.Request) {
+		v, err := server.PathBool(r, "v")
+		fmt.Fprintf(w, "%v %v", v, err)
+	})
+
+	s.HandleFunc("GET /uuid/{v}", func(w http.ResponseWriter, r *http.Request) {
+		v, err := server.PathUUID(r, "v")
+		fmt.Fprintf(w, "%v %v", v, err)
+	})
+
+	cases := []struct {
+		path string
+		body string
+	}{
+		{"/int/42", "42 <nil>"},
+		{"/int/abc", `0 invalid path parameter "v": expected an integer, got "abc"`},
+		{"/int64/9223372036854775807", "9223372036854775807 <nil>"},
+		{"/int64/1.5", `0 invalid path parameter "v": expected a 64-bit integer, got "1.5"`},
+		{"/bool/true", "true <nil>"},
+		{"/bool/yes", `false invalid path parameter "v": expected a boolean, got "yes"`},
+		{"/uuid/6BA7B810-9DAD-11D1-80B4-00C04FD430C8", "6ba7b810-9dad-11d1-80b4-00c04fd430c8 <nil>"},
+		{"/uuid/6ba7b810", ` invalid path parameter "v": expected a UUID, got "6ba7b810"`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+		})
+	}
+}
+
+func TestPathParam(t *testing.T) {
+	s := server.New(
+		server.WithErrorHandler(http.StatusBadRequest, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+		}),
+	)
+
+	s.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := server.PathParam(w, r, "id", server.PathInt)
+		if !ok {
+			return
+		}
+
+		fmt.Fprintf(w, "user %d", id)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusOK || res.Body.String() != "user 7" {
+			t.Errorf("Expected 200 'user 7', got %d %q", res.Code, res.Body.String())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/seven", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, res.Code)
+		}
+
+		if !strings.Contains(res.Body.String(), `"id"`) || !strings.Contains(res.Body.String(), `"seven"`) {
+			t.Errorf("Expected the parameter name and value in %q", res.Body.String())
+		}
+	})
+}
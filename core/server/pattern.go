@@ -0,0 +1,79 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// checkPattern returns an error for the common mistakes in the pattern
+// passed when registering a route, before it's joined with the prefix.
+func checkPattern(pattern string) error {
+	if pattern != strings.TrimSpace(pattern) {
+		return errors.New("pattern has leading or trailing spaces")
+	}
+
+	if method, _, found := strings.Cut(pattern, "/"); found && method != "" && !strings.Contains(method, " ") {
+		if checkMethods(strings.Split(method, ",")) == nil && method == strings.ToUpper(method) && !strings.Contains(method, ".") {
+			return fmt.Errorf("missing space between the method %q and the path", method)
+		}
+	}
+
+	return nil
+}
+
+// checkPath returns an error when the braces of the path aren't balanced,
+// {$} is not at the end or a parameter name is used more than once, which
+// includes the names used in the prefix of the group.
+func checkPath(prefix, path string) error {
+	depth := 0
+	for _, r := range path {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+
+		if depth < 0 {
+			return errors.New("unbalanced braces in the path")
+		}
+	}
+
+	if depth != 0 {
+		return errors.New("unbalanced braces in the path")
+	}
+
+	if i := strings.Index(path, "{$}"); i >= 0 && i != len(path)-len("{$}") {
+		return errors.New("{$} can only be at the end of the path")
+	}
+
+	inPrefix := paramNames(prefix)
+	var names []string
+	for _, name := range paramNames(path) {
+		if slices.Contains(names, name) {
+			if slices.Contains(inPrefix, name) {
+				return fmt.Errorf("parameter %q shadows the one of the group prefix %q", name, prefix)
+			}
+
+			return fmt.Errorf("parameter %q is used more than once", name)
+		}
+
+		names = append(names, name)
+	}
+
+	return nil
+}
+
+// paramNames returns the names of the parameters of the path,
+// including the one of its format.
+func paramNames(path string) []string {
+	path, format := parseFormat(path)
+	names := wildcardNames(path)
+	if format != "" {
+		names = append(names, wildcardNames(format)...)
+	}
+
+	return names
+}
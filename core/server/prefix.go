@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// stripPrefix is like http.StripPrefix but the prefix can contain path
+// parameters, like /orgs/{org}, which match any value in their segment.
+func stripPrefix(prefix string, h http.Handler) http.Handler {
+	if !strings.Contains(prefix, "{") {
+		return http.StripPrefix(prefix, h)
+	}
+
+	n := strings.Count(prefix, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		segs := strings.SplitN(r.URL.Path, "/", n+2)
+		if len(segs) <= n {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.StripPrefix(strings.Join(segs[:n+1], "/"), h).ServeHTTP(w, r)
+	})
+}
+
+// matchPrefix returns whether the path is the prefix or is under it,
+// segments of the prefix with path parameters match any value.
+func matchPrefix(prefix, path string) bool {
+	psegs := strings.Split(prefix, "/")
+	segs := strings.Split(path, "/")
+	if len(segs) < len(psegs) {
+		return false
+	}
+
+	for i, seg := range psegs {
+		if seg == segs[i] || (strings.HasPrefix(seg, "{") && segs[i] != "") {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// Proxy allows to forward the requests under the prefix to the target,
+// which is useful while migrating from another service. The prefix is
+// stripped from the path, which is joined with the path of the target,
+// and the Host header is set to the host of the target. Errors reaching
+// the target are written through the error handlers with a 502, or a 504
+// when it timed out.
+func (rg *router) Proxy(prefix string, target *url.URL) {
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+		},
+
+		ErrorHandler: proxyError,
+	}
+
+	rg.Mount(prefix, proxy)
+}
+
+// proxyError writes the error of a proxied request, with a 504 when
+// the target timed out and a 502 for the rest of the errors.
+func proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadGateway
+
+	var nerr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &nerr) && nerr.Timeout()) {
+		status = http.StatusGatewayTimeout
+	}
+
+	Error(w, err, status)
+}
@@ -0,0 +1,105 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old/stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("first\n"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+
+		if r.URL.Path == "/old/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		w.Header().Set("X-Group", r.Header.Get("X-Group"))
+		w.Write([]byte(r.Host + " " + r.URL.RequestURI()))
+	}))
+
+	defer backend.Close()
+
+	target, _ := url.Parse(backend.URL + "/old")
+	closed := httptest.NewServer(http.NotFoundHandler())
+	unreachable, _ := url.Parse(closed.URL)
+	closed.Close()
+
+	errorHandler := func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("proxy error"))
+	}
+
+	s := server.New(
+		server.WithErrorHandler(http.StatusBadGateway, errorHandler),
+		server.WithErrorHandler(http.StatusGatewayTimeout, errorHandler),
+	)
+
+	s.Group("/", func(r server.Router) {
+		r.Proxy("/legacy/", target)
+		r.Proxy("/down/", unreachable)
+	}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("X-Group", "true")
+
+			ctx, cancel := context.WithTimeout(r.Context(), 50*time.Millisecond)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	t.Run("forwards the request", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/legacy/users?page=2", nil))
+
+		expected := target.Host + " /old/users?page=2"
+		if res.Body.String() != expected {
+			t.Errorf("Expected body %q, got %q", expected, res.Body.String())
+		}
+
+		if res.Header().Get("X-Group") != "true" {
+			t.Errorf("Expected the group middleware to run")
+		}
+	})
+
+	t.Run("streams the response", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/legacy/stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer res.Body.Close()
+
+		line, err := bufio.NewReader(res.Body).ReadString('\n')
+		if err != nil || line != "first\n" {
+			t.Errorf("Expected the first line to be flushed, got %q %v", line, err)
+		}
+	})
+
+	for path, status := range map[string]int{"/down/users": http.StatusBadGateway, "/legacy/slow": http.StatusGatewayTimeout} {
+		t.Run(path, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if res.Code != http.StatusTeapot || res.Body.String() != "proxy error" {
+				t.Errorf("Expected the %d error handler response, got %d %q", status, res.Code, res.Body.String())
+			}
+		})
+	}
+}
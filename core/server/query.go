@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+)
+
+// queryMatcher is a pattern a query parameter of the requests
+// to a route must match, like invoice.* for the type parameter.
+type queryMatcher struct {
+	key     string
+	pattern string
+}
+
+// match returns whether any of the values of the parameter matches.
+func (qm queryMatcher) match(query url.Values) bool {
+	for _, v := range query[qm.key] {
+		if ok, _ := path.Match(qm.pattern, v); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchQuery makes the route only serve the requests with a value of the
+// query parameter that matches the pattern, which can be an exact value or
+// a glob like invoice.*. It allows several routes with the same pattern to
+// serve different requests, the ones that don't match any of them go to the
+// route with the pattern that has no matchers or get a 404.
+func (rf *RouteRef) MatchQuery(key, pattern string) *RouteRef {
+	rr := rf.registry
+	if _, err := path.Match(pattern, ""); err != nil {
+		rr.report(fmt.Errorf("invalid query pattern %q for %q registered at %s: %v", pattern, key, callerSource(), err))
+		return rf
+	}
+
+	rr.update(func() {
+		rf.query = append(slices.Clip(rf.query), queryMatcher{key: key, pattern: pattern})
+
+		// the candidates take the matchers when they're added.
+		rr.rebuild()
+
+		// the route conflicted with another one with the same
+		// pattern, the matcher could tell them apart now.
+		if rf.register != nil {
+			rr.errs = slices.DeleteFunc(rr.errs, func(err error) bool { return err == rf.err })
+			if err := rf.register(); err != nil {
+				rr.errs = append(rr.errs, err)
+				rf.err = err
+
+				return
+			}
+
+			rf.register, rf.err = nil, nil
+		}
+
+		if rf.index < 0 {
+			return
+		}
+
+		// the map is copied as the published tables share it.
+		route := &rr.routes[rf.index]
+		route.Query = maps.Clone(route.Query)
+		if route.Query == nil {
+			route.Query = map[string]string{}
+		}
+
+		route.Query[key] = pattern
+	})
+
+	return rf
+}
@@ -0,0 +1,86 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestMatchQuery(t *testing.T) {
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}
+	}
+
+	s := server.New()
+	s.HandleFunc("POST /hooks", handler("invoices")).MatchQuery("type", "invoice.*")
+	s.HandleFunc("POST /hooks", handler("customers")).MatchQuery("type", "customer.created")
+	s.HandleFunc("POST /events", handler("paid")).MatchQuery("type", "invoice.paid").MatchQuery("live", "true")
+
+	s.Group("/slack/", func(r server.Router) {
+		r.HandleFunc("POST /hooks", handler("default"))
+		r.HandleFunc("POST /hooks", handler("commands")).MatchQuery("kind", "command")
+	})
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/hooks?type=invoice.paid", http.StatusOK, "invoices"},
+		{"/hooks?type=customer.created", http.StatusOK, "customers"},
+		{"/hooks?type=customer.deleted", http.StatusNotFound, ""},
+		{"/hooks", http.StatusNotFound, ""},
+		{"/events?type=invoice.paid&live=true", http.StatusOK, "paid"},
+		{"/events?type=invoice.paid", http.StatusNotFound, ""},
+		{"/slack/hooks?kind=command", http.StatusOK, "commands"},
+		{"/slack/hooks?kind=event", http.StatusOK, "default"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code {
+				t.Errorf("Expected status %d, got %d", c.code, res.Code)
+			}
+
+			if c.body != "" && res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+		})
+	}
+
+	t.Run("routes", func(t *testing.T) {
+		if err := s.Check(); err != nil {
+			t.Fatalf("Expected no errors, got %v", err)
+		}
+
+		routes := s.Routes()
+		expected := map[string]string{"type": "invoice.paid", "live": "true"}
+		if !reflect.DeepEqual(routes[2].Query, expected) {
+			t.Errorf("Expected query %v, got %v", expected, routes[2].Query)
+		}
+
+		if routes[3].Query != nil {
+			t.Errorf("Expected no query for the default route, got %v", routes[3].Query)
+		}
+	})
+
+	t.Run("conflict without matchers", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("POST /hooks", handler("a"))
+		s.HandleFunc("POST /hooks", handler("b"))
+
+		if err := s.Check(); err == nil || !strings.Contains(err.Error(), "conflicts with") {
+			t.Errorf("Expected a conflict error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitStore keeps the requests made by each client, the memory
+// store is used by default and others, like one backed by Redis, can
+// be passed to RateLimit with WithRateLimitStore.
+type RateLimitStore interface {
+	// Take takes a request from the ones the key can make in the window,
+	// it returns false and how long to wait when there are none left.
+	Take(key string, limit int, window time.Duration) (bool, time.Duration, error)
+}
+
+// RateLimitOption allows to configure the RateLimit middleware.
+type RateLimitOption func(*rateLimit)
+
+// WithRateLimitStore sets the store that keeps the requests of the clients.
+func WithRateLimitStore(store RateLimitStore) RateLimitOption {
+	return func(rl *rateLimit) {
+		rl.store = store
+	}
+}
+
+// rateLimit is the configuration of the RateLimit middleware.
+type rateLimit struct {
+	store RateLimitStore
+}
+
+// RateLimit returns a middleware that allows each client to make up to
+// limit requests in the window, the ones exceeding it get a 429 with a
+// Retry-After header through the error handlers. Clients are told apart
+// by the key returned by keyFn, which defaults to ClientIP, the IP resolved
+// by RealIP or the remote address of the request. Requests are let through
+// when the store fails.
+func RateLimit(limit int, window time.Duration, keyFn func(*http.Request) string, options ...RateLimitOption) Middleware {
+	rl := &rateLimit{store: NewMemoryRateLimitStore()}
+	for _, option := range options {
+		option(rl)
+	}
+
+	if keyFn == nil {
+		keyFn = ClientIP
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait, err := rl.store.Take(keyFn(r), limit, window)
+			if err != nil {
+				Logger(r).Error("rate limit store failed", "error", err)
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				Error(w, fmt.Errorf("429 too many requests"), http.StatusTooManyRequests)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ForwardedForKey is a RateLimit key function that returns the first IP of
+// the X-Forwarded-For header, or ClientIP when there is none. Clients can
+// set the header to anything, so it's only safe behind a proxy that
+// replaces it, otherwise RealIP with the proxies as trusted resolves the
+// IP of the client for the default key.
+func ForwardedForKey(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		ip, _, _ := strings.Cut(fwd, ",")
+		return strings.TrimSpace(ip)
+	}
+
+	return ClientIP(r)
+}
+
+// NewMemoryRateLimitStore returns a RateLimitStore that keeps a token
+// bucket for each client in memory. Buckets that have been idle for
+// long enough to be full again are removed.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimit{buckets: map[string]*bucket{}}
+}
+
+// memoryRateLimit is the in memory RateLimitStore.
+type memoryRateLimit struct {
+	moot    sync.Mutex
+	buckets map[string]*bucket
+
+	// swept is when the idle buckets were last removed.
+	swept time.Time
+}
+
+// bucket holds the tokens of a client, a token is added
+// every window/limit up to limit and each request takes one.
+type bucket struct {
+	tokens float64
+	last   time.Time
+	window time.Duration
+}
+
+func (m *memoryRateLimit) Take(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	m.moot.Lock()
+	defer m.moot.Unlock()
+
+	now := time.Now()
+	m.sweep(now, window)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit), last: now, window: window}
+		m.buckets[key] = b
+	}
+
+	rate := float64(limit) / window.Seconds()
+	b.tokens = min(float64(limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return false, wait, nil
+	}
+
+	b.tokens--
+
+	return true, 0, nil
+}
+
+// sweep removes the buckets that have been idle for longer than their
+// window, which are full again, at most once every window.
+func (m *memoryRateLimit) sweep(now time.Time, window time.Duration) {
+	if now.Sub(m.swept) < window {
+		return
+	}
+
+	m.swept = now
+	for key, b := range m.buckets {
+		if now.Sub(b.last) >= b.window {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package server_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+// failingStore is a RateLimitStore that always fails.
+type failingStore struct{}
+
+func (failingStore) Take(string, int, time.Duration) (bool, time.Duration, error) {
+	return false, 0, errors.New("store is down")
+}
+
+func TestRateLimit(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+
+	forwarded := 0
+	serve := func(s http.Handler, ip string) *httptest.ResponseRecorder {
+		// clients can send any X-Forwarded-For header.
+		forwarded++
+
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = ip + ":51000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("10.0.0.%d", forwarded))
+
+		res := httptest.NewRecorder()
+		s.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("limits each client", func(t *testing.T) {
+		s := server.New(
+			server.WithErrorHandler(http.StatusTooManyRequests, func(w http.ResponseWriter, r *http.Request, err error) {
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte("slow down"))
+			}),
+		)
+
+		s.Use(server.RateLimit(2, time.Minute, nil))
+		s.HandleFunc("POST /login", ok)
+		h := s.Handler()
+
+		for i := 0; i < 2; i++ {
+			if res := serve(h, "1.1.1.1"); res.Code != http.StatusOK {
+				t.Fatalf("Expected request %d to be allowed, got %d", i+1, res.Code)
+			}
+		}
+
+		res := serve(h, "1.1.1.1")
+		if res.Code != http.StatusTooManyRequests || res.Body.String() != "slow down" {
+			t.Errorf("Expected the error handler for 429, got %d %q", res.Code, res.Body.String())
+		}
+
+		if res.Header().Get("Retry-After") != "30" {
+			t.Errorf("Expected Retry-After 30, got %q", res.Header().Get("Retry-After"))
+		}
+
+		if res := serve(h, "2.2.2.2"); res.Code != http.StatusOK {
+			t.Errorf("Expected another client to be allowed, got %d", res.Code)
+		}
+	})
+
+	t.Run("forwarded header behind a proxy", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.RateLimit(1, time.Minute, server.ForwardedForKey))
+		s.HandleFunc("POST /login", ok)
+		h := s.Handler()
+
+		// the proxy replaces the header with the IP of the client.
+		proxied := func(ip string) int {
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req.Header.Set("X-Forwarded-For", ip+", 10.0.0.1")
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			return res.Code
+		}
+
+		if proxied("1.1.1.1") != http.StatusOK || proxied("2.2.2.2") != http.StatusOK {
+			t.Fatal("Expected the clients to be told apart by the header")
+		}
+
+		if code := proxied("1.1.1.1"); code != http.StatusTooManyRequests {
+			t.Errorf("Expected the client over the limit to get 429, got %d", code)
+		}
+	})
+
+	t.Run("tokens are refilled over the window", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.RateLimit(1, 50*time.Millisecond, func(r *http.Request) string { return "all" }))
+		s.HandleFunc("POST /login", ok)
+		h := s.Handler()
+
+		serve(h, "1.1.1.1")
+		if res := serve(h, "2.2.2.2"); res.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected the key function to group the clients, got %d", res.Code)
+		}
+
+		time.Sleep(60 * time.Millisecond)
+		if res := serve(h, "1.1.1.1"); res.Code != http.StatusOK {
+			t.Errorf("Expected the request to be allowed after the window, got %d", res.Code)
+		}
+	})
+
+	t.Run("store errors let the requests through", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.RateLimit(1, time.Minute, nil, server.WithRateLimitStore(failingStore{})))
+		s.HandleFunc("POST /login", ok)
+
+		if res := serve(s.Handler(), "1.1.1.1"); res.Code != http.StatusOK {
+			t.Errorf("Expected the request to be allowed, got %d", res.Code)
+		}
+	})
+}
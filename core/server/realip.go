@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// realIPKey is the context key for the IP of the client resolved by RealIP.
+const realIPKey contextKey = "realIP"
+
+// RealIP returns a middleware that resolves the IP of the client for the
+// requests that come through the trusted proxies, like a load balancer.
+// The Forwarded, X-Forwarded-For or X-Real-IP header, in that order, is
+// walked from the right skipping the addresses of the trusted proxies,
+// and the first one that isn't trusted is the client. The headers of
+// requests that don't come from a trusted proxy are ignored, so clients
+// can't spoof their IP with them. The IP is set as the RemoteAddr of the
+// request and returned by ClientIP, and it's the one the logger and the
+// RateLimit middleware use.
+func RealIP(trustedProxies ...netip.Prefix) Middleware {
+	trusted := func(addr netip.Addr) bool {
+		for _, p := range trustedProxies {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, ok := parseIP(r.RemoteAddr)
+			if ok && trusted(ip) {
+				chain := forwardedFor(r.Header)
+				for i := len(chain) - 1; i >= 0; i-- {
+					addr, ok := parseIP(chain[i])
+					if !ok {
+						// what's left of the chain can't be trusted.
+						break
+					}
+
+					ip = addr
+					if !trusted(addr) {
+						break
+					}
+				}
+			}
+
+			if ip.IsValid() {
+				r = r.WithContext(context.WithValue(r.Context(), realIPKey, ip.String()))
+				r.RemoteAddr = ip.String()
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the IP of the client resolved by the RealIP
+// middleware, or the one of the remote address of the request.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(realIPKey).(string); ok {
+		return ip
+	}
+
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// forwardedFor returns the addresses the request was forwarded for,
+// from the client to the last proxy.
+func forwardedFor(h http.Header) []string {
+	var chain []string
+	if values := h.Values("Forwarded"); len(values) > 0 {
+		for _, value := range values {
+			for _, element := range strings.Split(value, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+					if strings.EqualFold(key, "for") {
+						chain = append(chain, strings.Trim(value, `"`))
+					}
+				}
+			}
+		}
+
+		return chain
+	}
+
+	if values := h.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, value := range values {
+			for _, addr := range strings.Split(value, ",") {
+				chain = append(chain, strings.TrimSpace(addr))
+			}
+		}
+
+		return chain
+	}
+
+	if ip := h.Get("X-Real-IP"); ip != "" {
+		return []string{strings.TrimSpace(ip)}
+	}
+
+	return nil
+}
+
+// parseIP parses an IP address with or without a port,
+// IPv6 addresses with a port are enclosed in brackets.
+func parseIP(s string) (netip.Addr, bool) {
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
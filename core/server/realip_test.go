@@ -0,0 +1,101 @@
+package server_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestRealIP(t *testing.T) {
+	s := server.New()
+	s.Use(server.RealIP(netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")))
+	s.HandleFunc("GET /ip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(server.ClientIP(r) + " " + r.RemoteAddr))
+	})
+
+	h := s.Handler()
+
+	cases := []struct {
+		name     string
+		remote   string
+		headers  map[string]string
+		expected string
+	}{
+		{"no proxy", "203.0.113.9:5555", nil, "203.0.113.9"},
+		{"untrusted source is not believed", "203.0.113.9:5555", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "203.0.113.9"},
+		{"trusted proxy", "10.0.0.1:443", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+		{"spoofed entries on the left", "10.0.0.1:443", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7, 10.0.0.2"}, "198.51.100.7"},
+		{"all trusted", "10.0.0.1:443", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"invalid entry", "10.0.0.1:443", map[string]string{"X-Forwarded-For": "garbage, 10.0.0.2"}, "10.0.0.2"},
+		{"port suffix", "10.0.0.1:443", map[string]string{"X-Forwarded-For": "198.51.100.7:4711"}, "198.51.100.7"},
+		{"x-real-ip", "10.0.0.1:443", map[string]string{"X-Real-IP": "198.51.100.7"}, "198.51.100.7"},
+		{"forwarded", "10.0.0.1:443", map[string]string{"Forwarded": `for=192.0.2.60;proto=https, for="[2001:db8::1]:4711"`}, "2001:db8::1"},
+		{"forwarded first", "10.0.0.1:443", map[string]string{"Forwarded": "for=192.0.2.60", "X-Forwarded-For": "198.51.100.7"}, "192.0.2.60"},
+		{"ipv6 proxy", "[fd00::1]:443", map[string]string{"X-Forwarded-For": "2001:db8::2"}, "2001:db8::2"},
+		{"ipv4 mapped", "[::ffff:10.0.0.1]:443", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = c.remote
+			for k, v := range c.headers {
+				req.Header.Set(k, v)
+			}
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			if expected := c.expected + " " + c.expected; res.Body.String() != expected {
+				t.Errorf("Expected %q, got %q", expected, res.Body.String())
+			}
+		})
+	}
+
+	t.Run("logger and rate limit use the real IP", func(t *testing.T) {
+		var logs bytes.Buffer
+		defer slog.SetDefault(slog.Default())
+		slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+		s := server.New()
+		s.Use(server.RealIP(netip.MustParsePrefix("10.0.0.0/8")))
+		s.Use(server.RateLimit(1, time.Minute, nil))
+		s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {})
+		h := s.Handler()
+
+		serve := func(forwarded string) int {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:443"
+			req.Header.Set("X-Forwarded-For", forwarded)
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			return res.Code
+		}
+
+		if code := serve("198.51.100.7"); code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", code)
+		}
+
+		if !strings.Contains(logs.String(), "ip=198.51.100.7") {
+			t.Errorf("Expected the real IP in the log, got %q", logs.String())
+		}
+
+		// the spoofed entry on the left doesn't give the client a new key.
+		if code := serve("1.2.3.4, 198.51.100.7"); code != http.StatusTooManyRequests {
+			t.Errorf("Expected 429 for the same client, got %d", code)
+		}
+
+		if code := serve("198.51.100.8"); code != http.StatusOK {
+			t.Errorf("Expected 200 for another client, got %d", code)
+		}
+	})
+}
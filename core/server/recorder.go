@@ -0,0 +1,61 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// recorder records the status, the headers and the body of the response
+// written to the writer, like the Cache, Idempotency and Debug middleware
+// do. The body is recorded up to one byte more than the limit, so it can
+// be told whether it was truncated.
+type recorder struct {
+	http.ResponseWriter
+
+	status int
+	header http.Header
+	body   bytes.Buffer
+	max    int
+
+	// flushed is set when the response has been streamed.
+	flushed bool
+}
+
+func (rw *recorder) WriteHeader(status int) {
+	// informational responses are sent before the final headers.
+	if rw.status == 0 && status >= http.StatusOK {
+		rw.status = status
+		rw.header = rw.ResponseWriter.Header().Clone()
+	}
+
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	if n := rw.max + 1 - rw.body.Len(); n > 0 {
+		rw.body.Write(b[:min(n, len(b))])
+	}
+
+	return rw.ResponseWriter.Write(b)
+}
+
+// whole returns whether the whole response was recorded, it wasn't
+// when the body is larger than the limit or it was streamed.
+func (rw *recorder) whole() bool {
+	return !rw.flushed && rw.body.Len() <= rw.max
+}
+
+// Flush sends the response written so far.
+func (rw *recorder) Flush() {
+	rw.flushed = true
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the wrapped http.ResponseWriter.
+func (rw *recorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
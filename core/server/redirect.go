@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redirect allows to register a route that redirects the requests to the
+// target with the passed status. Path parameters of the pattern can be used
+// in the target, like /accounts/{id}/settings, and the query is preserved.
+func (rg *router) Redirect(pattern, target string, status int) {
+	rg.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		url := expandParams(target, r)
+		if r.URL.RawQuery != "" {
+			sep := "?"
+			if strings.Contains(url, "?") {
+				sep = "&"
+			}
+
+			url += sep + r.URL.RawQuery
+		}
+
+		http.Redirect(w, r, url, status)
+	}))
+}
+
+// expandParams replaces the {name} and {name...} parameters in the
+// target with the path values of the request. The values are escaped,
+// so they can't add a host or a query to the target, the slashes of the
+// {name...} values are kept as they span several segments.
+func expandParams(target string, r *http.Request) string {
+	var b strings.Builder
+	for {
+		before, rest, found := strings.Cut(target, "{")
+		name, after, closed := strings.Cut(rest, "}")
+		if !found || !closed {
+			b.WriteString(target)
+			return b.String()
+		}
+
+		b.WriteString(before)
+		segs := []string{r.PathValue(name)}
+		if strings.HasSuffix(name, "...") {
+			segs = strings.Split(r.PathValue(strings.TrimSuffix(name, "...")), "/")
+		}
+
+		for i, seg := range segs {
+			segs[i] = url.PathEscape(seg)
+		}
+
+		b.WriteString(strings.Join(segs, "/"))
+		target = after
+	}
+}
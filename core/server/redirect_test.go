@@ -0,0 +1,65 @@
+package server_test
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestRedirect(t *testing.T) {
+	output := new(strings.Builder)
+	log.SetOutput(output)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	s := server.New()
+	s.Redirect("GET /signup", "/register", http.StatusMovedPermanently)
+	s.Group("/users/", func(r server.Router) {
+		r.Redirect("GET /{id}/edit", "/accounts/{id}/settings?tab=profile", http.StatusFound)
+		r.Redirect("POST /{id}/files/{path...}", "/files/{id}/{path...}", http.StatusPermanentRedirect)
+	})
+
+	s.Redirect("GET /blog/{slug}", "/{slug}", http.StatusMovedPermanently)
+
+	testCases := []struct {
+		method   string
+		target   string
+		code     int
+		location string
+	}{
+		{http.MethodGet, "/signup", http.StatusMovedPermanently, "/register"},
+		{http.MethodGet, "/signup?ref=home&a=1", http.StatusMovedPermanently, "/register?ref=home&a=1"},
+		{http.MethodGet, "/users/42/edit?x=y", http.StatusFound, "/accounts/42/settings?tab=profile&x=y"},
+		{http.MethodPost, "/users/42/files/a/b.txt", http.StatusPermanentRedirect, "/files/42/a/b.txt"},
+		{http.MethodPost, "/users/42/files/a/b%3Fc.txt", http.StatusPermanentRedirect, "/files/42/a/b%3Fc.txt"},
+		{http.MethodGet, "/blog/%2Fevil.com", http.StatusMovedPermanently, "/%2Fevil.com"},
+		{http.MethodGet, "/blog/a%3Fb%3Dc", http.StatusMovedPermanently, "/a%3Fb=c"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.target, func(t *testing.T) {
+			output.Reset()
+
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != tc.code {
+				t.Errorf("Expected status %d, got %d", tc.code, res.Code)
+			}
+
+			if loc := res.Header().Get("Location"); loc != tc.location {
+				t.Errorf("Expected location %q, got %q", tc.location, loc)
+			}
+
+			if !strings.Contains(output.String(), "status="+strconv.Itoa(tc.code)) {
+				t.Errorf("Expected the redirect to be logged, got %q", output.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"cmp"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// defaultHSTS is the Strict-Transport-Security header sent by
+// default, it tells the browsers to use HTTPS for two years.
+const defaultHSTS = "max-age=63072000; includeSubDomains"
+
+// RedirectHTTPSOption allows to configure the RedirectHTTPS middleware.
+type RedirectHTTPSOption func(*redirectHTTPS)
+
+// WithRedirectHTTPSSkip sets the paths, and the ones under them, that are
+// served over plain HTTP, like the ACME challenges or the health checks.
+func WithRedirectHTTPSSkip(paths ...string) RedirectHTTPSOption {
+	return func(rh *redirectHTTPS) {
+		rh.skip = append(rh.skip, paths...)
+	}
+}
+
+// WithRedirectHTTPSHSTS sets the Strict-Transport-Security header to the
+// responses to HTTPS requests, it defaults to max-age=63072000;
+// includeSubDomains when the value is empty.
+func WithRedirectHTTPSHSTS(value string) RedirectHTTPSOption {
+	return func(rh *redirectHTTPS) {
+		rh.hsts = cmp.Or(value, defaultHSTS)
+	}
+}
+
+// WithRedirectHTTPSLocalhost redirects the requests to localhost too,
+// which are served over plain HTTP by default for local development.
+func WithRedirectHTTPSLocalhost() RedirectHTTPSOption {
+	return func(rh *redirectHTTPS) {
+		rh.localhost = true
+	}
+}
+
+// redirectHTTPS is the configuration of the RedirectHTTPS middleware.
+type redirectHTTPS struct {
+	skip      []string
+	hsts      string
+	localhost bool
+}
+
+// RedirectHTTPS returns a middleware that redirects the plain HTTP requests
+// to the same URL over HTTPS with a 308, so the method and body are kept.
+// Requests made over TLS, or through a proxy that sets X-Forwarded-Proto to
+// https, are served. Requests to localhost are served as is by default so
+// the development server keeps working.
+func RedirectHTTPS(options ...RedirectHTTPSOption) Middleware {
+	rh := &redirectHTTPS{}
+	for _, option := range options {
+		option(rh)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if secure(r) {
+				if rh.hsts != "" {
+					w.Header().Set("Strict-Transport-Security", rh.hsts)
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			host := requestHost(r)
+			if host == "" || underPaths(r.URL.Path, rh.skip) || (!rh.localhost && localhost(host)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			target := "https://" + strings.TrimSuffix(r.Host, ":80") + r.URL.RequestURI()
+			http.Redirect(w, r, target, http.StatusPermanentRedirect)
+		})
+	}
+}
+
+// secure returns whether the request was made over HTTPS,
+// directly or through a proxy that sets X-Forwarded-Proto.
+func secure(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+
+	// proxies chained append their scheme, the first one is the client's.
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+// localhost returns whether the host is the local machine.
+func localhost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,91 @@
+package server_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestRedirectHTTPS(t *testing.T) {
+	s := server.New()
+	s.Use(server.RedirectHTTPS(
+		server.WithRedirectHTTPSSkip("/.well-known/acme-challenge", "/healthz"),
+		server.WithRedirectHTTPSHSTS(""),
+	))
+
+	s.HandleFunc("/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	h := s.Handler()
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("plain HTTP", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/orders?page=2&sort=desc", nil)
+		res := serve(req)
+		if res.Code != http.StatusPermanentRedirect {
+			t.Fatalf("Expected 308, got %d", res.Code)
+		}
+
+		if loc := res.Header().Get("Location"); loc != "https://example.com/orders?page=2&sort=desc" {
+			t.Errorf("Expected the full URI over HTTPS, got %q", loc)
+		}
+
+		if res.Header().Get("Strict-Transport-Security") != "" {
+			t.Error("Expected no HSTS over plain HTTP")
+		}
+	})
+
+	t.Run("secure requests", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/orders", nil)
+		req.TLS = &tls.ConnectionState{}
+		res := serve(req)
+		if res.Code != http.StatusOK {
+			t.Errorf("Expected TLS requests to be served, got %d", res.Code)
+		}
+
+		if hsts := res.Header().Get("Strict-Transport-Security"); hsts != "max-age=63072000; includeSubDomains" {
+			t.Errorf("Expected the default HSTS, got %q", hsts)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/orders", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		if res := serve(req); res.Code != http.StatusOK {
+			t.Errorf("Expected requests forwarded over HTTPS to be served, got %d", res.Code)
+		}
+	})
+
+	t.Run("skipped paths", func(t *testing.T) {
+		for _, path := range []string{"/.well-known/acme-challenge/token", "/healthz"} {
+			if res := serve(httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil)); res.Code != http.StatusOK {
+				t.Errorf("Expected %s to be served over HTTP, got %d", path, res.Code)
+			}
+		}
+	})
+
+	t.Run("localhost", func(t *testing.T) {
+		for _, host := range []string{"localhost:3000", "127.0.0.1:3000", "[::1]:3000", "app.localhost"} {
+			if res := serve(httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil)); res.Code != http.StatusOK {
+				t.Errorf("Expected %s to be served over HTTP, got %d", host, res.Code)
+			}
+		}
+
+		s := server.New()
+		s.Use(server.RedirectHTTPS(server.WithRedirectHTTPSLocalhost()))
+		s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {})
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "http://localhost/", nil))
+		if res.Code != http.StatusPermanentRedirect || res.Header().Get("Location") != "https://localhost/" {
+			t.Errorf("Expected localhost to be redirected, got %d %q", res.Code, res.Header().Get("Location"))
+		}
+	})
+}
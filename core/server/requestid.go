@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// requestIDKey is the context key for the ID of the request.
+const requestIDKey contextKey = "requestID"
+
+// requestIDHeader is the header the ID of the request
+// is read from and written to the response.
+const requestIDHeader = "X-Request-ID"
+
+// WithoutRequestID allows to disable the middleware that
+// sets an ID to the requests, it's part of the base middleware.
+func WithoutRequestID() Option {
+	return func(m *mux) {
+		m.Skip("requestID")
+	}
+}
+
+// RequestID returns the ID of the request, empty
+// when the request ID middleware is disabled.
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey).(string)
+	return id
+}
+
+// requestID is a middleware that sets an ID to the request, the one of the
+// X-Request-ID header when it's valid or a random one, and writes it to the
+// same header of the response.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey, id))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newRequestID returns a random ID of 16 hex characters.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// validRequestID returns whether the ID received from the client can be
+// used, it must be short and only have characters that are safe in logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, r := range id {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,105 @@
+package server_test
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestRequestID(t *testing.T) {
+	var output bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&output)
+
+	s := server.New()
+	s.HandleFunc("GET /id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(server.RequestID(r)))
+	})
+
+	s.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	serve := func(path, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if id != "" {
+			req.Header.Set("X-Request-ID", id)
+		}
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("generated", func(t *testing.T) {
+		output.Reset()
+
+		res := serve("/id", "")
+		id := res.Body.String()
+		if len(id) != 16 {
+			t.Fatalf("Expected a generated ID, got %q", id)
+		}
+
+		if res.Header().Get("X-Request-ID") != id {
+			t.Errorf("Expected the ID in the response header, got %q", res.Header().Get("X-Request-ID"))
+		}
+
+		if !strings.Contains(output.String(), "request_id="+id) {
+			t.Errorf("Expected the ID in the log, got %q", output.String())
+		}
+
+		if other := serve("/id", "").Body.String(); other == id {
+			t.Errorf("Expected a different ID for each request, got %q twice", id)
+		}
+	})
+
+	t.Run("from the header", func(t *testing.T) {
+		if id := serve("/id", "req-42").Body.String(); id != "req-42" {
+			t.Errorf("Expected the ID of the header, got %q", id)
+		}
+
+		if id := serve("/id", "bad id\nlevel=ERROR").Body.String(); len(id) != 16 {
+			t.Errorf("Expected an invalid ID to be replaced, got %q", id)
+		}
+	})
+
+	t.Run("recoverer", func(t *testing.T) {
+		current := os.Stderr
+		r, w, _ := os.Pipe()
+		os.Stderr = w
+		t.Cleanup(func() { os.Stderr = current })
+
+		t.Setenv("GO_ENV", "development")
+		serve("/panic", "req-500")
+
+		w.Close()
+		var stderr bytes.Buffer
+		io.Copy(&stderr, r)
+
+		if !strings.Contains(stderr.String(), "boom request_id=req-500") {
+			t.Errorf("Expected the ID next to the stack trace, got %q", stderr.String())
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := server.New(server.WithoutRequestID())
+		s.HandleFunc("GET /id", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(server.RequestID(r)))
+		})
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/id", nil))
+
+		if res.Body.String() != "" || res.Header().Get("X-Request-ID") != "" {
+			t.Errorf("Expected no ID, got %q %q", res.Body.String(), res.Header().Get("X-Request-ID"))
+		}
+	})
+}
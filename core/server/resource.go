@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net/http"
+	"path"
+)
+
+// resourceAction is one of the conventional routes of a resource,
+// registered when the resource implements its method.
+type resourceAction struct {
+	method  string
+	path    string
+	handler func(resource any) (http.HandlerFunc, bool)
+}
+
+// resourceActions are the routes registered by Resource, in order.
+var resourceActions = []resourceAction{
+	{http.MethodGet, "", func(res any) (http.HandlerFunc, bool) {
+		h, ok := res.(interface {
+			Index(http.ResponseWriter, *http.Request)
+		})
+		if !ok {
+			return nil, false
+		}
+
+		return h.Index, true
+	}},
+	{http.MethodGet, "/new", func(res any) (http.HandlerFunc, bool) {
+		h, ok := res.(interface {
+			New(http.ResponseWriter, *http.Request)
+		})
+		if !ok {
+			return nil, false
+		}
+
+		return h.New, true
+	}},
+	{http.MethodPost, "", func(res any) (http.HandlerFunc, bool) {
+		h, ok := res.(interface {
+			Create(http.ResponseWriter, *http.Request)
+		})
+		if !ok {
+			return nil, false
+		}
+
+		return h.Create, true
+	}},
+	{http.MethodGet, "/{id}", func(res any) (http.HandlerFunc, bool) {
+		h, ok := res.(interface {
+			Show(http.ResponseWriter, *http.Request)
+		})
+		if !ok {
+			return nil, false
+		}
+
+		return h.Show, true
+	}},
+	{http.MethodGet, "/{id}/edit", func(res any) (http.HandlerFunc, bool) {
+		h, ok := res.(interface {
+			Edit(http.ResponseWriter, *http.Request)
+		})
+		if !ok {
+			return nil, false
+		}
+
+		return h.Edit, true
+	}},
+	{http.MethodPut + "," + http.MethodPatch, "/{id}", func(res any) (http.HandlerFunc, bool) {
+		h, ok := res.(interface {
+			Update(http.ResponseWriter, *http.Request)
+		})
+		if !ok {
+			return nil, false
+		}
+
+		return h.Update, true
+	}},
+	{http.MethodDelete, "/{id}", func(res any) (http.HandlerFunc, bool) {
+		h, ok := res.(interface {
+			Destroy(http.ResponseWriter, *http.Request)
+		})
+		if !ok {
+			return nil, false
+		}
+
+		return h.Destroy, true
+	}},
+}
+
+// Resource registers the conventional routes for the actions the resource
+// implements, each of them being a method with the signature of a handler
+// function:
+//
+//	Index   GET /users
+//	New     GET /users/new
+//	Create  POST /users
+//	Show    GET /users/{id}
+//	Edit    GET /users/{id}/edit
+//	Update  PUT,PATCH /users/{id}
+//	Destroy DELETE /users/{id}
+//
+// The options are applied to each of the routes.
+func (rg *router) Resource(prefix string, resource any, options ...RouteOption) {
+	for _, action := range resourceActions {
+		handler, ok := action.handler(resource)
+		if !ok {
+			continue
+		}
+
+		rg.HandleFunc(action.method+" "+path.Join("/", prefix, action.path), handler, options...)
+	}
+}
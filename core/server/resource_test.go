@@ -0,0 +1,91 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+type usersResource struct{}
+
+func (usersResource) Index(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("index"))
+}
+
+func (usersResource) New(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("new"))
+}
+
+func (usersResource) Show(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("show " + r.PathValue("id")))
+}
+
+func (usersResource) Update(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("update " + r.PathValue("id")))
+}
+
+func TestResource(t *testing.T) {
+	s := server.New()
+	s.Group("/admin/", func(r server.Router) {
+		r.Resource("/users", usersResource{})
+	})
+
+	cases := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/admin/users", http.StatusOK, "index"},
+		{http.MethodGet, "/admin/users/new", http.StatusOK, "new"},
+		{http.MethodGet, "/admin/users/7", http.StatusOK, "show 7"},
+		{http.MethodPut, "/admin/users/7", http.StatusOK, "update 7"},
+		{http.MethodPatch, "/admin/users/7", http.StatusOK, "update 7"},
+		{http.MethodPost, "/admin/users", http.StatusMethodNotAllowed, ""},
+		{http.MethodDelete, "/admin/users/7", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/admin/users/7/edit", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code {
+				t.Errorf("Expected status %d, got %d", c.code, res.Code)
+			}
+
+			if c.body != "" && res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+		})
+	}
+
+	t.Run("routes", func(t *testing.T) {
+		var patterns []string
+		for _, route := range s.Routes() {
+			patterns = append(patterns, strings.TrimSpace(route.Method+" "+route.Pattern))
+		}
+
+		expected := []string{
+			"GET /admin/users",
+			"GET /admin/users/new",
+			"GET /admin/users/{id}",
+			"PUT,PATCH /admin/users/{id}",
+			"/",
+		}
+
+		if !slices.Equal(patterns, expected) {
+			t.Errorf("Expected routes %v, got %v", expected, patterns)
+		}
+
+		if err := s.Check(); err != nil {
+			t.Errorf("Expected no errors, got %v", err)
+		}
+	})
+}
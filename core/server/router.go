@@ -1,14 +1,15 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"path"
+	"slices"
 	"strings"
 
 	"io/fs"
-
-	"github.com/leapkit/leapkit/core/server/internal/response"
 )
 
 // Router is the interface that wraps the basic methods for a router
@@ -16,29 +17,146 @@ type Router interface {
 	// Use allows to specify a middleware that should be executed for all the handlers
 	Use(middleware ...Middleware)
 
+	// UseNamed allows to specify a middleware under a name so it can be skipped
+	UseNamed(name string, middleware Middleware)
+
+	// UseBefore allows to specify a middleware that runs right before the named one
+	UseBefore(name string, middleware Middleware)
+
+	// UseAfter allows to specify a middleware that runs right after the named one
+	UseAfter(name string, middleware Middleware)
+
+	// MiddlewareChain returns the names of the middleware of the router in the order they run
+	MiddlewareChain() []string
+
+	// Skip allows to omit the named middleware from the routes of the router
+	Skip(names ...string)
+
+	// Without allows to remove the named middleware from the ones the router inherited
+	Without(names ...string)
+
 	// ResetMiddleware clears the list of middleware on the router by setting the baseMiddleware.
 	ResetMiddleware()
 
 	// Handle allows to register a new handler for a specific pattern
-	Handle(pattern string, handler http.Handler)
+	Handle(pattern string, handler http.Handler, options ...RouteOption) *RouteRef
 
 	// HandleFunc allows to register a new handler function for a specific pattern
-	HandleFunc(pattern string, handler http.HandlerFunc)
+	HandleFunc(pattern string, handler http.HandlerFunc, options ...RouteOption) *RouteRef
+
+	// Get registers a new handler function for GET requests on the path
+	Get(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef
+
+	// Post registers a new handler function for POST requests on the path
+	Post(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef
+
+	// Put registers a new handler function for PUT requests on the path
+	Put(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef
+
+	// Patch registers a new handler function for PATCH requests on the path
+	Patch(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef
+
+	// Delete registers a new handler function for DELETE requests on the path
+	Delete(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef
+
+	// Options registers a new handler function for OPTIONS requests on the path
+	Options(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef
+
+	// Methods registers a new handler function for the set of methods on
+	// the path, like registering a route for each of them.
+	Methods(methods []string, path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef
+
+	// Any registers a new handler function for all the standard
+	// methods on the path, listed once in Routes.
+	Any(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef
+
+	// Resource registers the conventional routes for the actions
+	// the resource implements, like Index for GET on the path.
+	Resource(prefix string, resource any, options ...RouteOption)
+
+	// Fallback registers the handler for the pattern with the lowest
+	// priority, serving only the paths that don't match other routes.
+	Fallback(pattern string, handler http.HandlerFunc, reserved ...string) *RouteRef
+
+	// CatchAll registers the handler for all the methods and the paths
+	// under the prefix that don't match any other route.
+	CatchAll(handler http.HandlerFunc, options ...RouteOption) *RouteRef
+
+	// Mount allows to serve an http.Handler for all the methods and
+	// subpaths under the prefix, the prefix is stripped from the request
+	// path before calling the handler.
+	Mount(prefix string, handler http.Handler)
+
+	// Proxy allows to forward the requests under the prefix to the
+	// target, the prefix is stripped before forwarding the request.
+	Proxy(prefix string, target *url.URL)
+
+	// Redirect allows to register a route that redirects to the target,
+	// path parameters of the pattern can be used in the target.
+	Redirect(pattern, target string, status int)
+
+	// NotFound allows to set the handler for the requests that
+	// don't match any route under the prefix of the router.
+	NotFound(fn ErrorHandlerFn)
+
+	// ErrorHandler allows to register the function that writes the
+	// response for the status in the routes of the router.
+	ErrorHandler(status int, fn ErrorHandlerFn)
+
+	// Static allows to serve the files of a fs.FS under the prefix
+	// with cache headers, missing files go through the error handlers.
+	Static(prefix string, fs fs.FS, options ...StaticOption)
+
+	// SPA allows to serve a single page application under the prefix, unknown
+	// paths requested by the browser are served with its index.html.
+	SPA(prefix string, fs fs.FS, options ...StaticOption)
 
 	// Folder allows to serve static files from a directory
 	Folder(prefix string, fs fs.FS)
 
-	// Group allows to create a new group of routes with a common prefix
-	Group(prefix string, fn func(Router))
+	// Group allows to create a new group of routes with a common prefix,
+	// the passed middleware is only executed for the handlers in the group.
+	Group(prefix string, fn func(Router), middleware ...Middleware)
+
+	// Prefix returns a router for the routes under the prefix with the
+	// middleware of the router followed by the passed middleware.
+	Prefix(prefix string, middleware ...Middleware) Router
+
+	// Host allows to create a group of routes that only match requests
+	// for the passed host, wildcards like *.example.com are supported.
+	Host(host string, fn func(Router), middleware ...Middleware)
+
+	// Version allows to create a group of routes under the version prefix
+	// that stores the version in the request context.
+	Version(version string, fn func(Router), middleware ...Middleware)
 }
 
 // router is a group of routes with a common prefix and middleware
 // that should be executed for all the handlers in the group
 type router struct {
 	prefix     string
-	mux        *http.ServeMux
 	middleware []Middleware
-	rootSet    bool
+
+	// host the routes of the router are scoped to,
+	// empty when they match any host.
+	host string
+
+	// version of the version group the router belongs to.
+	version string
+
+	// errors are the error handlers registered in the router.
+	errors *errorScope
+
+	// names of the middleware registered with UseNamed by their position,
+	// it's shorter than middleware when the last ones have no name.
+	names []string
+
+	// skip holds the names of the middleware the routes of the router skip.
+	skip []string
+
+	// registry is shared between the router and its groups
+	// to keep track of the registered routes.
+	*registry
 }
 
 // Use allows to specify a middleware that should be executed for all the handlers
@@ -47,65 +165,380 @@ func (rg *router) Use(middleware ...Middleware) {
 	rg.middleware = append(rg.middleware, middleware...)
 }
 
-// ResetMiddleware clears the list of middleware on the router by setting the baseMiddleware.
+// UseNamed allows to specify a middleware that should be executed for all the
+// handlers in the group under a name, so groups and routes can skip it.
+func (rg *router) UseNamed(name string, middleware Middleware) {
+	pad := make([]string, len(rg.middleware)-len(rg.names))
+	rg.names = slices.Concat(rg.names, pad, []string{name})
+	rg.middleware = append(rg.middleware, middleware)
+}
+
+// UseBefore allows to specify a middleware that runs right before the one
+// registered with the name, like one that must run before the logger.
+// The name must have been registered with UseNamed.
+func (rg *router) UseBefore(name string, middleware Middleware) {
+	rg.insert(name, 0, middleware)
+}
+
+// UseAfter allows to specify a middleware that runs right after the one
+// registered with the name, like one that must run after the recoverer.
+// The name must have been registered with UseNamed.
+func (rg *router) UseAfter(name string, middleware Middleware) {
+	rg.insert(name, 1, middleware)
+}
+
+// insert adds the middleware at the offset from the one
+// registered with the name, without a name of its own.
+func (rg *router) insert(name string, offset int, middleware Middleware) {
+	i := slices.Index(rg.names, name)
+	if i < 0 {
+		rg.report(fmt.Errorf("middleware %q to insert at %s is not registered", name, callerSource()))
+		return
+	}
+
+	// the lists are shared with the parent router, so they're copied.
+	rg.middleware = slices.Insert(slices.Clone(rg.middleware), i+offset, middleware)
+	rg.names = slices.Insert(slices.Clone(rg.names), i+offset, "")
+}
+
+// MiddlewareChain returns the names of the middleware of the router in the
+// order they run, the ones registered without a name are listed with the
+// name of their function, like the dev routes page does.
+func (rg *router) MiddlewareChain() []string {
+	chain := make([]string, len(rg.middleware))
+	for i, mw := range rg.middleware {
+		if i < len(rg.names) && rg.names[i] != "" {
+			chain[i] = rg.names[i]
+			continue
+		}
+
+		chain[i] = middlewareName(mw)
+	}
+
+	return chain
+}
+
+// Skip allows to omit the middleware registered with the names from the routes
+// of the router, the names must have been registered with UseNamed.
+func (rg *router) Skip(names ...string) {
+	for _, name := range names {
+		if !slices.Contains(rg.names, name) {
+			rg.report(fmt.Errorf("middleware %q skipped at %s is not registered", name, callerSource()))
+			continue
+		}
+
+		rg.skip = append(slices.Clip(rg.skip), name)
+	}
+}
+
+// Without allows to remove the middleware registered with the names from the
+// ones the router has, keeping the rest in their order. Unlike Skip, the
+// middleware registered later under the same names is applied. The names
+// must have been registered with UseNamed.
+func (rg *router) Without(names ...string) {
+	for _, name := range names {
+		if !slices.Contains(rg.names, name) {
+			rg.report(fmt.Errorf("middleware %q removed at %s is not registered", name, callerSource()))
+			continue
+		}
+
+		// the lists are shared with the parent router, so they're copied.
+		middleware := make([]Middleware, 0, len(rg.middleware))
+		named := make([]string, 0, len(rg.names))
+		for i, mw := range rg.middleware {
+			if i < len(rg.names) && rg.names[i] == name {
+				continue
+			}
+
+			middleware = append(middleware, mw)
+			if i < len(rg.names) {
+				named = append(named, rg.names[i])
+			}
+		}
+
+		rg.middleware, rg.names = middleware, named
+	}
+}
+
+// ResetMiddleware clears the list of middleware on the router by setting the
+// baseMiddleware, with the logger and the recoverer configured by the options.
 func (rg *router) ResetMiddleware() {
-	rg.middleware = baseMiddleware
+	rg.middleware = rg.base
+	rg.names = rg.baseNames
 }
 
 // Handle allows to register a new handler for a specific pattern
 // in the group with the middleware that should be executed for the handler
 // specified in the group.
-func (rg *router) Handle(pattern string, handler http.Handler) {
+func (rg *router) Handle(pattern string, handler http.Handler, options ...RouteOption) *RouteRef {
+	if err := checkPattern(pattern); err != nil {
+		return rg.invalid(pattern, err)
+	}
+
 	method := ""
 	route := pattern
 
-	if parts := strings.Split(pattern, " "); len(parts) > 1 {
-		method = parts[0]
-		route = parts[1]
+	// the methods of a set can be separated by spaces, like "GET, POST /login".
+	if i := strings.LastIndex(pattern, " "); i > 0 {
+		method = strings.ReplaceAll(pattern[:i], " ", "")
+		route = pattern[i+1:]
+	}
+
+	full := path.Join(rg.prefix, route)
+	if err := checkPath(rg.prefix, full); err != nil {
+		return rg.invalid(pattern, err)
+	}
+
+	// the root pattern only matches the root path, CatchAll
+	// registers the handler for every path under it.
+	if rg.strictRoot && full == "/" {
+		full = "/{$}"
 	}
 
-	pattern = fmt.Sprintf("%s %s", method, path.Join(rg.prefix, route))
+	pattern = fmt.Sprintf("%s %s", method, full)
 	pattern = strings.Trim(pattern, " ")
 
-	// When this route is set we mark the rootSet as true
-	rg.rootSet = rg.rootSet || (pattern == "/")
+	return rg.register(newRoute(pattern, handler), handler, options...)
+}
+
+// wrap wraps the handler with the middleware, the
+// first one being the first to run on the request.
+func wrap(middleware []Middleware, handler http.Handler) http.Handler {
+	handler = restoreURL(keepRequest(handler))
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler
+}
+
+// register adds the handler to the mux of the router for the route, wrapped
+// with the middleware of the router and the route options, and keeps track
+// of it. When the route can't be registered, because it conflicts with
+// another route or its pattern is invalid, the error is kept for Check.
+// The handler can read the route from the request context with CurrentRoute.
+func (rg *router) register(route Route, handler http.Handler, options ...RouteOption) *RouteRef {
+	route.Host = rg.host
+	route.Source = callerSource()
+
+	ref := &RouteRef{
+		registry:   rg.registry,
+		index:      -1,
+		middleware: slices.Clip(rg.middleware),
+		skip:       slices.Clip(rg.skip),
+		group:      rg.prefix,
+		errors:     rg.errors,
+	}
+
+	for _, option := range options {
+		option(ref)
+	}
+
+	for _, name := range ref.skip[len(rg.skip):] {
+		if !slices.Contains(rg.names, name) {
+			rg.report(fmt.Errorf("middleware %q skipped by route %q registered at %s is not registered", name, strings.TrimSpace(route.Method+" "+route.Pattern), route.Source))
+		}
+	}
+
+	ref.middleware = closest(skipNamed(ref.middleware, rg.names, ref.skip))
+	ref.handler = wrap(ref.middleware, handler)
+
+	if err := checkMethods(route.methods()); err != nil {
+		return rg.invalid(strings.TrimSpace(route.Method+" "+route.Pattern), err)
+	}
+
+	methods, added := route.methods(), 0
+	ref.register = func() error {
+		for ; added < len(methods); added++ {
+			single := route
+			single.Method = methods[added]
+
+			if err := rg.add(single, ref, withRoute(ref)); err != nil {
+				return err
+			}
+		}
 
-	// Wrapping with the middleware
-	for i := len(rg.middleware) - 1; i >= 0; i-- {
-		handler = rg.middleware[i](handler)
+		ref.index = len(rg.routes)
+		rg.routes = append(rg.routes, route)
+		rg.refs = append(rg.refs, ref)
+
+		return nil
 	}
 
-	rg.mux.Handle(pattern, handler)
+	rg.update(func() {
+		if err := ref.register(); err != nil {
+			rg.errs = append(rg.errs, err)
+			ref.err = err
+
+			return
+		}
+
+		ref.register = nil
+	})
+
+	return ref
+}
+
+// invalid keeps the error for the route with the pattern, which
+// can't be registered, and returns a ref that points to no route.
+func (rg *router) invalid(pattern string, err error) *RouteRef {
+	rg.report(fmt.Errorf("invalid route %q registered at %s: %v", pattern, callerSource(), err))
+
+	return &RouteRef{registry: rg.registry, index: -1}
 }
 
 // HandleFunc allows to register a new handler function for a specific pattern
 // in the group with the middleware that should be executed for the handler
 // specified in the group.
-func (rg *router) HandleFunc(pattern string, handler http.HandlerFunc) {
-	rg.Handle(pattern, http.HandlerFunc(handler))
+func (rg *router) HandleFunc(pattern string, handler http.HandlerFunc, options ...RouteOption) *RouteRef {
+	return rg.Handle(pattern, http.HandlerFunc(handler), options...)
+}
+
+// Get registers a new handler function for GET requests on the path.
+func (rg *router) Get(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef {
+	return rg.HandleFunc(http.MethodGet+" "+path, handler, options...)
+}
+
+// Post registers a new handler function for POST requests on the path.
+func (rg *router) Post(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef {
+	return rg.HandleFunc(http.MethodPost+" "+path, handler, options...)
+}
+
+// Put registers a new handler function for PUT requests on the path.
+func (rg *router) Put(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef {
+	return rg.HandleFunc(http.MethodPut+" "+path, handler, options...)
+}
+
+// Patch registers a new handler function for PATCH requests on the path.
+func (rg *router) Patch(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef {
+	return rg.HandleFunc(http.MethodPatch+" "+path, handler, options...)
+}
+
+// Delete registers a new handler function for DELETE requests on the path.
+func (rg *router) Delete(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef {
+	return rg.HandleFunc(http.MethodDelete+" "+path, handler, options...)
+}
+
+// Options registers a new handler function for OPTIONS requests on the path.
+func (rg *router) Options(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef {
+	return rg.HandleFunc(http.MethodOptions+" "+path, handler, options...)
+}
+
+// Methods registers a new handler function for the set of methods on the
+// path, which is the same as passing them separated by commas in the pattern
+// to HandleFunc, like "GET,POST /login". The handler runs with the same
+// middleware for all of them and the route is listed once in Routes.
+func (rg *router) Methods(methods []string, path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef {
+	if len(methods) == 0 {
+		return rg.invalid(path, errors.New("no methods"))
+	}
+
+	return rg.HandleFunc(strings.Join(methods, ",")+" "+path, handler, options...)
+}
+
+// anyMethods are the methods Any registers the handler for.
+var anyMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions, http.MethodTrace,
+}
+
+// Any registers a new handler function for all the standard methods on the
+// path. Unlike a pattern without a method, requests with other methods get
+// a 405 and the route is listed once in Routes with the set of methods.
+func (rg *router) Any(path string, handler http.HandlerFunc, options ...RouteOption) *RouteRef {
+	return rg.Methods(anyMethods, path, handler, options...)
+}
+
+// Fallback registers the handler for the pattern with the lowest priority,
+// like GET /{slug} for the pages of a CMS. It only serves the requests whose
+// path doesn't match any other route for any method, the ones whose path
+// starts with one of the reserved prefixes get a 404 instead.
+func (rg *router) Fallback(pattern string, handler http.HandlerFunc, reserved ...string) *RouteRef {
+	return rg.HandleFunc(pattern, handler, func(rf *RouteRef) {
+		rf.fallback = true
+		for _, prefix := range reserved {
+			rf.reserved = append(rf.reserved, path.Join(rg.prefix, prefix))
+		}
+	})
+}
+
+// CatchAll registers the handler for all the methods and the paths under the
+// prefix of the router that don't match any other route, which is what the
+// root pattern does unless WithStrictRoot is used.
+func (rg *router) CatchAll(handler http.HandlerFunc, options ...RouteOption) *RouteRef {
+	pattern := strings.TrimSuffix(rg.prefix, "/") + "/"
+
+	return rg.register(newRoute(pattern, handler), handler, options...)
+}
+
+// Mount allows to serve an http.Handler for all the methods and subpaths
+// under the prefix, which is combined with the prefix of the group. The full
+// prefix is stripped from the request path before calling the handler so
+// third party handlers can work as if they were served at the root.
+func (rg *router) Mount(prefix string, handler http.Handler) {
+	mount := strings.TrimSuffix(path.Join(rg.prefix, prefix), "/")
+	pattern := mount + "/"
+
+	rg.register(newRoute(pattern, handler), stripPrefix(mount, handler))
 }
 
 // Folder allows to serve static files from a directory
 func (rg *router) Folder(prefix string, fs fs.FS) {
-	rg.mux.Handle(
-		fmt.Sprintf("GET %s/", path.Join(rg.prefix, prefix)),
-		http.StripPrefix(prefix, http.FileServerFS(fs)),
-	)
+	pattern := fmt.Sprintf("GET %s/", path.Join(rg.prefix, prefix))
+	handler := http.StripPrefix(prefix, http.FileServerFS(fs))
+
+	// folders are served without the middleware of the router.
+	bare := &router{host: rg.host, registry: rg.registry}
+	bare.register(newRoute(pattern, handler), handler)
 }
 
 // Group allows to create a new group of routes with a common prefix
-// and middleware that should be executed for all the handlers in the group
-func (rg *router) Group(prefix string, rfn func(rg Router)) {
-	group := &router{
+// and middleware that should be executed for all the handlers in the group.
+// The passed middleware runs after the middleware of the parent router.
+func (rg *router) Group(prefix string, rfn func(rg Router), middleware ...Middleware) {
+	rfn(rg.Prefix(prefix, middleware...))
+}
+
+// Prefix returns a router for the routes under the prefix, which is
+// combined with the prefix of the router, like the one passed to the
+// function of Group. It keeps the middleware the router has when it's
+// created followed by the passed one, so it can be passed around to
+// register routes in other packages.
+func (rg *router) Prefix(prefix string, middleware ...Middleware) Router {
+	return &router{
 		prefix:     path.Join(rg.prefix, prefix),
-		mux:        rg.mux,
-		middleware: rg.middleware,
+		middleware: slices.Concat(rg.middleware, middleware),
+		host:       rg.host,
+		version:    rg.version,
+		errors:     &errorScope{parent: rg.errors},
+		names:      slices.Clip(rg.names),
+		skip:       slices.Clip(rg.skip),
+		registry:   rg.registry,
 	}
-
-	rfn(group)
 }
 
-func (rg *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	w = &response.Writer{ResponseWriter: w}
-	rg.mux.ServeHTTP(w, r)
+// Host allows to create a group of routes that only match requests for
+// the passed host, which can be a wildcard like *.example.com to match its
+// subdomains. The routes share the middleware of the router and requests to
+// the host that don't match any of them fall back to the host-less routes.
+func (rg *router) Host(host string, rfn func(rg Router), middleware ...Middleware) {
+	host = strings.ToLower(host)
+	rg.update(func() {
+		if _, ok := rg.hosts[host]; !ok {
+			rg.hosts[host] = rg.newMatcher()
+		}
+	})
+
+	group := &router{
+		prefix:     rg.prefix,
+		middleware: slices.Concat(rg.middleware, []Middleware{setHost}, middleware),
+		host:       host,
+		version:    rg.version,
+		errors:     &errorScope{parent: rg.errors},
+		names:      slices.Clip(rg.names),
+		skip:       slices.Clip(rg.skip),
+		registry:   rg.registry,
+	}
+
+	rfn(group)
 }
@@ -5,10 +5,12 @@ import (
 	"context"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -46,6 +48,30 @@ func TestRouter(t *testing.T) {
 					r.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) {
 						w.Write([]byte("Hello users!"))
 					})
+
+					r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+						w.Write([]byte("Show user " + r.PathValue("id")))
+					})
+
+					r.Post("/{$}", func(w http.ResponseWriter, r *http.Request) {
+						w.Write([]byte("User created!"))
+					})
+
+					r.Put("/{id}", func(w http.ResponseWriter, r *http.Request) {
+						w.Write([]byte("User updated!"))
+					})
+
+					r.Patch("/{id}", func(w http.ResponseWriter, r *http.Request) {
+						w.Write([]byte("User patched!"))
+					})
+
+					r.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+						w.Write([]byte("User deleted!"))
+					})
+
+					r.Options("/{$}", func(w http.ResponseWriter, r *http.Request) {
+						w.Write([]byte("User options!"))
+					})
 				})
 			})
 		})
@@ -63,10 +89,16 @@ func TestRouter(t *testing.T) {
 		{"GET", "/api/v1/", "Welcome to the API v1!", http.StatusOK},
 		{"GET", "/api/", "This is the API!", http.StatusOK},
 		{"GET", "/api/docs", "API documentation!", http.StatusOK},
+		{"GET", "/api/v1/users/1", "Show user 1", http.StatusOK},
+		{"POST", "/api/v1/users/", "User created!", http.StatusOK},
+		{"PUT", "/api/v1/users/1", "User updated!", http.StatusOK},
+		{"PATCH", "/api/v1/users/1", "User patched!", http.StatusOK},
+		{"DELETE", "/api/v1/users/1", "User deleted!", http.StatusOK},
+		{"OPTIONS", "/api/v1/users/", "User options!", http.StatusOK},
 	}
 
 	for _, tt := range testCases {
-		t.Run(tt.route, func(t *testing.T) {
+		t.Run(tt.method+" "+tt.route, func(t *testing.T) {
 			req, _ := http.NewRequest(tt.method, tt.route, nil)
 			res := httptest.NewRecorder()
 			s.Handler().ServeHTTP(res, req)
@@ -83,6 +115,15 @@ func TestRouter(t *testing.T) {
 
 }
 
+// orderHandler is an http.Handler that records it was called.
+type orderHandler struct {
+	holder *[]string
+}
+
+func (h orderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	*h.holder = append(*h.holder, "handler")
+}
+
 func TestMiddleware(t *testing.T) {
 	t.Run("ResetMiddleware test", func(t *testing.T) {
 		s := server.New()
@@ -170,6 +211,103 @@ func TestMiddleware(t *testing.T) {
 		}
 	})
 
+	t.Run("Group middleware", func(t *testing.T) {
+		holder := []string{}
+
+		mw := func(s string) func(http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					holder = append(holder, s)
+					next.ServeHTTP(w, r)
+				})
+			}
+		}
+
+		s := server.New()
+		s.Use(mw("root"))
+
+		s.Group("/admin/", func(r server.Router) {
+			r.Use(mw("use"))
+			r.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+				holder = append(holder, "end")
+			})
+
+			r.Group("/nested/", func(r server.Router) {
+				r.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+					holder = append(holder, "end")
+				})
+			}, mw("nested"))
+		}, mw("one"), mw("two"))
+
+		s.HandleFunc("GET /other/{$}", func(w http.ResponseWriter, r *http.Request) {
+			holder = append(holder, "end")
+		})
+
+		testCases := []struct {
+			path     string
+			expected []string
+		}{
+			{"/admin/", []string{"root", "one", "two", "use", "end"}},
+			{"/admin/nested/", []string{"root", "one", "two", "use", "nested", "end"}},
+			{"/other/", []string{"root", "end"}},
+		}
+
+		for _, tc := range testCases {
+			holder = []string{}
+
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if slices.Compare(holder, tc.expected) != 0 {
+				t.Errorf("Expected order '%v' for %s, got '%v'", tc.expected, tc.path, holder)
+			}
+		}
+	})
+
+	t.Run("Handle with http.Handler", func(t *testing.T) {
+		holder := []string{}
+
+		mw := func(s string) func(http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					holder = append(holder, s)
+					next.ServeHTTP(w, r)
+				})
+			}
+		}
+
+		s := server.New()
+		s.Use(mw("root"))
+
+		s.Group("/api/", func(r server.Router) {
+			r.Use(mw("use"))
+			r.Handle("GET /health", orderHandler{&holder})
+		}, mw("group"))
+
+		s.Handle("GET /health", orderHandler{&holder})
+
+		testCases := []struct {
+			path     string
+			expected []string
+		}{
+			{"/api/health", []string{"root", "group", "use", "handler"}},
+			{"/health", []string{"root", "handler"}},
+		}
+
+		for _, tc := range testCases {
+			holder = []string{}
+
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if slices.Compare(holder, tc.expected) != 0 {
+				t.Errorf("Expected order '%v' for %s, got '%v'", tc.expected, tc.path, holder)
+			}
+		}
+	})
+
 	t.Run("WithSession Option", func(t *testing.T) {
 		var req *http.Request
 		ctx := context.Background()
@@ -274,6 +412,29 @@ func TestBaseMiddlewares(t *testing.T) {
 		}
 	})
 
+	t.Run("logger route and handler", func(t *testing.T) {
+		t.Cleanup(output.Reset)
+
+		s := server.New()
+		s.HandleFunc("GET /users/{id}", showUser)
+
+		h := s.Handler()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+		for _, field := range []string{"route=/users/{id}", "handler=github.com/leapkit/leapkit/core/server_test.showUser"} {
+			if !strings.Contains(output.String(), field) {
+				t.Errorf("Expected log message %v, got %v", field, output)
+			}
+		}
+
+		output.Reset()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+		if !strings.Contains(output.String(), "route=404") {
+			t.Errorf("Expected log message %v, got %v", "route=404", output)
+		}
+	})
+
 	t.Run("recoverer error stack trace in development mode", func(t *testing.T) {
 		current := os.Stderr
 
@@ -345,6 +506,11 @@ func TestBaseMiddlewares(t *testing.T) {
 	})
 }
 
+// showUser is a named handler for the tests that check its name.
+func showUser(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.PathValue("id")))
+}
+
 func TestCatchAll(t *testing.T) {
 	expectedNotFoundText := "Something went wrong"
 
@@ -398,6 +564,53 @@ func TestCatchAll(t *testing.T) {
 		}
 	})
 
+	t.Run("strict root", func(t *testing.T) {
+		s := server.New(server.WithStrictRoot())
+		s.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("home"))
+		})
+
+		s.Group("/docs/", func(r server.Router) {
+			r.CatchAll(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("docs " + r.URL.Path))
+			})
+		})
+
+		cases := []struct {
+			path string
+			code int
+			body string
+		}{
+			{"/", http.StatusOK, "home"},
+			{"/enters/the/get", http.StatusNotFound, expectedNotFoundText},
+			{"/docs/intro/routing", http.StatusOK, "docs /docs/intro/routing"},
+		}
+
+		for _, c := range cases {
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			s.Handler().ServeHTTP(resp, req)
+
+			if resp.Code != c.code || !strings.Contains(resp.Body.String(), c.body) {
+				t.Errorf("Expected %d %v for %v, got %d %v", c.code, c.body, c.path, resp.Code, resp.Body.String())
+			}
+		}
+	})
+
+	t.Run("strict root with catch-all", func(t *testing.T) {
+		s := server.New(server.WithStrictRoot())
+		s.CatchAll(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/enters/the/post", nil)
+		s.Handler().ServeHTTP(resp, req)
+
+		if exp := "ok"; resp.Body.String() != exp {
+			t.Errorf("Expected body %v, got %v", exp, resp.Body.String())
+		}
+	})
 }
 
 func TestRegisterErrorMessages(t *testing.T) {
@@ -522,3 +735,873 @@ func TestRegisterErrorMessages(t *testing.T) {
 
 	})
 }
+
+func TestMount(t *testing.T) {
+	mounted := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	})
+
+	t.Run("nested prefix", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.InCtxMiddleware("customValue", "mw"))
+
+		s.Group("/api/", func(r server.Router) {
+			r.Mount("/graphql/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				v, _ := r.Context().Value("customValue").(string)
+				w.Write([]byte(v + " " + r.Method + " " + r.URL.Path))
+			}))
+		})
+
+		testCases := []struct {
+			method string
+			path   string
+			body   string
+		}{
+			{http.MethodGet, "/api/graphql/", "mw GET /"},
+			{http.MethodPost, "/api/graphql/query", "mw POST /query"},
+			{http.MethodDelete, "/api/graphql/a/b", "mw DELETE /a/b"},
+		}
+
+		for _, tc := range testCases {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Body.String() != tc.body {
+				t.Errorf("Expected body %q, got %q", tc.body, res.Body.String())
+			}
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/other", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, res.Code)
+		}
+	})
+
+	t.Run("mounted at the root", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		})
+
+		s.Mount("/", mounted)
+
+		req := httptest.NewRequest(http.MethodPut, "/some/path", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Body.String() != "PUT /some/path" {
+			t.Errorf("Expected body %q, got %q", "PUT /some/path", res.Body.String())
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/hello", nil)
+		res = httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Body.String() != "hello" {
+			t.Errorf("Expected body %q, got %q", "hello", res.Body.String())
+		}
+	})
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	s := server.New()
+	s.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	s.HandleFunc("DELETE /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	s.HandleFunc("POST /users/{$}", func(w http.ResponseWriter, r *http.Request) {})
+
+	testCases := []struct {
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		{http.MethodPost, "/users/1", http.StatusMethodNotAllowed, "GET, HEAD, DELETE"},
+		{http.MethodGet, "/users/", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodPost, "/other", http.StatusNotFound, ""},
+		{http.MethodGet, "/users/1", http.StatusOK, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != tc.code {
+				t.Errorf("Expected status %d, got %d", tc.code, res.Code)
+			}
+
+			if allow := res.Header().Get("Allow"); allow != tc.allow {
+				t.Errorf("Expected Allow header %q, got %q", tc.allow, allow)
+			}
+		})
+	}
+
+	t.Run("custom error handler", func(t *testing.T) {
+		s := server.New(
+			server.WithErrorHandler(http.StatusMethodNotAllowed, func(w http.ResponseWriter, r *http.Request, err error) {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				w.Write([]byte(r.Method + " not allowed, use " + w.Header().Get("Allow")))
+			}),
+		)
+
+		s.HandleFunc("GET /users/{$}", func(w http.ResponseWriter, r *http.Request) {})
+
+		req := httptest.NewRequest(http.MethodPost, "/users/", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if exp := "POST not allowed, use GET, HEAD"; res.Body.String() != exp {
+			t.Errorf("Expected body %q, got %q", exp, res.Body.String())
+		}
+	})
+}
+
+func TestAutoOptions(t *testing.T) {
+	s := server.New(server.WithAutoOptions())
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	s.HandleFunc("GET /users/{$}", func(w http.ResponseWriter, r *http.Request) {})
+	s.HandleFunc("POST /users/{$}", func(w http.ResponseWriter, r *http.Request) {})
+	s.HandleFunc("PUT /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	s.HandleFunc("OPTIONS /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("explicit"))
+	})
+
+	t.Run("automatic response", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/users/", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusNoContent {
+			t.Errorf("Expected status %d, got %d", http.StatusNoContent, res.Code)
+		}
+
+		if exp := "GET, HEAD, POST, OPTIONS"; res.Header().Get("Allow") != exp {
+			t.Errorf("Expected Allow %q, got %q", exp, res.Header().Get("Allow"))
+		}
+
+		if res.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("Expected the middleware to run for the automatic response")
+		}
+	})
+
+	t.Run("explicit handler", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/users/1", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Body.String() != "explicit" {
+			t.Errorf("Expected body %q, got %q", "explicit", res.Body.String())
+		}
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/unknown", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, res.Code)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("GET /users/{$}", func(w http.ResponseWriter, r *http.Request) {})
+
+		req := httptest.NewRequest(http.MethodOptions, "/users/", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, res.Code)
+		}
+	})
+}
+
+func TestGroupParams(t *testing.T) {
+	s := server.New()
+	s.Group("/orgs/{org}/", func(r server.Router) {
+		r.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("home " + r.PathValue("org")))
+		})
+
+		r.Group("/projects/", func(r server.Router) {
+			r.HandleFunc("GET /{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("project " + r.PathValue("org") + " " + r.PathValue("id")))
+			})
+		})
+
+		r.Mount("/files", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("file " + r.URL.Path))
+		}))
+
+		r.NotFound(func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("org not found"))
+		})
+	}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.PathValue("org") == "unknown" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/orgs/acme/", http.StatusOK, "home acme"},
+		{"/orgs/acme/projects/7", http.StatusOK, "project acme 7"},
+		{"/orgs/acme/files/docs/a.txt", http.StatusOK, "file /docs/a.txt"},
+		{"/orgs/unknown/projects/7", http.StatusForbidden, ""},
+		{"/orgs/acme/other", http.StatusNotFound, "org not found"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code {
+				t.Errorf("Expected status %d, got %d", c.code, res.Code)
+			}
+
+			if res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+		})
+	}
+
+	t.Run("routes", func(t *testing.T) {
+		var patterns []string
+		for _, route := range s.Routes() {
+			patterns = append(patterns, strings.TrimSpace(route.Method+" "+route.Pattern))
+		}
+
+		expected := []string{"GET /orgs/{org}/{$}", "GET /orgs/{org}/projects/{id}", "/orgs/{org}/files/", "/"}
+		if !slices.Equal(patterns, expected) {
+			t.Errorf("Expected routes %v, got %v", expected, patterns)
+		}
+
+		url, err := s.Routes()[1].URL("org", "acme", "id", "7")
+		if err != nil || url != "/orgs/acme/projects/7" {
+			t.Errorf("Expected url /orgs/acme/projects/7, got %q (%v)", url, err)
+		}
+	})
+}
+
+func TestAny(t *testing.T) {
+	s := server.New()
+	s.Group("/webhooks/", func(r server.Router) {
+		r.Any("/github", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Method + " " + r.Header.Get("X-Group")))
+		})
+	}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("X-Group", "webhooks")
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			req := httptest.NewRequest(method, "/webhooks/github", nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Body.String() != method+" webhooks" {
+				t.Errorf("Expected body %q, got %q", method+" webhooks", res.Body.String())
+			}
+		})
+	}
+
+	t.Run("other methods", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodConnect, "/webhooks/github", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, res.Code)
+		}
+	})
+
+	t.Run("routes", func(t *testing.T) {
+		routes := s.Routes()
+		if len(routes) != 2 {
+			t.Fatalf("Expected the route to be listed once, got %v", routes)
+		}
+
+		expected := "GET,HEAD,POST,PUT,PATCH,DELETE,OPTIONS,TRACE"
+		if routes[0].Method != expected || routes[0].Pattern != "/webhooks/github" {
+			t.Errorf("Expected route %s /webhooks/github, got %s %s", expected, routes[0].Method, routes[0].Pattern)
+		}
+	})
+}
+
+func TestMethods(t *testing.T) {
+	login := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.Header.Get("X-Group")))
+	}
+
+	s := server.New()
+	s.Group("/", func(r server.Router) {
+		r.HandleFunc("GET, POST /login", login)
+		r.Methods([]string{http.MethodPut, http.MethodDelete}, "/session", login)
+	}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("X-Group", "auth")
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	cases := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{http.MethodGet, "/login", http.StatusOK},
+		{http.MethodPost, "/login", http.StatusOK},
+		{http.MethodPut, "/login", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/session", http.StatusOK},
+		{http.MethodDelete, "/session", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code {
+				t.Errorf("Expected status %d, got %d", c.code, res.Code)
+			}
+
+			if c.code == http.StatusOK && res.Body.String() != c.method+" auth" {
+				t.Errorf("Expected body %q, got %q", c.method+" auth", res.Body.String())
+			}
+		})
+	}
+
+	t.Run("routes", func(t *testing.T) {
+		routes := s.Routes()
+		if routes[0].Method != "GET,POST" || routes[1].Method != "PUT,DELETE" {
+			t.Errorf("Expected the routes to be listed once, got %v", routes)
+		}
+	})
+
+	t.Run("invalid methods", func(t *testing.T) {
+		for pattern, message := range map[string]string{
+			"GET,,POST /a":  "empty method",
+			"GET,GET /b":    `method "GET" is listed more than once`,
+			"GET,P@ST /c":   `invalid method "P@ST"`,
+			"GET,POST / /d": "invalid route",
+		} {
+			s := server.New()
+			s.HandleFunc(pattern, login)
+
+			err := s.Check()
+			if err == nil || !strings.Contains(err.Error(), message) {
+				t.Errorf("Expected error for %q to contain %q, got %v", pattern, message, err)
+			}
+		}
+
+		s := server.New()
+		s.Methods(nil, "/e", login)
+		if err := s.Check(); err == nil || !strings.Contains(err.Error(), "no methods") {
+			t.Errorf("Expected no methods error, got %v", err)
+		}
+	})
+}
+
+func TestHandlerMemoized(t *testing.T) {
+	s := server.New()
+	s.Host("api.example.com", func(r server.Router) {
+		r.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	s.Handler()
+	routes := len(s.Routes())
+
+	s.Handler()
+	if len(s.Routes()) != routes {
+		t.Errorf("Expected %d routes after calling Handler again, got %v", routes, s.Routes())
+	}
+
+	s.HandleFunc("GET /later", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("later"))
+	})
+
+	res := httptest.NewRecorder()
+	s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/later", nil))
+	if res.Body.String() != "later" {
+		t.Errorf("Expected the route registered after Handler to be served, got %q", res.Body.String())
+	}
+}
+
+// BenchmarkHandler measures serving a no-op handler through the
+// server, calling Handler per request, against a plain http.ServeMux.
+func BenchmarkHandler(b *testing.B) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	// the logger of the base middleware writes to the default logger.
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	b.Run("server", func(b *testing.B) {
+		s := server.New()
+		s.HandleFunc("GET /users/{id}", noop)
+
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		w := httptest.NewRecorder()
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.Handler().ServeHTTP(w, req)
+		}
+	})
+
+	b.Run("http.ServeMux", func(b *testing.B) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /users/{id}", noop)
+
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		w := httptest.NewRecorder()
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mux.ServeHTTP(w, req)
+		}
+	})
+}
+
+func registerUserRoutes(r server.Router) {
+	r.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("users " + w.Header().Get("X-Api")))
+	})
+
+	r.Group("/admin/", func(r server.Router) {
+		r.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("stats " + w.Header().Get("X-Api") + " " + w.Header().Get("X-Admin")))
+		})
+	}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Admin", "true")
+			next.ServeHTTP(w, r)
+		})
+	})
+}
+
+// BenchmarkServeHTTP measures the allocations of serving a request with
+// a no-op handler: without middleware, with the base middleware and with
+// a session that the handler never uses. Pooling the response writers and
+// loading the session lazily got the first and the last to 3 and 11
+// allocs/op, the base middleware still takes 25, mostly in the access log,
+// the valuer and the request ID, against 1 for a plain http.ServeMux in
+// BenchmarkHandler.
+func BenchmarkServeHTTP(b *testing.B) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	// the logger of the base middleware writes to the default logger.
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	bare := server.SkipMiddleware("valuer", "requestID", "logger", "recoverer", "maintenance")
+	cases := []struct {
+		name    string
+		options []server.Option
+		route   []server.RouteOption
+	}{
+		{"bare", nil, []server.RouteOption{bare}},
+		{"base middleware", nil, nil},
+		{"session", []server.Option{server.WithSession("secret", "leapkit")}, []server.RouteOption{bare}},
+	}
+
+	for _, bc := range cases {
+		b.Run(bc.name, func(b *testing.B) {
+			s := server.New(bc.options...)
+			s.HandleFunc("GET /users/{id}", noop, bc.route...)
+
+			h := s.Handler()
+			req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			w := httptest.NewRecorder()
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				h.ServeHTTP(w, req)
+			}
+		})
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	s := server.New()
+	api := s.Prefix("/api/", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Api", "true")
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	// middleware added to the server later doesn't apply to the prefix.
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+	})
+
+	registerUserRoutes(api)
+
+	for path, body := range map[string]string{"/api/users": "users true", "/api/admin/stats": "stats true true"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Body.String() != body {
+				t.Errorf("Expected body %q, got %q", body, res.Body.String())
+			}
+		})
+	}
+}
+
+func TestFallback(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.SetOutput(output)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	legacy := http.NewServeMux()
+	legacy.HandleFunc("/old/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("legacy " + r.Method + " " + r.URL.Path))
+	})
+
+	s := server.New(
+		server.WithFallback(legacy),
+		server.WithErrorHandler(http.StatusNotFound, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("leapkit not found"))
+		}),
+	)
+
+	s.HandleFunc("GET /old/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("leapkit"))
+	})
+
+	cases := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/old/users", http.StatusOK, "leapkit"},
+		{http.MethodGet, "/old/posts", http.StatusAccepted, "legacy GET /old/posts"},
+		{http.MethodPost, "/old/users", http.StatusAccepted, "legacy POST /old/users"},
+		{http.MethodGet, "/other", http.StatusNotFound, "404 page not found\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			output.Reset()
+
+			req := httptest.NewRequest(c.method, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code || res.Body.String() != c.body {
+				t.Errorf("Expected %d %q, got %d %q", c.code, c.body, res.Code, res.Body.String())
+			}
+
+			if !strings.Contains(output.String(), "status="+strconv.Itoa(c.code)) {
+				t.Errorf("Expected the status %d to be logged, got %v", c.code, output)
+			}
+		})
+	}
+}
+
+func TestSkipMiddleware(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.SetOutput(output)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Session")))
+	}
+
+	s := server.New()
+	s.UseNamed("session", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("X-Session", "loaded")
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	s.HandleFunc("GET /home", handler)
+	s.HandleFunc("GET /status", handler, server.SkipMiddleware("session"))
+	s.Group("/health/", func(r server.Router) {
+		r.Skip("session", "logger")
+		r.HandleFunc("GET /live", handler)
+	})
+
+	cases := []struct {
+		path   string
+		body   string
+		logged bool
+	}{
+		{"/home", "loaded", true},
+		{"/status", "", true},
+		{"/health/live", "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			output.Reset()
+
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+
+			if logged := strings.Contains(output.String(), "url="+c.path); logged != c.logged {
+				t.Errorf("Expected logged %v, got %v", c.logged, output.String())
+			}
+		})
+	}
+
+	t.Run("unknown names", func(t *testing.T) {
+		s := server.New()
+		s.Group("/api/", func(r server.Router) {
+			r.Skip("sesion")
+			r.HandleFunc("GET /users", handler, server.SkipMiddleware("auth"))
+		})
+
+		err := s.Check()
+		for _, exp := range []string{`middleware "sesion" skipped at`, `middleware "auth" skipped by route "GET /api/users"`} {
+			if err == nil || !strings.Contains(err.Error(), exp) {
+				t.Errorf("Expected error to contain %q, got %v", exp, err)
+			}
+		}
+	})
+}
+
+func TestWithout(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.SetOutput(output)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	// tag returns a middleware that appends the name to the X-Chain header.
+	tag := func(name string) server.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Chain", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	s := server.New()
+	s.Use(tag("first"))
+	s.UseNamed("session", tag("session"))
+	s.Use(tag("last"))
+
+	s.HandleFunc("GET /home", handler)
+	s.Group("/api/", func(r server.Router) {
+		r.Without("session")
+		r.HandleFunc("GET /users", handler)
+
+		r.Group("/v2/", func(r server.Router) {
+			r.UseNamed("session", tag("token"))
+			r.HandleFunc("GET /users", handler)
+		})
+	})
+
+	s.Group("/bare/", func(r server.Router) {
+		r.ResetMiddleware()
+		r.HandleFunc("GET /status", handler)
+	})
+
+	cases := []struct {
+		path   string
+		chain  []string
+		logged bool
+	}{
+		{"/home", []string{"first", "session", "last"}, true},
+		{"/api/users", []string{"first", "last"}, true},
+		{"/api/v2/users", []string{"first", "last", "token"}, true},
+		{"/bare/status", nil, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			output.Reset()
+
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, c.path, nil))
+
+			if chain := res.Header().Values("X-Chain"); !slices.Equal(chain, c.chain) {
+				t.Errorf("Expected chain %v, got %v", c.chain, chain)
+			}
+
+			if logged := strings.Contains(output.String(), "url="+c.path); logged != c.logged {
+				t.Errorf("Expected logged %v, got %v", c.logged, output.String())
+			}
+		})
+	}
+
+	t.Run("unknown names", func(t *testing.T) {
+		s := server.New()
+		s.Group("/api/", func(r server.Router) {
+			r.Without("sesion")
+		})
+
+		if err := s.Check(); err == nil || !strings.Contains(err.Error(), `middleware "sesion" removed at`) {
+			t.Errorf("Expected the unknown name to be reported, got %v", err)
+		}
+	})
+}
+
+func TestUseBeforeAfter(t *testing.T) {
+	holder := []string{}
+
+	mw := func(s string) server.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				holder = append(holder, s)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	s := server.New()
+	s.UseNamed("tracing", mw("tracing"))
+	s.UseBefore("logger", mw("before logger"))
+	s.UseAfter("recoverer", mw("after recoverer"))
+	s.UseAfter("tracing", mw("after tracing"))
+
+	s.Group("/api/", func(r server.Router) {
+		r.UseBefore("tracing", mw("api"))
+		r.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {
+			holder = append(holder, "end")
+		})
+	})
+
+	s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		holder = append(holder, "end")
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		chain := s.MiddlewareChain()
+		expected := []string{"valuer", "requestID", "before logger", "logger", "recoverer", "after recoverer", "maintenance", "devNoCache", "tracing", "after tracing"}
+		if len(chain) != len(expected) {
+			t.Fatalf("Expected %d middleware, got %v", len(expected), chain)
+		}
+
+		for i, name := range expected {
+			if strings.Contains(name, " ") {
+				// unnamed middleware are listed with the name of their function.
+				if !strings.HasPrefix(chain[i], "github.com/leapkit/leapkit/core/server_test.") {
+					t.Errorf("Expected an unnamed middleware at %d, got %q", i, chain[i])
+				}
+
+				continue
+			}
+
+			if chain[i] != name {
+				t.Errorf("Expected %q at %d, got %q", name, i, chain[i])
+			}
+		}
+	})
+
+	t.Run("execution order", func(t *testing.T) {
+		cases := []struct {
+			path     string
+			expected []string
+		}{
+			{"/", []string{"before logger", "after recoverer", "tracing", "after tracing", "end"}},
+			{"/api/users", []string{"before logger", "after recoverer", "api", "tracing", "after tracing", "end"}},
+		}
+
+		for _, c := range cases {
+			holder = []string{}
+			s.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, c.path, nil))
+
+			if !slices.Equal(holder, c.expected) {
+				t.Errorf("Expected order %v for %s, got %v", c.expected, c.path, holder)
+			}
+		}
+	})
+
+	t.Run("unknown names", func(t *testing.T) {
+		s := server.New()
+		s.UseBefore("tracer", mw("one"))
+
+		if err := s.Check(); err == nil || !strings.Contains(err.Error(), `middleware "tracer" to insert at`) {
+			t.Errorf("Expected the unknown name to be reported, got %v", err)
+		}
+	})
+}
+
+func TestFallbackRoute(t *testing.T) {
+	echo := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + r.PathValue("slug")))
+		}
+	}
+
+	s := server.New()
+	s.Fallback("GET /{slug}", echo("page"), "/api", "/admin")
+	s.HandleFunc("GET /about", echo("about"))
+	s.HandleFunc("POST /contact", echo("contact"))
+	s.HandleFunc("GET /api/users", echo("users"))
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/about", http.StatusOK, "about "},
+		{"/pricing", http.StatusOK, "page pricing"},
+		{"/contact", http.StatusMethodNotAllowed, ""},
+		{"/api", http.StatusNotFound, ""},
+		{"/admin", http.StatusNotFound, ""},
+		{"/api/users", http.StatusOK, "users "},
+		{"/blog/hello", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != c.code {
+				t.Errorf("Expected status %d, got %d", c.code, res.Code)
+			}
+
+			if c.body != "" && res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,464 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Route describes a handler registered in the server, it's
+// useful to inspect the routes that have been registered
+// across the different groups.
+type Route struct {
+	// Method is the HTTP method of the route, empty when the route
+	// matches any method. Routes registered for a set of methods have
+	// them separated by commas, like GET,POST.
+	Method string
+
+	// Pattern is the full path pattern of the route including
+	// the prefixes of the groups it was registered in.
+	Pattern string
+
+	// Host the route is scoped to, empty when
+	// the route matches any host.
+	Host string
+
+	// Handler is the name of the function that handles the route.
+	Handler string
+
+	// Source is the file:line where the route was registered.
+	Source string
+
+	// Meta holds the metadata attached to the route with RouteRef.Meta.
+	Meta map[string]any
+
+	// Query holds the patterns the query parameters must match
+	// for the route to serve the request, set with MatchQuery.
+	Query map[string]string
+}
+
+// routeKey is the context key for the route that matched the request.
+const routeKey contextKey = "route"
+
+// RouteRef is returned when registering a route, it allows
+// to attach information to the route after registering it.
+type RouteRef struct {
+	registry *registry
+
+	// index of the route in the registry, -1 when
+	// the route couldn't be registered.
+	index int
+
+	// handler served for the route, wrapped with the
+	// middleware the router had when it was registered.
+	handler    http.Handler
+	middleware []Middleware
+
+	// skip holds the names of the middleware the route skips.
+	skip []string
+
+	// group is the prefix of the router the route was registered in.
+	group string
+
+	// errors are the error handlers of the group, nil for
+	// routes registered without the ones of the groups.
+	errors *errorScope
+
+	// query matchers the requests must meet to be served by the route.
+	query []queryMatcher
+
+	// register adds the route to the mux when it couldn't be registered,
+	// so it can be retried once query matchers tell it apart, and err is
+	// the error it failed with.
+	register func() error
+	err      error
+
+	// fallback routes only serve the requests whose path doesn't match
+	// any other route, nor starts with one of the reserved prefixes.
+	fallback bool
+	reserved []string
+
+	// unmatched is set for the catch-all route of the server,
+	// which serves the requests that don't match any route.
+	unmatched bool
+}
+
+// RouteOption allows to configure a route when registering it.
+type RouteOption func(*RouteRef)
+
+// Meta attaches the value to the route under the key, it's listed in
+// Routes and middleware can read it from the request with CurrentRoute.
+func (rf *RouteRef) Meta(key string, value any) *RouteRef {
+	if rf.index < 0 {
+		return rf
+	}
+
+	rf.registry.update(func() {
+		// the map is copied as the published tables share it.
+		route := &rf.registry.routes[rf.index]
+		route.Meta = maps.Clone(route.Meta)
+		if route.Meta == nil {
+			route.Meta = map[string]any{}
+		}
+
+		route.Meta[key] = value
+	})
+
+	return rf
+}
+
+// withRoute serves the handler of the route making
+// the route available in the context of the request.
+func withRoute(ref *RouteRef) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), routeKey, ref))
+		if ref.errors != nil {
+			withErrorScope(w, ref.errors)
+		}
+
+		ref.handler.ServeHTTP(w, r)
+	})
+}
+
+// CurrentRoute returns the route that matched the request,
+// false when the request didn't go through a registered route.
+func CurrentRoute(r *http.Request) (Route, bool) {
+	ref, ok := r.Context().Value(routeKey).(*RouteRef)
+	if !ok || ref.index < 0 {
+		return Route{}, false
+	}
+
+	return ref.registry.current().routes[ref.index], true
+}
+
+// URL builds the path of the route replacing its parameters with the
+// passed name and value pairs, like URL("org", "acme", "id", "1") for
+// /orgs/{org}/projects/{id}. Values are escaped except for the ones of
+// {name...} parameters, it returns an error when a parameter is missing.
+func (r Route) URL(params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("route %q: odd number of parameters", r.Pattern)
+	}
+
+	values := map[string]string{}
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	pattern, format := parseFormat(r.Pattern)
+
+	segs := strings.Split(pattern, "/")
+	for i, seg := range segs {
+		if !strings.HasPrefix(seg, "{") || seg == "{$}" {
+			continue
+		}
+
+		name, expr, _ := strings.Cut(strings.Trim(seg, "{}"), ":")
+		rest := false
+		if strings.HasSuffix(name, "...") {
+			name, rest = strings.TrimSuffix(name, "..."), true
+		}
+
+		value, ok := values[name]
+		if !ok {
+			return "", fmt.Errorf("route %q: missing value for parameter %q", r.Pattern, name)
+		}
+
+		if _, checks, err := parseConstraints("{" + name + ":" + expr + "}"); expr != "" && err == nil && !checks[0].re.MatchString(value) {
+			return "", fmt.Errorf("route %q: value %q doesn't meet the constraint of parameter %q", r.Pattern, value, name)
+		}
+
+		segs[i] = url.PathEscape(value)
+		if rest {
+			segs[i] = value
+		}
+	}
+
+	path := strings.TrimSuffix(strings.Join(segs, "/"), "{$}")
+	if format != "" {
+		if ext := values[wildcardNames(format)[0]]; ext != "" {
+			path += "." + ext
+		}
+	}
+
+	return path, nil
+}
+
+// Routes returns the list of routes registered in the server
+// in the order they were registered.
+func (rg *router) Routes() []Route {
+	return append([]Route{}, rg.current().routes...)
+}
+
+// Check returns the errors found while registering the routes,
+// such as routes that conflict with other routes. Handler panics
+// with this error so it's useful to call it in tests.
+func (rg *router) Check() error {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	return errors.Join(rg.errs...)
+}
+
+// registry keeps track of the routes registered in a
+// server, it's shared between the router and its groups.
+type registry struct {
+	routes []Route
+	errs   []error
+
+	// refs of the routes registered through the routers.
+	refs []*RouteRef
+
+	// mux matches the routes that are not scoped to a host and
+	// hosts holds the one for each of the hosts that have routes.
+	mux   Matcher
+	hosts map[string]Matcher
+
+	// entries registered in the matchers, in the order they were added.
+	entries []entry
+
+	// mu serializes the changes to the registry, once live is set by
+	// Handler each of them publishes a new table for the requests.
+	mu    sync.Mutex
+	live  bool
+	table atomic.Pointer[table]
+
+	// newMatcher returns the matcher for the server and the host groups.
+	newMatcher func() Matcher
+
+	// notFound handlers registered by the groups.
+	notFound []notFoundHandler
+
+	// dispatchers registered in the muxes by host and shape.
+	dispatchers map[string]*dispatcher
+
+	// strictRoot makes the root pattern only match the root path.
+	strictRoot bool
+
+	// features are the feature flags of the server by name, set
+	// with EnableFeature and DisableFeature while it's serving.
+	features sync.Map
+
+	// matches returns whether the path of the request
+	// matches a route that is not a fallback route.
+	matches func(r *http.Request) bool
+
+	// base is the middleware the routers start with and get back
+	// with ResetMiddleware, the baseMiddleware with the ones the
+	// options configure, and baseNames their names.
+	base      []Middleware
+	baseNames []string
+}
+
+// add registers the handler in the matcher of the host of the route, which
+// has a single method or none, the caller keeps track of the route. It returns an error
+// instead of panicking when the mux rejects the pattern of the route,
+// pointing at the route it conflicts with when that's the case. Routes
+// with the same shape share a dispatcher that checks their constraints.
+func (rr *registry) add(route Route, ref *RouteRef, handler http.Handler) (err error) {
+	pattern := strings.TrimSpace(route.Method + " " + route.Pattern)
+
+	clean, format := parseFormat(pattern)
+	clean, checks, err := parseConstraints(clean)
+	if err != nil {
+		return fmt.Errorf("invalid route %q registered at %s: %v", pattern, route.Source, err)
+	}
+
+	c := candidate{route: route, names: wildcardNames(clean), checks: checks, handler: handler, ref: ref}
+	if ref != nil {
+		c.query = slices.Clip(ref.query)
+	}
+
+	if format != "" {
+		_, fchecks, err := parseConstraints(format)
+		if err != nil {
+			return fmt.Errorf("invalid route %q registered at %s: %v", pattern, route.Source, err)
+		}
+
+		c.format = wildcardNames(format)[0]
+		c.checks = append(c.checks, fchecks...)
+	}
+
+	key := route.Host + "|" + patternShape(clean)
+	if d, ok := rr.dispatchers[key]; ok {
+		if other, conflict := d.add(c); conflict {
+			return conflictError(route, other)
+		}
+
+		rr.entries = append(rr.entries, entry{route: route, ref: ref, handler: handler})
+
+		return nil
+	}
+
+	d := &dispatcher{pattern: clean, names: c.names}
+	d.fallback = func() http.Handler {
+		if root, ok := rr.current().dispatchers[route.Host+"|/"]; ok && root != d {
+			return root
+		}
+
+		return nil
+	}
+
+	d.add(c)
+
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			if rr.dispatchers == nil {
+				rr.dispatchers = map[string]*dispatcher{}
+			}
+
+			rr.dispatchers[key] = d
+			rr.entries = append(rr.entries, entry{route: route, ref: ref, handler: handler})
+
+			return
+		}
+
+		for _, other := range rr.routes {
+			if other.Host == route.Host && rr.conflicts(other, route) {
+				err = conflictError(route, other)
+				return
+			}
+		}
+
+		err = fmt.Errorf("invalid route %q registered at %s: %v", pattern, route.Source, rec)
+	}()
+
+	rr.matcher(route.Host).Handle(clean, d)
+
+	return nil
+}
+
+// conflictError returns the error for a route that
+// can't be registered because of the other route.
+func conflictError(route, other Route) error {
+	return fmt.Errorf(
+		"route %q registered at %s conflicts with route %q registered at %s",
+		strings.TrimSpace(route.Method+" "+route.Pattern), route.Source,
+		strings.TrimSpace(other.Method+" "+other.Pattern), other.Source,
+	)
+}
+
+// conflicts returns whether the patterns of both routes
+// can't be registered together in the same matcher.
+func (rr *registry) conflicts(a, b Route) (conflict bool) {
+	defer func() {
+		conflict = recover() != nil
+	}()
+
+	for _, ma := range a.methods() {
+		for _, mb := range b.methods() {
+			mux := rr.newMatcher()
+			for _, pattern := range []string{ma + " " + a.Pattern, mb + " " + b.Pattern} {
+				pattern, _ := parseFormat(strings.TrimSpace(pattern))
+				pattern, _, _ = parseConstraints(pattern)
+				mux.Handle(pattern, http.NotFoundHandler())
+			}
+		}
+	}
+
+	return false
+}
+
+// methods returns the methods the route is registered for,
+// a single empty method when it matches any method.
+func (r Route) methods() []string {
+	return strings.Split(r.Method, ",")
+}
+
+// checkMethods returns an error when the set of methods of a route
+// has an empty, duplicated or malformed method.
+func checkMethods(methods []string) error {
+	if len(methods) == 1 && methods[0] == "" {
+		return nil
+	}
+
+	for i, method := range methods {
+		if method == "" {
+			return errors.New("empty method in the list of methods")
+		}
+
+		if slices.Contains(methods[:i], method) {
+			return fmt.Errorf("method %q is listed more than once", method)
+		}
+
+		if strings.IndexFunc(method, func(r rune) bool {
+			return !('A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' || '0' <= r && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r))
+		}) >= 0 {
+			return fmt.Errorf("invalid method %q", method)
+		}
+	}
+
+	return nil
+}
+
+// callerSource returns the file:line of the first caller
+// outside of the server package.
+func callerSource() string {
+	pcs := make([]uintptr, 20)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, serverPkg+".") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// serverPkg is the import path of the server package.
+var serverPkg = reflect.TypeOf(registry{}).PkgPath()
+
+// newRoute builds the Route for the passed pattern and handler.
+func newRoute(pattern string, handler http.Handler) Route {
+	route := Route{Pattern: pattern}
+	if method, path, ok := strings.Cut(pattern, " "); ok {
+		route.Method = method
+		route.Pattern = path
+	}
+
+	route.Handler = handlerName(handler)
+
+	return route
+}
+
+// handlerName returns the name of the function behind the handler,
+// for handlers that are not functions it returns the type name.
+func handlerName(handler http.Handler) string {
+	v := reflect.ValueOf(handler)
+	if v.Kind() != reflect.Func {
+		return fmt.Sprintf("%T", handler)
+	}
+
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return fmt.Sprintf("%T", handler)
+	}
+
+	return fn.Name()
+}
+
+// shadowed returns whether the request should not be served by the
+// fallback route, because its path starts with a reserved prefix or
+// matches another route.
+func (rf *RouteRef) shadowed(r *http.Request) bool {
+	for _, prefix := range rf.reserved {
+		if matchPrefix(prefix, r.URL.Path) {
+			return true
+		}
+	}
+
+	return rf.registry.matches != nil && rf.registry.matches(r)
+}
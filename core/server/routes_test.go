@@ -0,0 +1,232 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func listUsers(w http.ResponseWriter, r *http.Request) {}
+
+func TestRoutes(t *testing.T) {
+	s := server.New()
+
+	s.HandleFunc("GET /{$}", listUsers)
+	s.Group("/api/", func(r server.Router) {
+		r.HandleFunc("POST /users", listUsers)
+
+		r.Group("/v1/", func(r server.Router) {
+			r.HandleFunc("GET /users/{id}", listUsers)
+			r.Handle("/health", http.NotFoundHandler())
+		})
+	})
+
+	expected := []server.Route{
+		{Method: "GET", Pattern: "/{$}", Handler: "github.com/leapkit/leapkit/core/server_test.listUsers"},
+		{Method: "POST", Pattern: "/api/users", Handler: "github.com/leapkit/leapkit/core/server_test.listUsers"},
+		{Method: "GET", Pattern: "/api/v1/users/{id}", Handler: "github.com/leapkit/leapkit/core/server_test.listUsers"},
+		{Method: "", Pattern: "/api/v1/health", Handler: "net/http.NotFound"},
+	}
+
+	routes := s.Routes()
+	if len(routes) != len(expected) {
+		t.Fatalf("Expected %d routes, got %d: %v", len(expected), len(routes), routes)
+	}
+
+	for i, route := range routes {
+		if !strings.Contains(route.Source, "routes_test.go:") {
+			t.Errorf("Expected route source in routes_test.go, got %v", route.Source)
+		}
+
+		route.Source = ""
+		if !reflect.DeepEqual(route, expected[i]) {
+			t.Errorf("Expected route %v, got %v", expected[i], route)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	t.Run("no errors", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("GET /users/{id}", handler)
+		s.HandleFunc("POST /users/{id}", handler)
+
+		if err := s.Check(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("conflicting routes", func(t *testing.T) {
+		s := server.New()
+		s.Group("/users/", func(r server.Router) {
+			r.HandleFunc("GET /{id}", handler)
+		})
+
+		s.Group("/", func(r server.Router) {
+			r.HandleFunc("GET /users/{name}", handler)
+		})
+
+		err := s.Check()
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		for _, exp := range []string{`"GET /users/{name}"`, `"GET /users/{id}"`, "routes_test.go:"} {
+			if !strings.Contains(err.Error(), exp) {
+				t.Errorf("Expected error to contain %v, got %v", exp, err)
+			}
+		}
+
+		if len(s.Routes()) != 1 {
+			t.Errorf("Expected the conflicting route not to be listed, got %v", s.Routes())
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected Handler to panic")
+			}
+		}()
+
+		s.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestCheckPatterns(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	cases := []struct {
+		prefix  string
+		pattern string
+		message string
+	}{
+		{"/", "GET/users", `missing space between the method "GET" and the path`},
+		{"/", "GET /users ", "leading or trailing spaces"},
+		{"/", " GET /users", "leading or trailing spaces"},
+		{"/", "GET /users/{id", "unbalanced braces"},
+		{"/", "GET /users/id}", "unbalanced braces"},
+		{"/", "GET /users/{$}/posts", "{$} can only be at the end"},
+		{"/", "GET /users/{id}/posts/{id}", `parameter "id" is used more than once`},
+		{"/orgs/{org}/", "GET /teams/{org}", `parameter "org" shadows the one of the group prefix "/orgs/{org}"`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern, func(t *testing.T) {
+			s := server.New()
+			s.Group(c.prefix, func(r server.Router) {
+				r.HandleFunc(c.pattern, handler)
+			})
+
+			err := s.Check()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+
+			for _, exp := range []string{c.message, "routes_test.go:"} {
+				if !strings.Contains(err.Error(), exp) {
+					t.Errorf("Expected error to contain %q, got %v", exp, err)
+				}
+			}
+		})
+	}
+
+	t.Run("valid patterns", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("GET,POST /login", handler)
+		s.HandleFunc("GET example.com/users/{id:[0-9]{1,3}}", handler)
+		s.HandleFunc("GET /files/{name}.{format}", handler)
+		s.HandleFunc("localhost/status", handler)
+
+		if err := s.Check(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+}
+
+func TestRouteURL(t *testing.T) {
+	cases := []struct {
+		pattern string
+		params  []string
+		url     string
+		err     bool
+	}{
+		{"/orgs/{org}/projects/{id}", []string{"org", "acme", "id", "1"}, "/orgs/acme/projects/1", false},
+		{"/orgs/{org}/{$}", []string{"org", "acme inc"}, "/orgs/acme%20inc/", false},
+		{"/files/{path...}", []string{"path", "docs/readme.md"}, "/files/docs/readme.md", false},
+		{"/orgs/{org}/projects/{id}", []string{"org", "acme"}, "", true},
+		{"/orgs/{org}", []string{"org"}, "", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern, func(t *testing.T) {
+			url, err := server.Route{Pattern: c.pattern}.URL(c.params...)
+			if (err != nil) != c.err {
+				t.Fatalf("Expected error %v, got %v", c.err, err)
+			}
+
+			if url != c.url {
+				t.Errorf("Expected url %q, got %q", c.url, url)
+			}
+		})
+	}
+}
+
+func TestRouteMeta(t *testing.T) {
+	s := server.New()
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, ok := server.CurrentRoute(r)
+			if ok && route.Meta["audience"] == "admin" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	s.HandleFunc("GET /reports", listUsers).
+		Meta("audience", "admin").
+		Meta("description", "List reports")
+
+	s.Get("/public", func(w http.ResponseWriter, r *http.Request) {
+		route, _ := server.CurrentRoute(r)
+		w.Write([]byte(route.Method + " " + route.Pattern))
+	})
+
+	t.Run("middleware", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, res.Code)
+		}
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/public", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Body.String() != "GET /public" {
+			t.Errorf("Expected body %q, got %q", "GET /public", res.Body.String())
+		}
+	})
+
+	t.Run("listing", func(t *testing.T) {
+		expected := map[string]any{"audience": "admin", "description": "List reports"}
+		if meta := s.Routes()[0].Meta; !reflect.DeepEqual(meta, expected) {
+			t.Errorf("Expected meta %v, got %v", expected, meta)
+		}
+
+		if meta := s.Routes()[1].Meta; meta != nil {
+			t.Errorf("Expected no meta, got %v", meta)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+)
+
+// SecureHeadersOptions configures the SecureHeaders middleware, the
+// headers left empty get their default value.
+type SecureHeadersOptions struct {
+	// ContentTypeOptions is the X-Content-Type-Options header,
+	// it defaults to nosniff.
+	ContentTypeOptions string
+
+	// FrameOptions is the X-Frame-Options header, it defaults to
+	// SAMEORIGIN so the pages can only be framed by the app.
+	FrameOptions string
+
+	// ReferrerPolicy is the Referrer-Policy header, it
+	// defaults to strict-origin-when-cross-origin.
+	ReferrerPolicy string
+
+	// StrictTransportSecurity is the Strict-Transport-Security header,
+	// it defaults to max-age=63072000; includeSubDomains. It's only sent
+	// for requests made over HTTPS, directly or through a proxy that sets
+	// the X-Forwarded-Proto header.
+	StrictTransportSecurity string
+
+	// ContentSecurityPolicy is the Content-Security-Policy header, it
+	// defaults to a policy that doesn't restrict the sources of the
+	// page but prevents it from being framed by other sites, changing
+	// its base URL and loading plugins.
+	ContentSecurityPolicy string
+
+	// Omit are the headers that are not sent, like X-Frame-Options.
+	Omit []string
+}
+
+// SecureHeaders returns a middleware that sets the security headers to the
+// responses. The headers are set before calling the handler, so it can
+// replace them with Header().Set or remove them with Header().Del.
+func SecureHeaders(options SecureHeadersOptions) Middleware {
+	headers := [][2]string{
+		{"X-Content-Type-Options", cmp.Or(options.ContentTypeOptions, "nosniff")},
+		{"X-Frame-Options", cmp.Or(options.FrameOptions, "SAMEORIGIN")},
+		{"Referrer-Policy", cmp.Or(options.ReferrerPolicy, "strict-origin-when-cross-origin")},
+		{"Content-Security-Policy", cmp.Or(options.ContentSecurityPolicy, "base-uri 'self'; frame-ancestors 'self'; object-src 'none'")},
+	}
+
+	headers = slices.DeleteFunc(headers, func(h [2]string) bool {
+		return omitted(options.Omit, h[0])
+	})
+
+	hsts := cmp.Or(options.StrictTransportSecurity, defaultHSTS)
+	if omitted(options.Omit, "Strict-Transport-Security") {
+		hsts = ""
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			for _, header := range headers {
+				h.Set(header[0], header[1])
+			}
+
+			if hsts != "" && secure(r) {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithSecureHeaders allows to add the SecureHeaders middleware to the
+// base middleware of the server, it's named secureHeaders so groups
+// and routes can skip it.
+func WithSecureHeaders(options SecureHeadersOptions) Option {
+	mw := SecureHeaders(options)
+	return func(m *mux) {
+		m.UseNamed("secureHeaders", mw)
+	}
+}
+
+// omitted returns whether the header is in the omitted ones.
+func omitted(omit []string, header string) bool {
+	return slices.ContainsFunc(omit, func(o string) bool {
+		return http.CanonicalHeaderKey(o) == header
+	})
+}
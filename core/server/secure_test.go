@@ -0,0 +1,127 @@
+package server_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	serve := func(h http.Handler, req *http.Request) http.Header {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res.Header()
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.SecureHeaders(server.SecureHeadersOptions{}))
+		s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {})
+
+		h := serve(s.Handler(), httptest.NewRequest(http.MethodGet, "/", nil))
+		expected := map[string]string{
+			"X-Content-Type-Options":    "nosniff",
+			"X-Frame-Options":           "SAMEORIGIN",
+			"Referrer-Policy":           "strict-origin-when-cross-origin",
+			"Content-Security-Policy":   "base-uri 'self'; frame-ancestors 'self'; object-src 'none'",
+			"Strict-Transport-Security": "",
+		}
+
+		for name, value := range expected {
+			if h.Get(name) != value {
+				t.Errorf("Expected %s to be %q, got %q", name, value, h.Get(name))
+			}
+		}
+	})
+
+	t.Run("HSTS over HTTPS", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.SecureHeaders(server.SecureHeadersOptions{}))
+		s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {})
+		handler := s.Handler()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{}
+		if h := serve(handler, req); h.Get("Strict-Transport-Security") != "max-age=63072000; includeSubDomains" {
+			t.Errorf("Expected HSTS for TLS requests, got %q", h.Get("Strict-Transport-Security"))
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		if h := serve(handler, req); h.Get("Strict-Transport-Security") == "" {
+			t.Error("Expected HSTS for requests forwarded over HTTPS")
+		}
+	})
+
+	t.Run("overridden and omitted", func(t *testing.T) {
+		s := server.New()
+		s.Use(server.SecureHeaders(server.SecureHeadersOptions{
+			FrameOptions:          "DENY",
+			ContentSecurityPolicy: "default-src 'self'",
+			Omit:                  []string{"referrer-policy", "Strict-Transport-Security"},
+		}))
+
+		s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{}
+		h := serve(s.Handler(), req)
+
+		if h.Get("X-Frame-Options") != "DENY" {
+			t.Errorf("Expected X-Frame-Options DENY, got %q", h.Get("X-Frame-Options"))
+		}
+
+		if h.Get("Content-Security-Policy") != "default-src 'self'" {
+			t.Errorf("Expected the CSP passed, got %q", h.Get("Content-Security-Policy"))
+		}
+
+		if _, ok := h["Referrer-Policy"]; ok {
+			t.Error("Expected Referrer-Policy to be omitted")
+		}
+
+		if _, ok := h["Strict-Transport-Security"]; ok {
+			t.Error("Expected Strict-Transport-Security to be omitted")
+		}
+	})
+
+	t.Run("handler overrides without duplicates", func(t *testing.T) {
+		s := server.New(server.WithSecureHeaders(server.SecureHeadersOptions{}))
+		s.Group("/embed", func(r server.Router) {
+			r.Use(server.SecureHeaders(server.SecureHeadersOptions{}))
+			r.HandleFunc("GET /widget", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Frame-Options", "ALLOWALL")
+			})
+		})
+
+		h := serve(s.Handler(), httptest.NewRequest(http.MethodGet, "/embed/widget", nil))
+		if v := h.Values("X-Frame-Options"); len(v) != 1 || v[0] != "ALLOWALL" {
+			t.Errorf("Expected the handler value only, got %v", v)
+		}
+
+		if v := h.Values("X-Content-Type-Options"); len(v) != 1 {
+			t.Errorf("Expected a single X-Content-Type-Options, got %v", v)
+		}
+	})
+
+	t.Run("skipped in a group", func(t *testing.T) {
+		s := server.New(server.WithSecureHeaders(server.SecureHeadersOptions{}))
+		s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {})
+		s.Group("/raw", func(r server.Router) {
+			r.Skip("secureHeaders")
+			r.HandleFunc("GET /file", func(w http.ResponseWriter, r *http.Request) {})
+		})
+
+		handler := s.Handler()
+		if h := serve(handler, httptest.NewRequest(http.MethodGet, "/", nil)); h.Get("X-Frame-Options") == "" {
+			t.Error("Expected the secure headers with WithSecureHeaders")
+		}
+
+		if h := serve(handler, httptest.NewRequest(http.MethodGet, "/raw/file", nil)); h.Get("X-Frame-Options") != "" {
+			t.Error("Expected the secure headers to be skipped")
+		}
+	})
+}
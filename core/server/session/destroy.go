@@ -0,0 +1,41 @@
+package session
+
+import (
+	"context"
+
+	"github.com/gorilla/sessions"
+)
+
+// Destroy removes the values of the session in the context and expires
+// its cookie, like when the user logs out. The session is deleted from
+// the store right away when one is used. FromCtx returns a new empty
+// session afterwards, which replaces the destroyed one in the cookie
+// when values are set in it, like a flash message.
+func Destroy(ctx context.Context) error {
+	lz := ctx.Value(ctxKey).(*lazy)
+	s := lz.get()
+
+	if st, ok := lz.store.(*idStore); ok && s.ID != "" {
+		if err := st.store.Delete(ctx, s.ID); err != nil {
+			return err
+		}
+	}
+
+	clear(s.Values)
+
+	lz.moot.Lock()
+	defer lz.moot.Unlock()
+
+	// the new session is not remembered.
+	opts := *s.Options
+	opts.MaxAge = lz.maxAge
+
+	fresh := sessions.NewSession(lz.store, lz.name)
+	fresh.Options = &opts
+	fresh.IsNew = true
+
+	lz.session = fresh
+	lz.destroyed = true
+
+	return nil
+}
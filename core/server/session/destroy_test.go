@@ -0,0 +1,91 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestDestroy(t *testing.T) {
+	servers := map[string]http.Handler{}
+	for name, options := range map[string][]server.Option{
+		"cookie": {server.WithSession("secret", "app")},
+		"memory": {server.WithSession("secret", "app"), server.WithSessionStore(session.NewMemoryStore())},
+	} {
+		s := server.New(options...)
+		s.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+			session.FromCtx(r.Context()).Values["user_id"] = "1"
+			w.Write([]byte("OK"))
+		})
+
+		s.HandleFunc("GET /logout", func(w http.ResponseWriter, r *http.Request) {
+			if err := session.Destroy(r.Context()); err != nil {
+				t.Error(err)
+			}
+
+			if len(session.FromCtx(r.Context()).Values) != 0 {
+				t.Error("Expected a new empty session after destroying it")
+			}
+
+			if r.URL.Query().Has("flash") {
+				session.Flash(r.Context(), "notice", "Bye!")
+			}
+
+			w.Write([]byte("OK"))
+		})
+
+		s.HandleFunc("GET /whoami", func(w http.ResponseWriter, r *http.Request) {
+			user, _ := session.FromCtx(r.Context()).Values["user_id"].(string)
+			for _, f := range session.Flashes(r.Context()) {
+				user += f.Message
+			}
+
+			w.Write([]byte(user))
+		})
+
+		servers[name] = s.Handler()
+	}
+
+	for name, h := range servers {
+		serve := func(path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			for _, c := range cookies {
+				req.AddCookie(c)
+			}
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			return res
+		}
+
+		t.Run(name, func(t *testing.T) {
+			login := serve("/login").Result().Cookies()
+
+			res := serve("/logout", login...)
+			if c := res.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+				t.Errorf("Expected the cookie expired, got %v", c)
+			}
+
+			if name == "memory" {
+				if body := serve("/whoami", login...).Body.String(); body != "" {
+					t.Errorf("Expected the session deleted from the store, got %q", body)
+				}
+			}
+
+			login = serve("/login").Result().Cookies()
+			res = serve("/logout?flash", login...)
+			c := res.Result().Cookies()
+			if len(c) != 1 || c[0].MaxAge < 0 {
+				t.Fatalf("Expected a new session for the flash, got %v", c)
+			}
+
+			if body := serve("/whoami", c...).Body.String(); body != "Bye!" {
+				t.Errorf("Expected only the flash in the new session, got %q", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/securecookie"
+)
+
+// WithEncryption encrypts the session cookie with AES-GCM and the key,
+// which must be 16, 24 or 32 bytes to use AES-128, AES-192 or AES-256, so
+// its values can't be read by the clients, besides being signed with the
+// secret. It panics when the key has another length. The cookies that
+// were only signed, before the encryption was enabled, are accepted until
+// graceUntil and encrypted when the session is saved again, pass the zero
+// time to reject them.
+func WithEncryption(key []byte, graceUntil time.Time) Option {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(fmt.Sprintf("session: the encryption key must be 16, 24 or 32 bytes, got %d", len(key)))
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(fmt.Sprintf("session: %v", err))
+	}
+
+	return func(store *cookieStore) {
+		store.Codecs = []securecookie.Codec{&encrypted{
+			aead:       aead,
+			codecs:     store.Codecs,
+			graceUntil: graceUntil,
+		}}
+	}
+}
+
+// errDecrypt is the error of the cookies that can't be decrypted.
+var errDecrypt = errors.New("session: the cookie can't be decrypted")
+
+// encrypted is the securecookie.Codec of the encrypted cookies, the
+// values are signed with the codecs and then encrypted.
+type encrypted struct {
+	aead       cipher.AEAD
+	codecs     []securecookie.Codec
+	graceUntil time.Time
+}
+
+// Encode signs the value and encrypts it, the name
+// of the cookie is authenticated with it.
+func (e *encrypted) Encode(name string, value any) (string, error) {
+	signed, err := securecookie.EncodeMulti(name, value, e.codecs...)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := e.aead.Seal(nonce, nonce, []byte(signed), []byte(name))
+
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decode decrypts the value and checks its signature, the values
+// that are only signed are decoded until the grace period ends.
+func (e *encrypted) Decode(name, value string, dst any) error {
+	signed, err := e.decrypt(name, value)
+	if err != nil {
+		if time.Now().Before(e.graceUntil) {
+			return securecookie.DecodeMulti(name, value, dst, e.codecs...)
+		}
+
+		return err
+	}
+
+	return securecookie.DecodeMulti(name, signed, dst, e.codecs...)
+}
+
+// decrypt returns the signed value of the encrypted one.
+func (e *encrypted) decrypt(name, value string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < e.aead.NonceSize() {
+		return "", errDecrypt
+	}
+
+	nonce, ciphertext := sealed[:e.aead.NonceSize()], sealed[e.aead.NonceSize():]
+	signed, err := e.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", errDecrypt
+	}
+
+	return string(signed), nil
+}
+
+// maxAge sets the maximum age of the signed values.
+func (e *encrypted) maxAge(age int) {
+	for _, codec := range e.codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestEncryption(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	handler := func(options ...session.Option) http.Handler {
+		s := server.New(server.WithSession("secret", "app", options...))
+		s.HandleFunc("GET /set", func(w http.ResponseWriter, r *http.Request) {
+			session.Set(r.Context(), "email", "ada@example.com")
+			w.Write([]byte("OK"))
+		})
+
+		s.HandleFunc("GET /get", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(session.GetOr(r.Context(), "email", "none")))
+		})
+
+		return s.Handler()
+	}
+
+	serve := func(h http.Handler, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	signed := handler()
+	encrypted := handler(session.WithEncryption(key, time.Time{}))
+	migrating := handler(session.WithEncryption(key, time.Now().Add(time.Hour)))
+
+	encryptedCookies := serve(encrypted, "/set").Result().Cookies()
+	signedCookies := serve(signed, "/set").Result().Cookies()
+
+	cases := []struct {
+		name    string
+		h       http.Handler
+		cookies []*http.Cookie
+		body    string
+	}{
+		{"encrypted", encrypted, encryptedCookies, "ada@example.com"},
+		{"encrypted read without the key", signed, encryptedCookies, "none"},
+		{"signed after the grace period", encrypted, signedCookies, "none"},
+		{"signed during the grace period", migrating, signedCookies, "ada@example.com"},
+		{"tampered", encrypted, []*http.Cookie{{Name: "app", Value: encryptedCookies[0].Value[:20] + "AAAA" + encryptedCookies[0].Value[24:]}}, "none"},
+	}
+
+	for _, tc := range cases {
+		res := serve(tc.h, "/get", tc.cookies...)
+		if res.Code != http.StatusOK || res.Body.String() != tc.body {
+			t.Errorf("Expected %q for the %s cookie, got %d %q", tc.body, tc.name, res.Code, res.Body.String())
+		}
+	}
+
+	t.Run("invalid key", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a key of an invalid length to panic")
+			}
+		}()
+
+		session.WithEncryption([]byte("short"), time.Time{})
+	})
+}
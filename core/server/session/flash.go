@@ -0,0 +1,45 @@
+package session
+
+import (
+	"context"
+	"encoding/gob"
+)
+
+// flashKey is the session key the flash messages are stored under.
+const flashKey = "_leapkit_flashes"
+
+func init() {
+	// the stores encode the session values with gob.
+	gob.Register([]FlashMessage{})
+}
+
+// FlashMessage is a message shown once to the user, usually on the page
+// the user is redirected to after submitting a form.
+type FlashMessage struct {
+	// Kind is the kind of the message, like notice, error or success.
+	Kind    string
+	Message string
+}
+
+// Flash adds a message of the kind to the flash messages of the session
+// in the context, it's saved with the session when the response is written
+// and lasts until it's read with Flashes, in this request or the next one.
+func Flash(ctx context.Context, kind, message string) {
+	s := FromCtx(ctx)
+	flashes, _ := s.Values[flashKey].([]FlashMessage)
+	s.Values[flashKey] = append(flashes, FlashMessage{Kind: kind, Message: message})
+}
+
+// Flashes returns the flash messages of the session in the context in the
+// order they were added and removes them, so they are shown only once.
+func Flashes(ctx context.Context) []FlashMessage {
+	s := FromCtx(ctx)
+	flashes, ok := s.Values[flashKey].([]FlashMessage)
+	if !ok {
+		return nil
+	}
+
+	delete(s.Values, flashKey)
+
+	return flashes
+}
@@ -0,0 +1,102 @@
+package session_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gobuffalo/plush/v5"
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestFlash(t *testing.T) {
+	s := server.New(server.WithSession("secret", "app"))
+	render := func(w http.ResponseWriter, r *http.Request) {
+		for _, f := range session.Flashes(r.Context()) {
+			fmt.Fprintf(w, "%s: %s\n", f.Kind, f.Message)
+		}
+	}
+
+	s.HandleFunc("POST /items", func(w http.ResponseWriter, r *http.Request) {
+		session.Flash(r.Context(), "notice", "Saved!")
+		http.Redirect(w, r, "/items", http.StatusSeeOther)
+	})
+
+	s.HandleFunc("POST /items/invalid", func(w http.ResponseWriter, r *http.Request) {
+		session.Flash(r.Context(), "error", "Name can't be blank")
+
+		// the flashes are read before the response is written, like
+		// when rendering a template, so the session is saved without them.
+		flashes := session.Flashes(r.Context())
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		for _, f := range flashes {
+			fmt.Fprintf(w, "%s: %s\n", f.Kind, f.Message)
+		}
+	})
+
+	s.HandleFunc("GET /items", render)
+	s.HandleFunc("GET /items/template", func(w http.ResponseWriter, r *http.Request) {
+		valuer := r.Context().Value("valuer").(interface{ Values() map[string]any })
+		result, err := plush.Render(`<%= flash("notice") %>|<%= flash("notice") %>`, plush.NewContextWith(valuer.Values()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write([]byte(result))
+	})
+
+	h := s.Handler()
+	cookies := map[string]*http.Cookie{}
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		for _, c := range res.Result().Cookies() {
+			cookies[c.Name] = c
+		}
+
+		return res
+	}
+
+	t.Run("after a redirect", func(t *testing.T) {
+		if res := serve(http.MethodPost, "/items"); res.Code != http.StatusSeeOther {
+			t.Fatalf("Expected a redirect, got %d", res.Code)
+		}
+
+		if body := serve(http.MethodGet, "/items").Body.String(); body != "notice: Saved!\n" {
+			t.Errorf("Expected the flash after the redirect, got %q", body)
+		}
+
+		if body := serve(http.MethodGet, "/items").Body.String(); body != "" {
+			t.Errorf("Expected the flash to be gone, got %q", body)
+		}
+	})
+
+	t.Run("templates", func(t *testing.T) {
+		serve(http.MethodPost, "/items")
+		if body := serve(http.MethodGet, "/items/template").Body.String(); body != "Saved!|" {
+			t.Errorf("Expected the flash once in the template, got %q", body)
+		}
+
+		if body := serve(http.MethodGet, "/items").Body.String(); body != "" {
+			t.Errorf("Expected the flash read by the template to be gone, got %q", body)
+		}
+	})
+
+	t.Run("same request", func(t *testing.T) {
+		if body := serve(http.MethodPost, "/items/invalid").Body.String(); body != "error: Name can't be blank\n" {
+			t.Errorf("Expected the flash in the same request, got %q", body)
+		}
+
+		if body := serve(http.MethodGet, "/items").Body.String(); body != "" {
+			t.Errorf("Expected the flash read in the same request to be gone, got %q", body)
+		}
+	})
+}
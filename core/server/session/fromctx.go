@@ -6,7 +6,32 @@ import (
 	"github.com/gorilla/sessions"
 )
 
-// FromCtx returns the session from the context.
+// FromCtx returns the session from the context, it's
+// loaded from the store the first time it's called.
 func FromCtx(ctx context.Context) *sessions.Session {
-	return ctx.Value(ctxKey).(*sessions.Session)
+	return ctx.Value(ctxKey).(*lazy).get()
+}
+
+// Named returns the session with the name from the context, like
+// the ones registered with server.WithNamedSession, it's loaded
+// from its cookie the first time it's called.
+func Named(ctx context.Context, name string) *sessions.Session {
+	return ctx.Value(namedKey(name)).(*lazy).get()
+}
+
+// Cookie returns the value of the cookie the session in the context is
+// loaded from, which tells apart the clients of the middleware that
+// don't load the session, empty when the request has none.
+func Cookie(ctx context.Context) string {
+	lz, ok := ctx.Value(ctxKey).(*lazy)
+	if !ok {
+		return ""
+	}
+
+	c, err := lz.req.Cookie(lz.name)
+	if err != nil {
+		return ""
+	}
+
+	return c.Value
 }
@@ -1,12 +1,28 @@
 package session
 
-import "github.com/gorilla/sessions"
+import (
+	"slices"
+
+	"github.com/gorilla/sessions"
+)
 
 // flashHelper is a helper function that can be used in templates
 // to retrieve a flash message from the session. This function returns
-// that helpers by receiving a pointer to the session.
+// that helpers by receiving a pointer to the session. The key is the
+// kind of the messages added with Flash, the first one is returned and
+// they're removed, or the key of the ones added with AddFlash.
 func flashHelper(session *sessions.Session) func(string) string {
 	return func(key string) string {
+		flashes, _ := session.Values[flashKey].([]FlashMessage)
+		if i := slices.IndexFunc(flashes, func(f FlashMessage) bool { return f.Kind == key }); i >= 0 {
+			session.Values[flashKey] = slices.DeleteFunc(slices.Clone(flashes), func(f FlashMessage) bool { return f.Kind == key })
+			if len(session.Values[flashKey].([]FlashMessage)) == 0 {
+				delete(session.Values, flashKey)
+			}
+
+			return flashes[i].Message
+		}
+
 		val := session.Flashes(key)
 		if len(val) == 0 {
 			return ""
@@ -15,3 +31,11 @@ func flashHelper(session *sessions.Session) func(string) string {
 		return val[0].(string)
 	}
 }
+
+// lazyFlashHelper is like flashHelper but it only loads
+// the session when the helper is called.
+func lazyFlashHelper(lz *lazy) func(string) string {
+	return func(key string) string {
+		return flashHelper(lz.get())(key)
+	}
+}
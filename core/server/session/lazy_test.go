@@ -0,0 +1,105 @@
+package session_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestLazy(t *testing.T) {
+	s := server.New(server.WithSession("secret", "app"))
+	s.HandleFunc("GET /anonymous", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+
+	s.HandleFunc("GET /read", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(session.GetOr(r.Context(), "user_id", "none")))
+	})
+
+	s.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		session.Set(r.Context(), "user_id", "1")
+		session.Set(r.Context(), "roles", []string{"reader"})
+		w.Write([]byte("OK"))
+	})
+
+	s.HandleFunc("GET /same", func(w http.ResponseWriter, r *http.Request) {
+		session.Set(r.Context(), "user_id", "1")
+		w.Write([]byte("OK"))
+	})
+
+	s.HandleFunc("GET /promote", func(w http.ResponseWriter, r *http.Request) {
+		roles, _ := session.Get[[]string](r.Context(), "roles")
+		roles[0] = "admin"
+		w.Write([]byte("OK"))
+	})
+
+	s.HandleFunc("GET /logout", func(w http.ResponseWriter, r *http.Request) {
+		session.Delete(r.Context(), "user_id")
+		w.Write([]byte("OK"))
+	})
+
+	h := s.Handler()
+	serve := func(path string, cookies ...*http.Cookie) []*http.Cookie {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res.Result().Cookies()
+	}
+
+	login := serve("/login")
+	cases := []struct {
+		path    string
+		cookies []*http.Cookie
+		set     bool
+	}{
+		{"/anonymous", nil, false},
+		{"/anonymous", login, false},
+		{"/read", nil, false},
+		{"/read", login, false},
+		{"/same", login, false},
+		{"/login", nil, true},
+		{"/promote", login, true},
+		{"/logout", login, true},
+	}
+
+	for _, tc := range cases {
+		if c := serve(tc.path, tc.cookies...); (len(c) > 0) != tc.set {
+			t.Errorf("Expected the cookie set to be %v on %s with %d cookies, got %v", tc.set, tc.path, len(tc.cookies), c)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	s := server.New(server.WithLogger(logger), server.WithSession("secret", "app"))
+	s.HandleFunc("GET /read", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(session.GetOr(r.Context(), "user_id", "none")))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/read", nil)
+	req.AddCookie(&http.Cookie{Name: "app", Value: "tampered"})
+
+	res := httptest.NewRecorder()
+	s.Handler().ServeHTTP(res, req)
+
+	if res.Body.String() != "none" {
+		t.Errorf("Expected a new session, got %q", res.Body.String())
+	}
+
+	if !strings.Contains(logs.String(), "level=WARN msg=\"session: loading the session\"") || !strings.Contains(logs.String(), "session_name=app") {
+		t.Errorf("Expected the error logged with the logger of the request, got %q", logs.String())
+	}
+}
@@ -0,0 +1,12 @@
+package session
+
+import "net/http"
+
+// Middleware returns a middleware that serves the requests with the
+// session, for the apps that use net/http without the server. It's the
+// middleware server.WithSession adds: the session is loaded into the
+// context the first time it's used and saved when the response is
+// written, and the errors saving it are logged and get a 500.
+func Middleware(secret, name string, options ...Option) func(http.Handler) http.Handler {
+	return New(secret, name, options...).Wrap
+}
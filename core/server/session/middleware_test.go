@@ -0,0 +1,73 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		session.Set(r.Context(), "user_id", "1")
+		session.Flash(r.Context(), "success", "Welcome")
+	})
+
+	mux.HandleFunc("GET /whoami", func(w http.ResponseWriter, r *http.Request) {
+		user, _ := session.Get[string](r.Context(), "user_id")
+		flashes := session.Flashes(r.Context())
+		if len(flashes) == 1 {
+			user += " " + flashes[0].Message
+		}
+
+		w.Write([]byte(user))
+	})
+
+	h := session.Middleware("secret", "app")(mux)
+
+	t.Run("saved without the server", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+		cookies := res.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != "app" {
+			t.Fatalf("Expected the session cookie, got %v", cookies)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.AddCookie(cookies[0])
+
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if res.Body.String() != "1 Welcome" {
+			t.Errorf("Expected the session values, got %q", res.Body.String())
+		}
+
+		if len(res.Result().Cookies()) != 1 {
+			t.Errorf("Expected the cookie without the flash, got %v", res.Result().Cookies())
+		}
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/whoami", nil))
+
+		if len(res.Result().Cookies()) != 0 {
+			t.Errorf("Expected no cookie for an unchanged session, got %v", res.Result().Cookies())
+		}
+	})
+
+	t.Run("save errors", func(t *testing.T) {
+		sw := session.New("secret", "app")
+		sw.SetStore(downStore{})
+
+		res := httptest.NewRecorder()
+		sw.Wrap(mux).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+		if res.Code != http.StatusInternalServerError || len(res.Result().Cookies()) != 0 {
+			t.Errorf("Expected a 500 without the cookie, got %d %v", res.Code, res.Result().Cookies())
+		}
+	})
+}
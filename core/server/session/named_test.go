@@ -0,0 +1,69 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestNamed(t *testing.T) {
+	s := server.New(
+		server.WithNamedSession("prefs", "other-secret", session.WithExpiration(365*24*time.Hour)),
+		server.WithSession("secret", "auth", session.WithMaxAge(0)),
+	)
+
+	s.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		session.FromCtx(r.Context()).Values["user_id"] = "1"
+		w.Write([]byte("OK"))
+	})
+
+	s.HandleFunc("GET /theme/{theme}", func(w http.ResponseWriter, r *http.Request) {
+		session.Named(r.Context(), "prefs").Values["theme"] = r.PathValue("theme")
+		w.Write([]byte("OK"))
+	})
+
+	s.HandleFunc("GET /whoami", func(w http.ResponseWriter, r *http.Request) {
+		user, _ := session.FromCtx(r.Context()).Values["user_id"].(string)
+		theme, _ := session.Named(r.Context(), "prefs").Values["theme"].(string)
+		same := session.Named(r.Context(), "auth") == session.FromCtx(r.Context())
+		if !same {
+			t.Error("Expected the primary session to be named too")
+		}
+
+		w.Write([]byte(user + " " + theme))
+	})
+
+	h := s.Handler()
+	cookies := map[string]*http.Cookie{}
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		for _, c := range res.Result().Cookies() {
+			cookies[c.Name] = c
+		}
+
+		return res
+	}
+
+	if c := serve("/theme/dark").Result().Cookies(); len(c) != 1 || c[0].Name != "prefs" || c[0].MaxAge != 365*24*60*60 {
+		t.Errorf("Expected only the long-lived prefs cookie, got %v", c)
+	}
+
+	if c := serve("/login").Result().Cookies(); len(c) != 1 || c[0].Name != "auth" || c[0].MaxAge != 0 {
+		t.Errorf("Expected only the auth cookie lasting until the browser is closed, got %v", c)
+	}
+
+	if body := serve("/whoami").Body.String(); body != "1 dark" {
+		t.Errorf("Expected the values of both sessions, got %q", body)
+	}
+}
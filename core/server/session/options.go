@@ -2,60 +2,84 @@ package session
 
 import (
 	"net/http"
-
-	"github.com/gorilla/sessions"
+	"time"
 )
 
 // Option for the session middleware
-type Option func(*sessions.CookieStore)
+type Option func(*cookieStore)
 
 // Set the domain for the application session
 // This is useful when you want to share the session
 // between subdomains.
 func WithDomain(domain string) Option {
-	return func(store *sessions.CookieStore) {
+	return func(store *cookieStore) {
 		store.Options.Domain = domain
 	}
 }
 
-// WithSecure value for the Secure flag on the session cookie.
+// WithSecure value for the Secure flag on the session cookie, which
+// defaults to true when GO_ENV is not development.
 func WithSecure(secure bool) Option {
-	return func(store *sessions.CookieStore) {
+	return func(store *cookieStore) {
 		store.Options.Secure = secure
 	}
 }
 
-// WithSameSite value for the SameSite option on the session cookie.
+// WithSameSite value for the SameSite option on the session cookie,
+// which defaults to http.SameSiteLaxMode.
 func WithSameSite(sameSite http.SameSite) Option {
-	return func(store *sessions.CookieStore) {
+	return func(store *cookieStore) {
 		store.Options.SameSite = sameSite
 	}
 }
 
+// WithPath sets the path of the session cookie, like the prefix
+// the app is served under. It defaults to /.
 func WithPath(path string) Option {
-	return func(store *sessions.CookieStore) {
+	return func(store *cookieStore) {
 		store.Options.Path = path
 	}
 }
 
-// WithMaxAge sets the maximum age for the session cookie.
-// A negative value means that the cookie is deleted when the browser is closed.
+// WithMaxAge sets the maximum age for the session cookie in seconds, the
+// signed values are rejected after it too. It defaults to 30 days, zero
+// means that the cookie is deleted when the browser is closed and a
+// negative value deletes the cookie.
 func WithMaxAge(maxAge int) Option {
-	return func(store *sessions.CookieStore) {
-		store.Options.MaxAge = maxAge
+	return func(store *cookieStore) {
+		if maxAge <= 0 {
+			store.Options.MaxAge = maxAge
+			return
+		}
+
+		store.MaxAge(maxAge)
+		for _, codec := range store.Codecs {
+			if e, ok := codec.(*encrypted); ok {
+				e.maxAge(maxAge)
+			}
+		}
 	}
 }
 
-// WithHTTPOnly sets the HttpOnly flag on the session cookie.
+// WithExpiration sets how long the session cookie lasts, like
+// WithMaxAge but with a duration, which is rounded down to seconds.
+func WithExpiration(d time.Duration) Option {
+	return WithMaxAge(int(d / time.Second))
+}
+
+// WithHTTPOnly used to set the HttpOnly flag on the session cookie.
+//
+// Deprecated: the session cookie is always HttpOnly, so scripts
+// can't read it, and this option is ignored.
 func WithHTTPOnly(httpOnly bool) Option {
-	return func(store *sessions.CookieStore) {
+	return func(store *cookieStore) {
 		store.Options.HttpOnly = httpOnly
 	}
 }
 
 // WithSecure sets the Secure flag on the session cookie.
 func WithSecureFlag(secure bool) Option {
-	return func(store *sessions.CookieStore) {
+	return func(store *cookieStore) {
 		store.Options.Secure = secure
 	}
 }
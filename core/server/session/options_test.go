@@ -0,0 +1,126 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestExpiration(t *testing.T) {
+	s := server.New(server.WithSession("secret", "app", session.WithExpiration(30*24*time.Hour)))
+	s.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		session.FromCtx(r.Context()).Values["user_id"] = "1"
+		w.Write([]byte("OK"))
+	})
+
+	s.HandleFunc("GET /whoami", func(w http.ResponseWriter, r *http.Request) {
+		v, _ := session.FromCtx(r.Context()).Values["user_id"].(string)
+		w.Write([]byte(v))
+	})
+
+	s.HandleFunc("GET /touch", func(w http.ResponseWriter, r *http.Request) {
+		session.Touch(r.Context())
+		w.Write([]byte("OK"))
+	})
+
+	s.HandleFunc("GET /logout", func(w http.ResponseWriter, r *http.Request) {
+		session.Destroy(r.Context())
+		w.Write([]byte("OK"))
+	})
+
+	h := s.Handler()
+	serve := func(path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	cookies := serve("/login").Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge != 30*24*60*60 || time.Until(cookies[0].Expires) < 29*24*time.Hour {
+		t.Fatalf("Expected the cookie to last 30 days, got %v", cookies)
+	}
+
+	res := serve("/whoami", cookies...)
+	if res.Body.String() != "1" {
+		t.Errorf("Expected the values of the session, got %q", res.Body.String())
+	}
+
+	if c := res.Result().Cookies(); len(c) != 0 {
+		t.Errorf("Expected no cookie on the requests that don't change the session, got %v", c)
+	}
+
+	if c := serve("/touch", cookies...).Result().Cookies(); len(c) != 1 || c[0].MaxAge != 30*24*60*60 {
+		t.Errorf("Expected the expiration refreshed when the session is touched, got %v", c)
+	}
+
+	res = serve("/logout", cookies...)
+	if c := res.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Errorf("Expected the cookie expired, got %v", c)
+	}
+
+	if res := serve("/whoami", res.Result().Cookies()...); res.Body.String() != "" {
+		t.Errorf("Expected the session destroyed, got %q", res.Body.String())
+	}
+}
+
+func TestCookieAttributes(t *testing.T) {
+	cookie := func(options ...session.Option) *http.Cookie {
+		s := server.New(server.WithSession("secret", "app", options...))
+		s.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			session.FromCtx(r.Context()).Values["user_id"] = "1"
+			w.Write([]byte("OK"))
+		})
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		cookies := res.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("Expected the session cookie, got %v", cookies)
+		}
+
+		return cookies[0]
+	}
+
+	t.Run("development", func(t *testing.T) {
+		t.Setenv("GO_ENV", "development")
+
+		c := cookie()
+		if c.Secure || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode || c.Path != "/" {
+			t.Errorf("Expected a lax HttpOnly cookie without Secure, got %v", c)
+		}
+	})
+
+	t.Run("production", func(t *testing.T) {
+		t.Setenv("GO_ENV", "production")
+
+		c := cookie()
+		if !c.Secure || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+			t.Errorf("Expected a lax HttpOnly cookie with Secure, got %v", c)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		c := cookie(
+			session.WithDomain("example.com"),
+			session.WithPath("/app"),
+			session.WithSameSite(http.SameSiteStrictMode),
+			session.WithSecure(true),
+			session.WithHTTPOnly(false),
+		)
+
+		if c.Domain != "example.com" || c.Path != "/app" || c.SameSite != http.SameSiteStrictMode || !c.Secure || !c.HttpOnly {
+			t.Errorf("Expected the attributes of the options, got %v", c)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package session
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// ErrTooLarge is the error of saving a session whose cookie
+// is larger than the browsers accept with OverflowError.
+var ErrTooLarge = errors.New("session: the cookie is too large")
+
+// maxCookieSize is the size of the name and the value
+// of the largest cookie the browsers accept.
+const maxCookieSize = 4096
+
+// Overflow is what is done with the sessions whose cookie
+// is larger than the browsers accept.
+type Overflow int
+
+const (
+	// OverflowLog logs a warning with the size of each value
+	// and doesn't save the session, it's the default.
+	OverflowLog Overflow = iota
+
+	// OverflowError fails saving the session with ErrTooLarge,
+	// the request gets the response of the 500 error handler.
+	OverflowError
+
+	// OverflowChunk splits the cookie in numbered cookies,
+	// like session, session_1 and session_2, which are joined
+	// back when the session is loaded.
+	OverflowChunk
+)
+
+// WithOverflow sets what is done with the sessions whose cookie
+// is larger than the 4KB the browsers accept, which are dropped
+// by them otherwise.
+func WithOverflow(mode Overflow) Option {
+	return func(store *cookieStore) {
+		store.overflow = mode
+	}
+}
+
+// cookieStore is the cookie store of the sessions, which checks
+// the size of the cookie before setting it.
+type cookieStore struct {
+	*sessions.CookieStore
+	overflow Overflow
+
+	// logger returns the logger of the request, the
+	// sessions that are too large are logged with it.
+	logger func(*http.Request) *slog.Logger
+}
+
+// Get returns the session with the name, cached for the request.
+func (cs *cookieStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(cs, name)
+}
+
+// New decodes the session of the cookie, joining its chunks,
+// it returns a new one when there is no cookie.
+func (cs *cookieStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(cs, name)
+	opts := *cs.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	value := c.Value
+	for i := 1; ; i++ {
+		c, err := r.Cookie(chunkName(name, i))
+		if err != nil {
+			break
+		}
+
+		value += c.Value
+	}
+
+	err = securecookie.DecodeMulti(name, value, &session.Values, cs.Codecs...)
+	if err == nil {
+		session.IsNew = false
+	}
+
+	return session, err
+}
+
+// Save encodes the values of the session in its cookie, handling
+// the cookies that are too large with the overflow mode. The chunks
+// of a previous larger cookie are expired.
+func (cs *cookieStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	name := session.Name()
+	encoded, err := securecookie.EncodeMulti(name, session.Values, cs.Codecs...)
+	if err != nil {
+		return err
+	}
+
+	chunks := []string{encoded}
+	if size := len(name) + len(encoded); size > maxCookieSize {
+		switch cs.overflow {
+		case OverflowChunk:
+			chunks = split(encoded, maxCookieSize-len(name)-len(strconv.Itoa(len(encoded)))-1)
+		case OverflowError:
+			return fmt.Errorf("%w: %d bytes, the values are %s", ErrTooLarge, size, valueSizes(session.Values))
+		default:
+			cs.logger(r).Warn("session: the cookie is too large, the session was not saved", "session_name", name, "size", size, "values", valueSizes(session.Values))
+			return nil
+		}
+	}
+
+	for i, chunk := range chunks {
+		http.SetCookie(w, sessions.NewCookie(chunkName(name, i), chunk, session.Options))
+	}
+
+	expired := *session.Options
+	expired.MaxAge = -1
+	for i := len(chunks); ; i++ {
+		if _, err := r.Cookie(chunkName(name, i)); err != nil {
+			break
+		}
+
+		http.SetCookie(w, sessions.NewCookie(chunkName(name, i), "", &expired))
+	}
+
+	return nil
+}
+
+// chunkName returns the name of the cookie of the
+// chunk, the first one has the name of the session.
+func chunkName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+
+	return name + "_" + strconv.Itoa(i)
+}
+
+// split splits the value in chunks of the size.
+func split(value string, size int) []string {
+	var chunks []string
+	for len(value) > size {
+		chunks = append(chunks, value[:size])
+		value = value[size:]
+	}
+
+	return append(chunks, value)
+}
+
+// valueSizes returns the keys of the values with the size of their
+// encoding, from the largest, so the ones to move out are found.
+func valueSizes(values map[any]any) string {
+	type entry struct {
+		key  string
+		size int
+	}
+
+	entries := make([]entry, 0, len(values))
+	for k, v := range values {
+		var buf bytes.Buffer
+		gob.NewEncoder(&buf).Encode(&v)
+		entries = append(entries, entry{fmt.Sprint(k), buf.Len()})
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Or(b.size-a.size, strings.Compare(a.key, b.key))
+	})
+
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = fmt.Sprintf("%s=%dB", e.key, e.size)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// unlimit removes the limit of the length of the values encoded
+// with the codecs, the cookie store checks the size of the cookie.
+func unlimit(codecs []securecookie.Codec) {
+	for _, codec := range codecs {
+		switch c := codec.(type) {
+		case *securecookie.SecureCookie:
+			c.MaxLength(0)
+		case *encrypted:
+			unlimit(c.codecs)
+		}
+	}
+}
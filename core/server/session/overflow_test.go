@@ -0,0 +1,107 @@
+package session_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestOverflow(t *testing.T) {
+	large := make([]byte, 2000)
+	rand.Read(large)
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	handler := func(options ...session.Option) http.Handler {
+		s := server.New(server.WithLogger(logger), server.WithSession("secret", "app", options...))
+		s.HandleFunc("GET /large", func(w http.ResponseWriter, r *http.Request) {
+			session.Set(r.Context(), "user_id", "1")
+			session.Set(r.Context(), "cart", hex.EncodeToString(large))
+			w.Write([]byte("OK"))
+		})
+
+		s.HandleFunc("GET /small", func(w http.ResponseWriter, r *http.Request) {
+			session.Delete(r.Context(), "cart")
+			w.Write([]byte("OK"))
+		})
+
+		s.HandleFunc("GET /cart", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(session.GetOr(r.Context(), "cart", "empty")))
+		})
+
+		return s.Handler()
+	}
+
+	serve := func(h http.Handler, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("log", func(t *testing.T) {
+		logs.Reset()
+
+		res := serve(handler(), "/large")
+		if res.Code != http.StatusOK || len(res.Result().Cookies()) != 0 {
+			t.Errorf("Expected the response without the cookie, got %d %v", res.Code, res.Result().Cookies())
+		}
+
+		if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "values=\"cart=") {
+			t.Errorf("Expected a warning with the size of the values, got %q", logs.String())
+		}
+
+		if !strings.Contains(logs.String(), "request_id=") {
+			t.Errorf("Expected the warning logged with the logger of the request, got %q", logs.String())
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		res := serve(handler(session.WithOverflow(session.OverflowError)), "/large")
+		if res.Code != http.StatusInternalServerError || res.Body.String() == "OK" || len(res.Result().Cookies()) != 0 {
+			t.Errorf("Expected the 500 response without the cookie, got %d %q %v", res.Code, res.Body.String(), res.Result().Cookies())
+		}
+	})
+
+	t.Run("chunk", func(t *testing.T) {
+		h := handler(session.WithOverflow(session.OverflowChunk))
+
+		cookies := serve(h, "/large").Result().Cookies()
+		if len(cookies) != 2 || cookies[0].Name != "app" || cookies[1].Name != "app_1" {
+			t.Fatalf("Expected the cookie in two chunks, got %d cookies", len(cookies))
+		}
+
+		for _, c := range cookies {
+			if len(c.Name)+len(c.Value) > 4096 {
+				t.Errorf("Expected the chunks to fit in a cookie, got %d bytes", len(c.Name)+len(c.Value))
+			}
+		}
+
+		if body := serve(h, "/cart", cookies...).Body.String(); body != hex.EncodeToString(large) {
+			t.Errorf("Expected the value joined from the chunks, got %d bytes", len(body))
+		}
+
+		smaller := serve(h, "/small", cookies...).Result().Cookies()
+		if len(smaller) != 2 || smaller[1].Name != "app_1" || smaller[1].MaxAge >= 0 {
+			t.Fatalf("Expected the chunk no longer used to be expired, got %v", smaller)
+		}
+
+		if body := serve(h, "/cart", smaller[0]).Body.String(); body != "empty" {
+			t.Errorf("Expected the smaller session, got %q", body)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package session
+
+import (
+	"cmp"
+	"context"
+	"time"
+)
+
+// RedisClient is the subset of a Redis client the RedisStore uses, so
+// any client can be adapted to it. Get returns nil when the key is
+// missing.
+type RedisClient interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+// NewRedisStore returns a Store that keeps the sessions in Redis with
+// the client, under the prefix, which defaults to "session:". The
+// sessions expire with the TTL of their keys.
+func NewRedisStore(client RedisClient, prefix string) Store {
+	return &redisStore{client: client, prefix: cmp.Or(prefix, "session:")}
+}
+
+// redisStore is the Redis Store.
+type redisStore struct {
+	client RedisClient
+	prefix string
+}
+
+func (rs *redisStore) Load(ctx context.Context, id string) ([]byte, error) {
+	return rs.client.Get(ctx, rs.prefix+id)
+}
+
+func (rs *redisStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return rs.client.Set(ctx, rs.prefix+id, data, ttl)
+}
+
+func (rs *redisStore) Delete(ctx context.Context, id string) error {
+	return rs.client.Del(ctx, rs.prefix+id)
+}
@@ -0,0 +1,29 @@
+package session
+
+import "context"
+
+// Regenerate gives the session in the context a new ID, keeping its
+// values, which prevents session fixation when it's called after the
+// user logs in. The session is deleted from the store under its old ID
+// when one is used, and the new cookie is set when the response is
+// written. With the default cookie store the values are signed again
+// in a new cookie.
+func Regenerate(ctx context.Context) error {
+	lz := ctx.Value(ctxKey).(*lazy)
+	s := lz.get()
+
+	if st, ok := lz.store.(*idStore); ok && s.ID != "" {
+		if err := st.store.Delete(ctx, s.ID); err != nil {
+			return err
+		}
+	}
+
+	s.ID = ""
+	s.IsNew = true
+
+	lz.moot.Lock()
+	lz.touched = true
+	lz.moot.Unlock()
+
+	return nil
+}
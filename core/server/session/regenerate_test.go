@@ -0,0 +1,76 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestRegenerate(t *testing.T) {
+	s := server.New(
+		server.WithSession("secret", "app"),
+		server.WithSessionStore(session.NewMemoryStore()),
+	)
+
+	s.HandleFunc("GET /visit", func(w http.ResponseWriter, r *http.Request) {
+		session.FromCtx(r.Context()).Values["cart"] = "3 items"
+		w.Write([]byte("OK"))
+	})
+
+	s.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		if err := session.Regenerate(r.Context()); err != nil {
+			t.Fatal(err)
+		}
+
+		session.FromCtx(r.Context()).Values["user_id"] = "1"
+		w.Header().Set("Content-Type", "text/plain")
+		http.Redirect(w, r, "/whoami", http.StatusSeeOther)
+	})
+
+	s.HandleFunc("GET /whoami", func(w http.ResponseWriter, r *http.Request) {
+		sw := session.FromCtx(r.Context())
+		user, _ := sw.Values["user_id"].(string)
+		cart, _ := sw.Values["cart"].(string)
+
+		w.Write([]byte(user + " " + cart))
+	})
+
+	h := s.Handler()
+	serve := func(method, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	before := serve(http.MethodGet, "/visit").Result().Cookies()
+	if len(before) != 1 {
+		t.Fatalf("Expected the session cookie, got %v", before)
+	}
+
+	res := serve(http.MethodPost, "/login", before...)
+	if v := res.Header().Values("Set-Cookie"); len(v) != 1 {
+		t.Fatalf("Expected one Set-Cookie for the new session, got %v", v)
+	}
+
+	after := res.Result().Cookies()
+	if after[0].Value == before[0].Value {
+		t.Error("Expected a new session ID")
+	}
+
+	if body := serve(http.MethodGet, "/whoami", after...).Body.String(); body != "1 3 items" {
+		t.Errorf("Expected the values kept with the new ID, got %q", body)
+	}
+
+	if body := serve(http.MethodGet, "/whoami", before...).Body.String(); body != " " {
+		t.Errorf("Expected the old session to be invalidated, got %q", body)
+	}
+}
@@ -0,0 +1,35 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// rememberKey is the session key the remembered MaxAge is stored under.
+const rememberKey = "_leapkit_remember"
+
+// Remember makes the cookie of the session in the context last for the
+// duration, like when the user checks "keep me signed in", instead of the
+// expiration set with the options. The choice is kept in the session, so
+// the cookie keeps lasting for the duration on the next responses and
+// after Regenerate, until Remember is called with zero to go back to the
+// expiration of the options. The signed values are still rejected once
+// they're older than the expiration of the options, or 30 days when the
+// cookie lasts until the browser is closed.
+func Remember(ctx context.Context, d time.Duration) {
+	lz := ctx.Value(ctxKey).(*lazy)
+	s := lz.get()
+
+	lz.moot.Lock()
+	defer lz.moot.Unlock()
+
+	if d <= 0 {
+		delete(s.Values, rememberKey)
+		s.Options.MaxAge = lz.maxAge
+
+		return
+	}
+
+	s.Values[rememberKey] = int(d / time.Second)
+	s.Options.MaxAge = int(d / time.Second)
+}
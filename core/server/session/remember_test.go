@@ -0,0 +1,108 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestRemember(t *testing.T) {
+	s := server.New(
+		server.WithSession("secret", "app", session.WithMaxAge(0)),
+		server.WithSessionStore(session.NewMemoryStore()),
+	)
+
+	s.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		if err := session.Regenerate(r.Context()); err != nil {
+			t.Fatal(err)
+		}
+
+		session.Set(r.Context(), "user_id", "1")
+		if r.FormValue("remember") == "on" {
+			session.Remember(r.Context(), 30*24*time.Hour)
+		}
+	})
+
+	s.HandleFunc("POST /regenerate", func(w http.ResponseWriter, r *http.Request) {
+		if err := session.Regenerate(r.Context()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	s.HandleFunc("POST /visit", func(w http.ResponseWriter, r *http.Request) {
+		session.Touch(r.Context())
+	})
+
+	s.HandleFunc("POST /forget", func(w http.ResponseWriter, r *http.Request) {
+		session.Remember(r.Context(), 0)
+	})
+
+	s.HandleFunc("POST /logout", func(w http.ResponseWriter, r *http.Request) {
+		if err := session.Destroy(r.Context()); err != nil {
+			t.Fatal(err)
+		}
+
+		session.Flash(r.Context(), "success", "Bye")
+	})
+
+	h := s.Handler()
+	serve := func(t *testing.T, path string, cookies ...*http.Cookie) *http.Cookie {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		cookies = res.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("Expected the session cookie on %s, got %v", path, cookies)
+		}
+
+		return cookies[0]
+	}
+
+	month := 30 * 24 * 60 * 60
+
+	t.Run("not remembered", func(t *testing.T) {
+		if c := serve(t, "/login"); c.MaxAge != 0 {
+			t.Errorf("Expected a browser session cookie, got MaxAge %d", c.MaxAge)
+		}
+	})
+
+	t.Run("remembered", func(t *testing.T) {
+		c := serve(t, "/login?remember=on")
+		if c.MaxAge != month {
+			t.Fatalf("Expected the cookie to last 30 days, got MaxAge %d", c.MaxAge)
+		}
+
+		if c = serve(t, "/visit", c); c.MaxAge != month {
+			t.Errorf("Expected the next responses to keep the duration, got MaxAge %d", c.MaxAge)
+		}
+
+		regenerated := serve(t, "/regenerate", c)
+		if regenerated.Value == c.Value || regenerated.MaxAge != month {
+			t.Errorf("Expected the regenerated session to keep the duration, got MaxAge %d", regenerated.MaxAge)
+		}
+
+		if c = serve(t, "/forget", regenerated); c.MaxAge != 0 {
+			t.Errorf("Expected the default expiration back, got MaxAge %d", c.MaxAge)
+		}
+
+		if c = serve(t, "/visit", c); c.MaxAge != 0 {
+			t.Errorf("Expected the default expiration to stay, got MaxAge %d", c.MaxAge)
+		}
+	})
+
+	t.Run("destroyed", func(t *testing.T) {
+		c := serve(t, "/login?remember=on")
+		if c = serve(t, "/logout", c); c.MaxAge != 0 {
+			t.Errorf("Expected the new session not to be remembered, got MaxAge %d", c.MaxAge)
+		}
+	})
+}
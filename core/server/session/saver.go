@@ -1,41 +1,118 @@
 package session
 
 import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
 	"net/http"
-	"sync"
+	"reflect"
 
 	"github.com/gorilla/sessions"
-	"github.com/leapkit/leapkit/core/server/internal/response"
 )
 
-// saver takes care of automatically saving the session
-// when the response is written, this avoids having to
-// call session.Save() in every handler.
-type saver struct {
-	*response.Writer
-	req   *http.Request
-	store *sessions.Session
-	moot  sync.Mutex
+// save saves the session into the headers of the response, this avoids
+// having to call session.Save() in every handler. It's a header hook so
+// it runs once, right before the headers are sent, and not when the
+// connection has been hijacked as there is no response to add the
+// cookie to.
+func (lz *lazy) save(h http.Header) {
+	lz.moot.Lock()
+	session := lz.session
+
+	// the cookie of a destroyed session is expired unless
+	// values were set in the new one.
+	if lz.destroyed && len(session.Values) == 0 {
+		session.Options.MaxAge = -1
+	}
+
+	// the cookie is only set when the session has changed, so
+	// the responses of the requests that only read it can be
+	// cached and don't carry it.
+	changed := lz.destroyed || lz.touched || lz.original.changed(session)
+
+	// the changes made after this are lost, they're compared
+	// with the saved session when the request is done.
+	lz.saved = true
+	lz.original = snapshotOf(session)
+	lz.moot.Unlock()
+
+	if !changed {
+		return
+	}
+
+	// the error handler writes the response instead of the one of
+	// the handler, or lets it through when it doesn't write any.
+	if err := session.Save(lz.req, headerWriter(h)); err != nil {
+		err = fmt.Errorf("saving the session %q: %w", lz.name, err)
+		lz.writer.Failure = func(w http.ResponseWriter) {
+			lz.fail(w, lz.req, err)
+		}
+	}
+}
+
+// lost returns whether the session changed in a way that
+// couldn't be saved, like after the headers were sent.
+func (lz *lazy) lost() bool {
+	lz.moot.Lock()
+	defer lz.moot.Unlock()
+
+	if lz.session == nil {
+		return false
+	}
+
+	// the sessions that can't be encoded failed saving.
+	if lz.saved {
+		return lz.original != nil && lz.original.changed(lz.session)
+	}
+
+	return lz.destroyed || lz.touched || lz.original.changed(lz.session)
 }
 
-func (s *saver) Header() http.Header {
-	s.saveSession()
-	return s.ResponseWriter.Header()
+// snapshot is a copy of a session, which
+// tells whether the session has changed.
+type snapshot struct {
+	id      string
+	options sessions.Options
+	values  map[any]any
 }
 
-func (s *saver) WriteHeader(code int) {
-	s.saveSession()
-	s.ResponseWriter.WriteHeader(code)
+// snapshotOf returns a snapshot of the session, the values are copied
+// encoding them like the stores do, so the changes to the values inside
+// them are noticed too. It returns nil when they can't be encoded.
+func snapshotOf(s *sessions.Session) *snapshot {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.Values); err != nil {
+		return nil
+	}
+
+	values := map[any]any{}
+	if err := gob.NewDecoder(&buf).Decode(&values); err != nil {
+		return nil
+	}
+
+	return &snapshot{id: s.ID, options: *s.Options, values: values}
 }
 
-func (s *saver) Write(b []byte) (int, error) {
-	s.saveSession()
-	return s.ResponseWriter.Write(b)
+// changed returns whether the session is different from the snapshot,
+// the sessions without one are always saved.
+func (sn *snapshot) changed(s *sessions.Session) bool {
+	if sn == nil {
+		return true
+	}
+
+	return sn.id != s.ID || sn.options != *s.Options || !reflect.DeepEqual(sn.values, s.Values)
 }
 
-func (s *saver) saveSession() {
-	s.moot.Lock()
-	defer s.moot.Unlock()
+// headerWriter is the http.ResponseWriter the session is saved
+// with, the stores only set the cookie in its headers.
+type headerWriter http.Header
+
+func (h headerWriter) Header() http.Header {
+	return http.Header(h)
+}
 
-	s.store.Save(s.req, s.ResponseWriter)
+func (h headerWriter) Write(b []byte) (int, error) {
+	return 0, http.ErrBodyNotAllowed
 }
+
+func (h headerWriter) WriteHeader(int) {}
@@ -0,0 +1,109 @@
+package session_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+// downStore is a session store that fails saving the sessions.
+type downStore struct{}
+
+func (downStore) Load(ctx context.Context, id string) ([]byte, error) {
+	return nil, nil
+}
+
+func (downStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (downStore) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+func TestSaveErrors(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	handler := func(options ...server.Option) http.Handler {
+		s := server.New(append([]server.Option{server.WithLogger(logger)}, options...)...)
+		s.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+			session.Set(r.Context(), "user_id", "1")
+			w.Write([]byte("OK"))
+		})
+
+		s.HandleFunc("GET /late", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("OK"))
+			session.Set(r.Context(), "user_id", "1")
+		})
+
+		return s.Handler()
+	}
+
+	serve := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return res
+	}
+
+	t.Run("500 error handler", func(t *testing.T) {
+		h := handler(
+			server.WithSession("secret", "app"),
+			server.WithSessionStore(downStore{}),
+			server.WithErrorHandler(http.StatusInternalServerError, func(w http.ResponseWriter, r *http.Request, err error) {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("failed: " + err.Error()))
+			}),
+		)
+
+		res := serve(h, "/login")
+		if res.Code != http.StatusInternalServerError || !strings.Contains(res.Body.String(), "connection refused") || strings.Contains(res.Body.String(), "OK") {
+			t.Errorf("Expected the response of the 500 error handler, got %d %q", res.Code, res.Body.String())
+		}
+	})
+
+	t.Run("session error handler", func(t *testing.T) {
+		logs.Reset()
+		h := handler(
+			server.WithSessionErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+				server.Logger(r).Warn("going on without the session", "error", err)
+			}),
+			server.WithSession("secret", "app"),
+			server.WithSessionStore(downStore{}),
+		)
+
+		res := serve(h, "/login")
+		if res.Code != http.StatusOK || res.Body.String() != "OK" || len(res.Result().Cookies()) != 0 {
+			t.Errorf("Expected the response of the handler without the cookie, got %d %q", res.Code, res.Body.String())
+		}
+
+		if !strings.Contains(logs.String(), "going on without the session") {
+			t.Errorf("Expected the error handler to be called, got %q", logs.String())
+		}
+	})
+
+	t.Run("after the response", func(t *testing.T) {
+		logs.Reset()
+		h := handler(server.WithSession("secret", "app"))
+
+		if res := serve(h, "/late"); len(res.Result().Cookies()) != 0 {
+			t.Errorf("Expected no cookie, got %v", res.Result().Cookies())
+		}
+
+		for _, exp := range []string{"level=ERROR", "request_id=", "session_name=app", "response was sent"} {
+			if !strings.Contains(logs.String(), exp) {
+				t.Errorf("Expected %q in the logs, got %q", exp, logs.String())
+			}
+		}
+	})
+}
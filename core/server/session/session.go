@@ -1,9 +1,12 @@
 package session
 
 import (
+	"cmp"
 	"context"
-	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"sync"
 
 	"github.com/gorilla/sessions"
 	"github.com/leapkit/leapkit/core/server/internal/response"
@@ -17,55 +20,242 @@ var ctxKey contextKey = "session"
 // into the http.Request context.
 type contextKey string
 
-func New(secret, name string, options ...Option) *session {
-	store := sessions.NewCookieStore([]byte(secret))
+// namedKey is the key type used to store the
+// sessions by their name into the context.
+type namedKey string
 
-	// Default options.
-	store.Options.HttpOnly = true
+func New(secret, name string, options ...Option) *session {
+	cs := &cookieStore{CookieStore: sessions.NewCookieStore([]byte(secret))}
 
-	// TODO: Review these 2 options for production.
-	store.Options.Secure = false
-	store.Options.SameSite = http.SameSiteLaxMode
+	// Default options, the cookie is only sent over HTTPS
+	// out of development.
+	cs.Options.Secure = cmp.Or(os.Getenv("GO_ENV"), "development") != "development"
+	cs.Options.SameSite = http.SameSiteLaxMode
 
 	// Run the options on the store
 	for _, option := range options {
-		option(store)
+		option(cs)
+	}
+
+	// the cookie is never readable by scripts.
+	cs.Options.HttpOnly = true
+
+	unlimit(cs.Codecs)
+
+	cs.logger = func(*http.Request) *slog.Logger {
+		return slog.Default()
 	}
 
 	return &session{
-		name:  name,
-		store: store,
+		name:    name,
+		store:   cs,
+		cookies: cs,
+		primary: true,
+		logger:  cs.logger,
 	}
 }
 
 type session struct {
 	name  string
-	store *sessions.CookieStore
+	store sessions.Store
+
+	// primary is set for the session returned by FromCtx and the
+	// templates helpers, the others are returned by Named.
+	primary bool
+
+	// cookies is the cookie store with the options and the secret
+	// of the cookie, the sessions are kept in it unless SetStore
+	// is called.
+	cookies *cookieStore
+
+	// errorHandler writes the response of the requests whose
+	// session can't be saved, nil for a 500 with the error logged.
+	errorHandler func(http.ResponseWriter, *http.Request, error)
+
+	// logger returns the logger of the request.
+	logger func(*http.Request) *slog.Logger
 }
 
-// Register returns an *http.Request with the session set in its context and also
-// a custom http.ResponseWriter implementation that will save the session after each HTTP call.
-func (s *session) Register(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
-	session, err := s.store.Get(r, s.name)
-	if err != nil {
-		fmt.Println(err, "session_name", s.name)
+// SetErrorHandler sets the function called when the session can't be
+// saved, like when its cookie is too large with OverflowError or the
+// store is down, before the response is sent. It writes the response
+// instead of the one of the handler, which is sent when it doesn't
+// write any, so the request can fail or go on without the session.
+func (s *session) SetErrorHandler(fn func(http.ResponseWriter, *http.Request, error)) {
+	s.errorHandler = fn
+}
+
+// SetPrimary sets whether the session is the primary one, returned by
+// FromCtx and the template helpers, which it is by default. The sessions
+// that are not primary are only returned by Named, so an app can have
+// other sessions, like a long-lived one with the preferences of the user.
+func (s *session) SetPrimary(primary bool) {
+	s.primary = primary
+}
+
+// SetLogger sets the function that returns the logger of the requests,
+// the errors saving the session are logged with it.
+func (s *session) SetLogger(fn func(*http.Request) *slog.Logger) {
+	s.logger = fn
+	s.cookies.logger = fn
+}
+
+// Wrap returns a handler that serves the requests with the session, see
+// Register. The changes to the session that can't be saved, because the
+// response was sent before them or the connection was hijacked, are
+// logged as errors.
+func (s *session) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served := response.Root(w) != nil
+
+		w, r = s.Register(w, r)
+		next.ServeHTTP(w, r)
+
+		lz := r.Context().Value(namedKey(s.name)).(*lazy)
+		if rw := lz.writer; rw.Status == 0 && rw.Bytes == 0 && !rw.Hijacked {
+			// the server sends the responses that weren't written.
+			if !served {
+				rw.RunHeaderHooks()
+			}
+
+			return
+		}
+
+		if !lz.lost() {
+			return
+		}
+
+		reason := "the response was sent before the session changed"
+		if lz.writer.Hijacked {
+			reason = "the connection was hijacked"
+		}
+
+		s.logger(r).Error("session: the changes to the session were not saved", "session_name", s.name, "reason", reason)
+	})
+}
+
+// fail writes the response of the request whose session can't be saved.
+func (s *session) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if s.errorHandler != nil {
+		s.errorHandler(w, r, err)
+		return
 	}
 
-	// Look for a valuer in the context and set the values for flash
-	// and session so that they can be used in other components of the request.
-	vlr, ok := r.Context().Value("valuer").(interface{ Set(string, any) })
-	if ok {
-		vlr.Set("flash", flashHelper(session))
-		vlr.Set("session", func() *sessions.Session { return session })
+	s.logger(r).Error(err.Error())
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// Register returns an *http.Request with the session set in its context and an
+// http.ResponseWriter that will save the session when the response is written.
+// The session is loaded from the store the first time it's used, requests that never
+// touch it don't decode the cookie nor save it.
+func (s *session) Register(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
+	lz := &lazy{req: r, store: s.store, name: s.name, fail: s.fail, logger: s.logger}
+
+	ctx := context.WithValue(r.Context(), namedKey(s.name), lz)
+	if s.primary {
+		// Look for a valuer in the context and set the values for flash
+		// and session so that they can be used in other components of the request.
+		vlr, ok := r.Context().Value("valuer").(interface{ Set(string, any) })
+		if ok {
+			vlr.Set("flash", lazyFlashHelper(lz))
+			vlr.Set("session", lz.get)
+		}
+
+		ctx = context.WithValue(ctx, ctxKey, lz)
 	}
 
-	r = r.WithContext(context.WithValue(r.Context(), ctxKey, session))
+	r = r.WithContext(ctx)
+	lz.req = r
 
-	w = &saver{
-		Writer: &response.Writer{ResponseWriter: w},
-		req:    r,
-		store:  session,
+	// the session is saved by a header hook of the writer, which the
+	// server also runs for the responses that weren't written.
+	lz.writer = response.Root(w)
+	if lz.writer == nil {
+		lz.writer = &response.Writer{ResponseWriter: w}
+		w = lz.writer
 	}
 
 	return w, r
 }
+
+// lazy loads the session of the request from the
+// store the first time it's used.
+type lazy struct {
+	req   *http.Request
+	store sessions.Store
+	name  string
+
+	// writer is the writer of the response the session is saved into.
+	writer *response.Writer
+
+	// fail writes the response when the session can't be saved.
+	fail func(http.ResponseWriter, *http.Request, error)
+
+	// logger returns the logger of the request.
+	logger func(*http.Request) *slog.Logger
+
+	moot    sync.Mutex
+	session *sessions.Session
+
+	// destroyed is set when the session has been destroyed,
+	// session is the new one that replaces it.
+	destroyed bool
+
+	// touched is set when the session must be saved even when it
+	// hasn't changed, like when its ID is regenerated.
+	touched bool
+
+	// original is the session as it was loaded, it's
+	// only saved when it has changed since then.
+	original *snapshot
+
+	// saved is set once the session has been saved, or
+	// it was checked that it hadn't changed.
+	saved bool
+
+	// maxAge is the MaxAge of the cookie set with the options,
+	// which the sessions go back to when they're not remembered.
+	maxAge int
+}
+
+// get returns the session of the request, loading it on the first call.
+func (lz *lazy) get() *sessions.Session {
+	lz.moot.Lock()
+	defer lz.moot.Unlock()
+
+	if lz.session != nil {
+		return lz.session
+	}
+
+	session, err := lz.store.Get(lz.req, lz.name)
+	if err != nil {
+		// the cookies that can't be decoded, like the ones signed with
+		// a previous secret, get a new session.
+		lz.logger(lz.req).Warn("session: loading the session", "session_name", lz.name, "error", err)
+	}
+
+	lz.maxAge = session.Options.MaxAge
+	if seconds, ok := session.Values[rememberKey].(int); ok {
+		session.Options.MaxAge = seconds
+	}
+
+	lz.session = session
+	lz.original = snapshotOf(session)
+
+	// the session is saved right before the headers are sent, so the
+	// cookie is set once with the final values. The hook is added when
+	// it's loaded so it runs before the ones of the middleware that check
+	// the cookies, like CacheControl.
+	lz.writer.HeaderHooks = append(lz.writer.HeaderHooks, lz.save)
+
+	return session
+}
+
+// loaded returns the session when it has been loaded, nil otherwise.
+func (lz *lazy) loaded() *sessions.Session {
+	lz.moot.Lock()
+	defer lz.moot.Unlock()
+
+	return lz.session
+}
@@ -0,0 +1,72 @@
+package session
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// NewSQLStore returns a Store that keeps the sessions in the table of
+// the database, which defaults to "sessions" and must have this schema:
+//
+//	CREATE TABLE sessions (
+//		id TEXT PRIMARY KEY,
+//		data BLOB NOT NULL,
+//		expires_at BIGINT NOT NULL
+//	);
+//
+// The queries use $N placeholders and ON CONFLICT upserts, which
+// PostgreSQL and SQLite support but MySQL doesn't, and the data column
+// is BYTEA in PostgreSQL. The expired sessions are not loaded, and
+// removed as new sessions are saved, the errors removing them are
+// logged without failing the save.
+func NewSQLStore(db *sql.DB, table string) Store {
+	return &sqlStore{db: db, table: cmp.Or(table, "sessions")}
+}
+
+// sqlStore is the database/sql Store.
+type sqlStore struct {
+	db    *sql.DB
+	table string
+
+	// swept is the unix time the expired sessions were last removed.
+	swept atomic.Int64
+}
+
+func (ss *sqlStore) Load(ctx context.Context, id string) ([]byte, error) {
+	var data []byte
+	query := fmt.Sprintf("SELECT data FROM %s WHERE id = $1 AND expires_at > $2", ss.table)
+	err := ss.db.QueryRowContext(ctx, query, id, time.Now().Unix()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+func (ss *sqlStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	now := time.Now()
+	if last := ss.swept.Load(); now.Unix()-last > 60 && ss.swept.CompareAndSwap(last, now.Unix()) {
+		query := fmt.Sprintf("DELETE FROM %s WHERE expires_at <= $1", ss.table)
+		if _, err := ss.db.ExecContext(ctx, query, now.Unix()); err != nil {
+			slog.Error("session: removing the expired sessions", "table", ss.table, "error", err)
+		}
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, data, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data, expires_at = excluded.expires_at`, ss.table)
+
+	_, err := ss.db.ExecContext(ctx, query, id, data, now.Add(ttl).Unix())
+	return err
+}
+
+func (ss *sqlStore) Delete(ctx context.Context, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", ss.table)
+	_, err := ss.db.ExecContext(ctx, query, id)
+	return err
+}
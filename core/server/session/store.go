@@ -0,0 +1,195 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"encoding/gob"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// Store keeps the values of the sessions on the server, keyed by
+// their ID, so the cookie only carries the signed ID. It allows
+// sessions larger than a cookie and revoking them by deleting them.
+type Store interface {
+	// Load returns the encoded values of the session, nil
+	// when there is none or it has expired.
+	Load(ctx context.Context, id string) ([]byte, error)
+
+	// Save stores the encoded values of the session for the ttl.
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+
+	// Delete removes the session, like when the user logs out.
+	Delete(ctx context.Context, id string) error
+}
+
+// defaultTTL is how long the sessions whose cookie has no
+// MaxAge, which lasts until the browser is closed, are kept.
+const defaultTTL = 24 * time.Hour
+
+// SetStore makes the session keep its values in the store and only its
+// signed ID in the cookie, with the options and the secret of the cookie
+// store.
+func (s *session) SetStore(store Store) {
+	cs := s.cookies
+	s.store = &idStore{store: store, codecs: cs.Codecs, options: cs.Options}
+}
+
+// idStore is the sessions.Store of the sessions kept in a Store.
+type idStore struct {
+	store   Store
+	codecs  []securecookie.Codec
+	options *sessions.Options
+}
+
+// Get returns the session with the name, cached for the request.
+func (s *idStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session with the ID of the cookie, it returns a new
+// one when there is no cookie or the session has expired or been
+// deleted.
+func (s *idStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	if err := securecookie.DecodeMulti(name, c.Value, &session.ID, s.codecs...); err != nil {
+		return session, err
+	}
+
+	data, err := s.store.Load(r.Context(), session.ID)
+	if err != nil || data == nil {
+		session.ID = ""
+		return session, err
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		session.ID = ""
+		return session, err
+	}
+
+	session.IsNew = false
+
+	return session, nil
+}
+
+// idEncoding encodes the random IDs of the sessions.
+var idEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Save stores the values of the session and sets the cookie with its
+// ID, the sessions with a negative MaxAge are deleted with their cookie.
+func (s *idStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.store.Delete(r.Context(), session.ID); err != nil {
+				return err
+			}
+		}
+
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = idEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+		return err
+	}
+
+	ttl := defaultTTL
+	if session.Options.MaxAge > 0 {
+		ttl = time.Duration(session.Options.MaxAge) * time.Second
+	}
+
+	if err := s.store.Save(r.Context(), session.ID, buf.Bytes(), ttl); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+
+	return nil
+}
+
+// NewMemoryStore returns a Store that keeps the sessions in memory,
+// which suits a single instance of the app. The expired ones are
+// removed as new sessions are saved.
+func NewMemoryStore() Store {
+	return &memoryStore{entries: map[string]memoryEntry{}}
+}
+
+// memoryStore is the in memory Store.
+type memoryStore struct {
+	moot    sync.Mutex
+	entries map[string]memoryEntry
+
+	// swept is when the expired entries were last removed.
+	swept time.Time
+}
+
+// memoryEntry is a session kept in memory.
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+func (m *memoryStore) Load(ctx context.Context, id string) ([]byte, error) {
+	m.moot.Lock()
+	defer m.moot.Unlock()
+
+	e, ok := m.entries[id]
+	if !ok || !time.Now().Before(e.expires) {
+		return nil, nil
+	}
+
+	return e.data, nil
+}
+
+func (m *memoryStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	m.moot.Lock()
+	defer m.moot.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.swept) > time.Minute {
+		m.swept = now
+		for key, e := range m.entries {
+			if !now.Before(e.expires) {
+				delete(m.entries, key)
+			}
+		}
+	}
+
+	m.entries[id] = memoryEntry{data: bytes.Clone(data), expires: now.Add(ttl)}
+
+	return nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, id string) error {
+	m.moot.Lock()
+	defer m.moot.Unlock()
+
+	delete(m.entries, id)
+
+	return nil
+}
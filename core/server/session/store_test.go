@@ -0,0 +1,190 @@
+package session_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// fakeRedis is a RedisClient backed by a map.
+type fakeRedis struct {
+	moot sync.Mutex
+	keys map[string][]byte
+}
+
+func (f *fakeRedis) Get(ctx context.Context, key string) ([]byte, error) {
+	f.moot.Lock()
+	defer f.moot.Unlock()
+
+	return f.keys[key], nil
+}
+
+func (f *fakeRedis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	f.moot.Lock()
+	defer f.moot.Unlock()
+
+	f.keys[key] = value
+	return nil
+}
+
+func (f *fakeRedis) Del(ctx context.Context, key string) error {
+	f.moot.Lock()
+	defer f.moot.Unlock()
+
+	delete(f.keys, key)
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE sessions (id TEXT PRIMARY KEY, data BLOB NOT NULL, expires_at BIGINT NOT NULL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redis := &fakeRedis{keys: map[string][]byte{}}
+	stores := map[string]session.Store{
+		"memory": session.NewMemoryStore(),
+		"redis":  session.NewRedisStore(redis, ""),
+		"sql":    session.NewSQLStore(db, ""),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			s := server.New(
+				server.WithSessionStore(store),
+				server.WithSession("session_test", "test"),
+			)
+
+			s.HandleFunc("GET /set/{value}", func(w http.ResponseWriter, r *http.Request) {
+				sw := session.FromCtx(r.Context())
+				sw.Values["value"] = r.PathValue("value")
+				sw.AddFlash("saved")
+
+				w.Write([]byte("OK"))
+			})
+
+			s.HandleFunc("GET /get", func(w http.ResponseWriter, r *http.Request) {
+				sw := session.FromCtx(r.Context())
+				v, _ := sw.Values["value"].(string)
+				if flashes := sw.Flashes(); len(flashes) == 1 {
+					v += " " + flashes[0].(string)
+				}
+
+				w.Write([]byte(v))
+			})
+
+			s.HandleFunc("GET /logout", func(w http.ResponseWriter, r *http.Request) {
+				sw := session.FromCtx(r.Context())
+				sw.Options.MaxAge = -1
+
+				w.Write([]byte("OK"))
+			})
+
+			h := s.Handler()
+			serve := func(path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				for _, c := range cookies {
+					req.AddCookie(c)
+				}
+
+				res := httptest.NewRecorder()
+				h.ServeHTTP(res, req)
+
+				return res
+			}
+
+			cookies := serve("/set/long-value").Result().Cookies()
+			if len(cookies) != 1 || len(cookies[0].Value) > 200 {
+				t.Fatalf("Expected a cookie with only the session ID, got %v", cookies)
+			}
+
+			if res := serve("/get", cookies...); res.Body.String() != "long-value saved" {
+				t.Errorf("Expected the values of the session from the store, got %q", res.Body.String())
+			}
+
+			if res := serve("/get", cookies...); res.Body.String() != "long-value" {
+				t.Errorf("Expected the flash removed from the store, got %q", res.Body.String())
+			}
+
+			res := serve("/logout", cookies...)
+			if c := res.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+				t.Errorf("Expected the cookie cleared, got %v", c)
+			}
+
+			if res := serve("/get", cookies...); res.Body.String() != "" {
+				t.Errorf("Expected the session deleted from the store, got %q", res.Body.String())
+			}
+		})
+	}
+
+	t.Run("default cookie store", func(t *testing.T) {
+		s := server.New(server.WithSession("session_test", "test"))
+		s.HandleFunc("GET /set", func(w http.ResponseWriter, r *http.Request) {
+			session.FromCtx(r.Context()).Values["value"] = "in the cookie"
+			w.Write([]byte("OK"))
+		})
+
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/set", nil))
+
+		if c := res.Result().Cookies(); len(c) != 1 || len(c[0].Value) < 60 {
+			t.Errorf("Expected the values in the cookie, got %v", c)
+		}
+	})
+}
+
+func TestSQLStoreSweep(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	// the expired session can't be removed.
+	_, err = db.Exec(`
+		CREATE TABLE sessions (id TEXT PRIMARY KEY, data BLOB NOT NULL, expires_at BIGINT NOT NULL);
+		INSERT INTO sessions VALUES ('expired', x'00', 1);
+		CREATE TRIGGER locked BEFORE DELETE ON sessions BEGIN SELECT RAISE(FAIL, 'locked'); END;
+	`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	store := session.NewSQLStore(db, "")
+	if err := store.Save(context.Background(), "id", []byte("data"), time.Minute); err != nil {
+		t.Fatalf("Expected the session saved when the sweep fails, got %v", err)
+	}
+
+	if data, err := store.Load(context.Background(), "id"); err != nil || string(data) != "data" {
+		t.Errorf("Expected the saved session, got %q %v", data, err)
+	}
+
+	if !strings.Contains(logs.String(), "removing the expired sessions") {
+		t.Errorf("Expected the sweep error logged, got %q", logs.String())
+	}
+}
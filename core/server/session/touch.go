@@ -0,0 +1,16 @@
+package session
+
+import "context"
+
+// Touch saves the session in the context when the response is written
+// even when it hasn't changed, which refreshes the expiration of its
+// cookie, and of the session in the store when one is used.
+func Touch(ctx context.Context) {
+	lz := ctx.Value(ctxKey).(*lazy)
+	lz.get()
+
+	lz.moot.Lock()
+	defer lz.moot.Unlock()
+
+	lz.touched = true
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"context"
+	"reflect"
+)
+
+// Get returns the value of the key in the session in the context as a T,
+// false when it's not set or has another type. Integers are converted
+// between their types when the value fits, so an int64 decoded from the
+// session is returned by Get[int].
+func Get[T any](ctx context.Context, key string) (T, bool) {
+	var zero T
+
+	value, ok := FromCtx(ctx).Values[key]
+	if !ok {
+		return zero, false
+	}
+
+	if v, ok := value.(T); ok {
+		return v, true
+	}
+
+	v, ok := convertNumber(value, reflect.TypeOf(zero))
+	if !ok {
+		return zero, false
+	}
+
+	return v.Interface().(T), true
+}
+
+// GetOr returns the value of the key in the session in the context
+// like Get, or the fallback when it's not set or has another type.
+func GetOr[T any](ctx context.Context, key string, fallback T) T {
+	if v, ok := Get[T](ctx, key); ok {
+		return v
+	}
+
+	return fallback
+}
+
+// Set sets the value of the key in the session in the context, custom
+// types must be registered with RegisterSessionTypes to be saved.
+func Set(ctx context.Context, key string, value any) {
+	FromCtx(ctx).Values[key] = value
+}
+
+// Delete removes the key from the session in the context.
+func Delete(ctx context.Context, key string) {
+	delete(FromCtx(ctx).Values, key)
+}
+
+// convertNumber converts the value to the type when both are integers,
+// or floats, and the value fits in it.
+func convertNumber(value any, typ reflect.Type) (reflect.Value, bool) {
+	if typ == nil || value == nil {
+		return reflect.Value{}, false
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case isSigned(v.Kind()) && isInt(typ.Kind()):
+		n := v.Int()
+		if isUint(typ.Kind()) {
+			if n < 0 || reflect.Zero(typ).OverflowUint(uint64(n)) {
+				return reflect.Value{}, false
+			}
+		} else if reflect.Zero(typ).OverflowInt(n) {
+			return reflect.Value{}, false
+		}
+	case isUint(v.Kind()) && isInt(typ.Kind()):
+		n := v.Uint()
+		if isUint(typ.Kind()) {
+			if reflect.Zero(typ).OverflowUint(n) {
+				return reflect.Value{}, false
+			}
+		} else if n > 1<<63-1 || reflect.Zero(typ).OverflowInt(int64(n)) {
+			return reflect.Value{}, false
+		}
+	case isFloat(v.Kind()) && isFloat(typ.Kind()):
+		if reflect.Zero(typ).OverflowFloat(v.Float()) {
+			return reflect.Value{}, false
+		}
+	default:
+		return reflect.Value{}, false
+	}
+
+	return v.Convert(typ), true
+}
+
+// isInt returns whether the kind is of a signed or unsigned integer.
+func isInt(k reflect.Kind) bool {
+	return isSigned(k) || isUint(k)
+}
+
+// isSigned returns whether the kind is of a signed integer.
+func isSigned(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+// isUint returns whether the kind is of an unsigned integer.
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+// isFloat returns whether the kind is of a float.
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
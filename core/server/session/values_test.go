@@ -0,0 +1,55 @@
+package session_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/session"
+)
+
+func TestValues(t *testing.T) {
+	s := server.New(server.WithSession("secret", "app"))
+	s.HandleFunc("GET /set", func(w http.ResponseWriter, r *http.Request) {
+		session.Set(r.Context(), "user_id", 42)
+		session.Set(r.Context(), "visits", int64(7))
+		session.Set(r.Context(), "big", int64(1)<<40)
+		session.Set(r.Context(), "name", "Ada")
+		session.Set(r.Context(), "temporary", true)
+		session.Delete(r.Context(), "temporary")
+
+		w.Write([]byte("OK"))
+	})
+
+	s.HandleFunc("GET /get", func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id, idOK := session.Get[int](ctx, "user_id")
+		visits, visitsOK := session.Get[int](ctx, "visits")
+		_, bigOK := session.Get[int32](ctx, "big")
+		_, wrongOK := session.Get[string](ctx, "user_id")
+		_, missingOK := session.Get[string](ctx, "missing")
+		_, deletedOK := session.Get[bool](ctx, "temporary")
+
+		fmt.Fprintln(w, id, idOK, visits, visitsOK, bigOK, wrongOK, missingOK, deletedOK)
+		fmt.Fprintln(w, session.GetOr(ctx, "name", "anonymous"), session.GetOr(ctx, "theme", "light"))
+	})
+
+	h := s.Handler()
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/set", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	for _, c := range res.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	expected := "42 true 7 true false false false false\nAda light\n"
+	if body := res.Body.String(); body != expected {
+		t.Errorf("Expected %q, got %q", expected, body)
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TrailingSlash determines how the server handles requests whose
+// path only matches a route when the trailing slash is added or removed.
+type TrailingSlash int
+
+const (
+	// Strict treats /api and /api/ as different paths,
+	// this is the default behavior of the server.
+	Strict TrailingSlash = iota
+
+	// Redirect redirects the request to the path that matches a route,
+	// with a 301 for GET and HEAD requests and a 308 for the rest of the
+	// methods so the body is kept. The query string is preserved.
+	Redirect
+
+	// MatchBoth serves the request with the route that matches
+	// the path with or without the trailing slash.
+	MatchBoth
+)
+
+// WithTrailingSlash allows to set how the server handles requests whose path
+// only matches a route with or without the trailing slash, by default it's Strict.
+func WithTrailingSlash(behavior TrailingSlash) Option {
+	return func(m *mux) {
+		m.trailingSlash = behavior
+	}
+}
+
+// slashAlternative returns a copy of the request with the trailing slash
+// of the path added or removed when only that matches a route, nil otherwise.
+func (s *mux) slashAlternative(r *http.Request) *http.Request {
+	if r.URL.Path == "/" {
+		return nil
+	}
+
+	if _, pattern := s.lookup(r); pattern != "" {
+		return nil
+	}
+
+	req := r.Clone(r.Context())
+	req.URL.RawPath = ""
+	req.URL.Path = r.URL.Path + "/"
+	if strings.HasSuffix(r.URL.Path, "/") {
+		req.URL.Path = strings.TrimSuffix(r.URL.Path, "/")
+	}
+
+	if _, pattern := s.lookup(req); pattern == "" {
+		return nil
+	}
+
+	return req
+}
+
+// redirectPermanent redirects the request to the url with a 301 for GET and
+// HEAD requests and a 308 for the rest of the methods so the body is kept.
+func redirectPermanent(w http.ResponseWriter, r *http.Request, url string) {
+	status := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		status = http.StatusPermanentRedirect
+	}
+
+	http.Redirect(w, r, url, status)
+}
@@ -0,0 +1,63 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestTrailingSlash(t *testing.T) {
+	routes := func(s server.Router) {
+		s.HandleFunc("GET /api/{$}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("api " + r.URL.Path))
+		})
+
+		s.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("users " + r.URL.Path))
+		})
+	}
+
+	testCases := []struct {
+		behavior server.TrailingSlash
+		method   string
+		target   string
+		code     int
+		location string
+		body     string
+	}{
+		{server.Strict, http.MethodPost, "/users/", http.StatusNotFound, "", ""},
+		{server.Strict, http.MethodGet, "/api/", http.StatusOK, "", "api /api/"},
+		{server.Redirect, http.MethodGet, "/api?page=2", http.StatusMovedPermanently, "/api/?page=2", ""},
+		{server.Redirect, http.MethodPost, "/users/", http.StatusPermanentRedirect, "/users", ""},
+		{server.Redirect, http.MethodGet, "/other/", http.StatusNotFound, "", ""},
+		{server.MatchBoth, http.MethodGet, "/api", http.StatusOK, "", "api /api/"},
+		{server.MatchBoth, http.MethodPost, "/users/", http.StatusOK, "", "users /users"},
+		{server.MatchBoth, http.MethodGet, "/other", http.StatusNotFound, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method+" "+tc.target, func(t *testing.T) {
+			s := server.New(server.WithTrailingSlash(tc.behavior))
+			routes(s)
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader("body"))
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != tc.code {
+				t.Errorf("Expected status %d, got %d", tc.code, res.Code)
+			}
+
+			if loc := res.Header().Get("Location"); loc != tc.location {
+				t.Errorf("Expected location %q, got %q", tc.location, loc)
+			}
+
+			if tc.body != "" && res.Body.String() != tc.body {
+				t.Errorf("Expected body %q, got %q", tc.body, res.Body.String())
+			}
+		})
+	}
+}
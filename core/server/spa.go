@@ -0,0 +1,92 @@
+package server
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// spa is the handler that serves the files of a single page application,
+// falling back to its index file for the navigation requests.
+type spa struct {
+	*static
+}
+
+// SPA allows to serve a single page application built in the fs.FS under
+// the prefix, like a React or Vue frontend. Files that exist are served like
+// Static does, with an immutable Cache-Control for the ones with a hash in
+// their name like app.3f9a2c1b.js. Other paths serve the index.html of the
+// application without caching when the browser navigates to them, so its
+// client side router can handle them, and respond with a 404 otherwise so
+// missing scripts don't get an HTML body. WithIndexFile changes the name of
+// the index file and WithCacheMaxAge the cache of the files without a hash.
+func (rg *router) SPA(prefix string, fsys fs.FS, options ...StaticOption) {
+	handler := &spa{&static{fs: fsys, maxAge: time.Hour, index: "index.html"}}
+	for _, option := range options {
+		option(handler.static)
+	}
+
+	mount := strings.TrimSuffix(path.Join(rg.prefix, prefix), "/")
+	pattern := http.MethodGet + " " + mount + "/"
+
+	rg.register(newRoute(pattern, handler), stripPrefix(mount, handler))
+}
+
+func (s *spa) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+
+	f, info, err := s.open(strings.TrimSuffix(name, "/"))
+	if err == nil && !info.IsDir() {
+		cacheControl := fmt.Sprintf("public, max-age=%d", int(s.maxAge.Seconds()))
+		if hashed(info.Name()) {
+			cacheControl = "public, max-age=31536000, immutable"
+		}
+
+		s.serve(w, r, f, info, cacheControl)
+		return
+	}
+
+	if err == nil {
+		f.Close()
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		Error(w, fmt.Errorf("404 page not found"), http.StatusNotFound)
+		return
+	}
+
+	f, info, err = s.open(s.index)
+	if err != nil || info.IsDir() {
+		if err == nil {
+			f.Close()
+		}
+
+		Error(w, fmt.Errorf("404 page not found"), http.StatusNotFound)
+		return
+	}
+
+	s.serve(w, r, f, info, "no-cache")
+}
+
+// hashed returns whether the name of the file has a content hash before
+// its extension, like app.3f9a2c1b.js or index-BfK3x9aZ.js. Hashes have
+// at least 8 letters, digits or underscores and one of them is a digit.
+func hashed(name string) bool {
+	stem := strings.TrimSuffix(name, path.Ext(name))
+	i := strings.LastIndexAny(stem, ".-")
+	if i < 0 {
+		return false
+	}
+
+	hash := stem[i+1:]
+	if len(hash) < 8 || !strings.ContainsAny(hash, "0123456789") {
+		return false
+	}
+
+	return strings.IndexFunc(hash, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_')
+	}) < 0
+}
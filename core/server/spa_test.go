@@ -0,0 +1,69 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestSPA(t *testing.T) {
+	files := fstest.MapFS{
+		"index.html":               {Data: []byte("<html>app</html>")},
+		"favicon.ico":              {Data: []byte("icon")},
+		"assets/index-3fK9x2aZ.js": {Data: []byte("console.log()")},
+		"assets/app.css":           {Data: []byte("body {}")},
+	}
+
+	s := server.New()
+	s.HandleFunc("GET /api/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("users"))
+	})
+
+	s.SPA("/app/", files)
+
+	testCases := []struct {
+		name   string
+		path   string
+		accept string
+		code   int
+		body   string
+		ctype  string
+		cache  string
+	}{
+		{"hashed asset", "/app/assets/index-3fK9x2aZ.js", "*/*", http.StatusOK, "console.log()", "text/javascript; charset=utf-8", "public, max-age=31536000, immutable"},
+		{"asset without hash", "/app/assets/app.css", "text/css", http.StatusOK, "body {}", "text/css; charset=utf-8", "public, max-age=3600"},
+		{"root", "/app/", "text/html", http.StatusOK, "<html>app</html>", "text/html; charset=utf-8", "no-cache"},
+		{"client route", "/app/users/1/edit", "text/html,application/xhtml+xml", http.StatusOK, "<html>app</html>", "text/html; charset=utf-8", "no-cache"},
+		{"missing asset", "/app/assets/missing.js", "*/*", http.StatusNotFound, "", "", ""},
+		{"api route", "/api/users", "text/html", http.StatusOK, "users", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Accept", tc.accept)
+
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Code != tc.code {
+				t.Fatalf("Expected status %d, got %d", tc.code, res.Code)
+			}
+
+			if tc.body != "" && res.Body.String() != tc.body {
+				t.Errorf("Expected body %q, got %q", tc.body, res.Body.String())
+			}
+
+			if tc.ctype != "" && res.Header().Get("Content-Type") != tc.ctype {
+				t.Errorf("Expected Content-Type %q, got %q", tc.ctype, res.Header().Get("Content-Type"))
+			}
+
+			if tc.cache != "" && res.Header().Get("Cache-Control") != tc.cache {
+				t.Errorf("Expected Cache-Control %q, got %q", tc.cache, res.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventStream writes server-sent events to the response, it's
+// created with SSE and flushes the response after each event.
+type EventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+	r  *http.Request
+
+	keepAlive time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	stop   chan struct{}
+}
+
+// SSEOption allows to configure the event stream created by SSE.
+type SSEOption func(*EventStream)
+
+// WithKeepAlive sets the interval of the keep-alive comments the event
+// stream sends while there are no events, by default it's 15 seconds.
+func WithKeepAlive(d time.Duration) SSEOption {
+	return func(es *EventStream) {
+		es.keepAlive = d
+	}
+}
+
+// SSE starts a server-sent events response for the request, it sets the
+// headers of the stream and sends keep-alive comments until the request
+// context is done or the stream is closed. It returns an error when the
+// response writer doesn't support flushing.
+//
+//	stream, err := server.SSE(w, r)
+//	if err != nil {
+//		server.Error(w, err, http.StatusInternalServerError)
+//		return
+//	}
+//
+//	defer stream.Close()
+//	for msg := range messages {
+//		if err := stream.Send("message", msg); err != nil {
+//			return
+//		}
+//	}
+func SSE(w http.ResponseWriter, r *http.Request, options ...SSEOption) (*EventStream, error) {
+	es := &EventStream{
+		w:         w,
+		rc:        http.NewResponseController(w),
+		r:         r,
+		keepAlive: 15 * time.Second,
+		stop:      make(chan struct{}),
+	}
+
+	for _, option := range options {
+		option(es)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Del("Content-Length")
+
+	w.WriteHeader(http.StatusOK)
+	if err := es.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("streaming not supported: %w", err)
+	}
+
+	go es.keepAliveLoop()
+
+	return es, nil
+}
+
+// Send writes an event with the data, multiline data is sent in multiple
+// data fields. It returns an error when the stream is closed or the
+// client went away.
+func (es *EventStream) Send(event, data string) error {
+	var b strings.Builder
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+
+	b.WriteString("\n")
+
+	return es.write(b.String())
+}
+
+// Done returns a channel that's closed when the client goes away.
+func (es *EventStream) Done() <-chan struct{} {
+	return es.r.Context().Done()
+}
+
+// Close stops the keep-alive comments, nothing can be sent after
+// it. It should be called before the handler returns.
+func (es *EventStream) Close() {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if !es.closed {
+		es.closed = true
+		close(es.stop)
+	}
+}
+
+func (es *EventStream) write(s string) error {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if es.closed {
+		return errors.New("event stream closed")
+	}
+
+	if err := es.r.Context().Err(); err != nil {
+		return err
+	}
+
+	if _, err := es.w.Write([]byte(s)); err != nil {
+		return err
+	}
+
+	return es.rc.Flush()
+}
+
+func (es *EventStream) keepAliveLoop() {
+	ticker := time.NewTicker(es.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if es.write(": keep-alive\n\n") != nil {
+				return
+			}
+		case <-es.stop:
+			return
+		case <-es.r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestSSE(t *testing.T) {
+	lines := make(lineWriter, 10)
+	log.SetOutput(lines)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	done := make(chan error, 1)
+	s := server.New()
+	s.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		stream, err := server.SSE(w, r, server.WithKeepAlive(10*time.Millisecond))
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+			return
+		}
+
+		defer stream.Close()
+
+		stream.Send("greeting", "hello\nworld")
+		stream.Send("", "plain")
+
+		<-stream.Done()
+		done <- stream.Send("late", "data")
+	})
+
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected content type text/event-stream, got %q", ct)
+	}
+
+	expected := []string{"event: greeting", "data: hello", "data: world", "", "data: plain", "", ": keep-alive"}
+
+	sc := bufio.NewScanner(res.Body)
+	for i := 0; i < len(expected) && sc.Scan(); i++ {
+		if sc.Text() != expected[i] {
+			t.Errorf("Expected line %q, got %q", expected[i], sc.Text())
+		}
+	}
+
+	cancel()
+	res.Body.Close()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Expected an error sending after the client went away")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the handler to stop when the client went away")
+	}
+
+	for {
+		select {
+		case line := <-lines:
+			if !strings.Contains(line, "url=/events") {
+				continue
+			}
+
+			if !strings.Contains(line, "status=200") || strings.Contains(line, "bytes=0") {
+				t.Errorf("Expected the stream to be logged with status and bytes, got %q", line)
+			}
+
+			return
+		case <-time.After(time.Second):
+			t.Fatal("Expected the request to be logged")
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// StaticOption allows to configure how the Static
+// method of the router serves the files.
+type StaticOption func(*static)
+
+// WithCacheMaxAge sets the max-age of the Cache-Control header of
+// the files served, when not specified it defaults to one hour.
+func WithCacheMaxAge(maxAge time.Duration) StaticOption {
+	return func(s *static) {
+		s.maxAge = maxAge
+	}
+}
+
+// WithIndexFile sets the file served when a directory is requested,
+// by default directories respond with a 404.
+func WithIndexFile(name string) StaticOption {
+	return func(s *static) {
+		s.index = name
+	}
+}
+
+// static is the handler that serves the files of a fs.FS.
+type static struct {
+	fs     fs.FS
+	maxAge time.Duration
+	index  string
+}
+
+// Static allows to serve the files in the fs.FS under the prefix, which is
+// combined with the prefix of the group and stripped before looking up the file.
+// Files are served with their Content-Type and a Cache-Control header, missing
+// files respond with a 404 through the error handlers of the server.
+func (rg *router) Static(prefix string, fsys fs.FS, options ...StaticOption) {
+	handler := &static{fs: fsys, maxAge: time.Hour}
+	for _, option := range options {
+		option(handler)
+	}
+
+	mount := strings.TrimSuffix(path.Join(rg.prefix, prefix), "/")
+	pattern := http.MethodGet + " " + mount + "/"
+
+	rg.register(newRoute(pattern, handler), stripPrefix(mount, handler))
+}
+
+func (s *static) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f, info, err := s.file(strings.TrimPrefix(r.URL.Path, "/"))
+	if err != nil {
+		Error(w, fmt.Errorf("404 page not found"), http.StatusNotFound)
+		return
+	}
+
+	s.serve(w, r, f, info, fmt.Sprintf("public, max-age=%d", int(s.maxAge.Seconds())))
+}
+
+// serve writes the content of the file with the Cache-Control header,
+// the Content-Type is taken from the extension of its name.
+func (s *static) serve(w http.ResponseWriter, r *http.Request, f fs.File, info fs.FileInfo, cacheControl string) {
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			Error(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		content = bytes.NewReader(data)
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, info.Name(), info.ModTime(), content)
+}
+
+// file opens the file with the passed name, for directories
+// it opens their index file when one has been set.
+func (s *static) file(name string) (fs.File, fs.FileInfo, error) {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" {
+		name = "."
+	}
+
+	f, info, err := s.open(name)
+	if err != nil || !info.IsDir() {
+		return f, info, err
+	}
+
+	f.Close()
+	if s.index == "" {
+		return nil, nil, fs.ErrNotExist
+	}
+
+	f, info, err = s.open(path.Join(name, s.index))
+	if err == nil && info.IsDir() {
+		f.Close()
+		return nil, nil, fs.ErrNotExist
+	}
+
+	return f, info, err
+}
+
+// open opens the file and returns it along with its info. Names with
+// .. elements are rejected so requests can't escape the root of the fs.FS.
+func (s *static) open(name string) (fs.File, fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, nil, fs.ErrInvalid
+	}
+
+	f, err := s.fs.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return f, info, nil
+}
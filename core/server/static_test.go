@@ -0,0 +1,78 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestStatic(t *testing.T) {
+	files := fstest.MapFS{
+		"app.css":           {Data: []byte("body {}")},
+		"js/app.js":         {Data: []byte("console.log()")},
+		"docs/index.html":   {Data: []byte("<html>docs</html>")},
+		"docs/guide/a.html": {Data: []byte("<html>a</html>")},
+	}
+
+	s := server.New(
+		server.WithErrorHandler(http.StatusNotFound, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("custom not found"))
+		}),
+	)
+
+	s.Group("/public/", func(r server.Router) {
+		r.Static("/assets/", files, server.WithCacheMaxAge(24*time.Hour))
+		r.Static("/site/", files, server.WithIndexFile("index.html"))
+	})
+
+	testCases := []struct {
+		path  string
+		code  int
+		body  string
+		ctype string
+		cache string
+	}{
+		{"/public/assets/app.css", http.StatusOK, "body {}", "text/css; charset=utf-8", "public, max-age=86400"},
+		{"/public/assets/js/app.js", http.StatusOK, "console.log()", "text/javascript; charset=utf-8", "public, max-age=86400"},
+		{"/public/assets/missing.css", http.StatusNotFound, "custom not found", "", ""},
+		{"/public/assets/docs/", http.StatusNotFound, "custom not found", "", ""},
+		{"/public/assets/../../app.css", 0, "", "", ""},
+		{"/public/site/docs/", http.StatusOK, "<html>docs</html>", "text/html; charset=utf-8", "public, max-age=3600"},
+		{"/public/site/docs/guide/", http.StatusNotFound, "custom not found", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.URL.Path = tc.path
+
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if tc.code != 0 && res.Code != tc.code {
+				t.Errorf("Expected status %d, got %d", tc.code, res.Code)
+			}
+
+			if tc.body != "" && res.Body.String() != tc.body {
+				t.Errorf("Expected body %q, got %q", tc.body, res.Body.String())
+			}
+
+			if tc.ctype != "" && res.Header().Get("Content-Type") != tc.ctype {
+				t.Errorf("Expected Content-Type %q, got %q", tc.ctype, res.Header().Get("Content-Type"))
+			}
+
+			if tc.cache != "" && res.Header().Get("Cache-Control") != tc.cache {
+				t.Errorf("Expected Cache-Control %q, got %q", tc.cache, res.Header().Get("Cache-Control"))
+			}
+
+			if tc.code == 0 && res.Code == http.StatusOK {
+				t.Errorf("Expected path traversal not to be served, got %q", res.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// table is the state the server serves the requests with. It's published
+// when Handler is called and every change made after that builds a new one
+// that replaces it atomically, so the requests in flight keep the one they
+// started with and the tables are never modified once published.
+type table struct {
+	mux         Matcher
+	hosts       map[string]Matcher
+	dispatchers map[string]*dispatcher
+	routes      []Route
+	refs        []*RouteRef
+	notFound    []notFoundHandler
+
+	// methods the routes are registered for, in the order they were
+	// first registered, the ones the unmatched requests are tried with.
+	methods []string
+}
+
+// entry is a handler registered in the matchers for a route with a
+// single method, kept to register it again when the table is rebuilt.
+type entry struct {
+	route   Route
+	ref     *RouteRef
+	handler http.Handler
+}
+
+// current returns the table the requests are served with, before Handler
+// is called it's built from the routes registered so far.
+func (rr *registry) current() *table {
+	if t := rr.table.Load(); t != nil {
+		return t
+	}
+
+	return &table{
+		mux:         rr.mux,
+		hosts:       rr.hosts,
+		dispatchers: rr.dispatchers,
+		routes:      rr.routes,
+		refs:        rr.refs,
+		notFound:    rr.notFound,
+		methods:     routeMethods(rr.routes),
+	}
+}
+
+// update runs fn holding the lock of the registry. Once the server is live
+// the matchers are rebuilt before running it, so fn never modifies the ones
+// serving requests, and the resulting table is published afterwards.
+func (rr *registry) update(fn func()) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if !rr.live {
+		fn()
+		return
+	}
+
+	rr.rebuild()
+	fn()
+	rr.fallbackHosts()
+	rr.publish()
+}
+
+// report keeps the error for Check.
+func (rr *registry) report(err error) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.errs = append(rr.errs, err)
+}
+
+// rebuild replaces the matchers and dispatchers with new ones
+// that have the handlers of the entries registered again.
+func (rr *registry) rebuild() {
+	entries := rr.entries
+	hosts := make(map[string]Matcher, len(rr.hosts))
+	for host := range rr.hosts {
+		hosts[host] = rr.newMatcher()
+	}
+
+	rr.mux, rr.hosts, rr.dispatchers, rr.entries = rr.newMatcher(), hosts, nil, nil
+	for _, e := range entries {
+		// entries were registered before, they can't fail now.
+		_ = rr.add(e.route, e.ref, e.handler)
+	}
+}
+
+// publish makes the current state of the registry
+// the table the requests are served with.
+func (rr *registry) publish() {
+	rr.table.Store(&table{
+		mux:         rr.mux,
+		hosts:       maps.Clone(rr.hosts),
+		dispatchers: rr.dispatchers,
+		routes:      slices.Clone(rr.routes),
+		refs:        slices.Clone(rr.refs),
+		notFound:    slices.Clone(rr.notFound),
+		methods:     routeMethods(rr.routes),
+	})
+}
+
+// routeMethods returns the methods the routes are registered
+// for, leaving out the routes that match any method.
+func routeMethods(routes []Route) []string {
+	var methods []string
+	for _, route := range routes {
+		for _, method := range route.methods() {
+			if method != "" && !slices.Contains(methods, method) {
+				methods = append(methods, method)
+			}
+		}
+	}
+
+	return methods
+}
+
+// fallbackHosts makes the hosts without a catch-all
+// route fall back to the host-less routes.
+func (rr *registry) fallbackHosts() {
+	for host := range rr.hosts {
+		if rr.rootSet(host) {
+			continue
+		}
+
+		route := Route{Pattern: "/", Host: host, Handler: handlerName(rr.mux)}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rr.current().mux.ServeHTTP(w, r)
+		})
+
+		if err := rr.add(route, nil, handler); err == nil {
+			rr.routes = append(rr.routes, route)
+		}
+	}
+}
+
+// rootSet returns whether a catch-all route for all the methods has
+// been registered for the host in the server or any of its groups.
+func (rr *registry) rootSet(host string) bool {
+	for _, route := range rr.routes {
+		if route.Host == host && route.Method == "" && route.Pattern == "/" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matcher returns the matcher for the routes scoped to the host.
+func (rr *registry) matcher(host string) Matcher {
+	if host == "" {
+		return rr.mux
+	}
+
+	return rr.hosts[host]
+}
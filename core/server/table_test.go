@@ -0,0 +1,155 @@
+package server_test
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestRegisterAfterStart(t *testing.T) {
+	// the logger of the base middleware writes to the default logger.
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	t.Run("routes registered while serving are served", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("pong"))
+		})
+
+		h := s.Handler()
+
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+
+					res := httptest.NewRecorder()
+					h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/ping", nil))
+					if res.Body.String() != "pong" {
+						t.Errorf("Expected pong while registering routes, got %q", res.Body.String())
+						return
+					}
+
+					h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/plugins/3", nil))
+					s.Routes()
+				}
+			}()
+		}
+
+		for i := 0; i < 20; i++ {
+			body := fmt.Sprint(i)
+			s.HandleFunc(fmt.Sprintf("GET /plugins/%d", i), func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			}).Meta("plugin", i)
+		}
+
+		s.Group("/admin", func(r server.Router) {
+			r.HandleFunc("GET /modules/{name}", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.PathValue("name")))
+			})
+		})
+
+		close(done)
+		wg.Wait()
+
+		if err := s.Check(); err != nil {
+			t.Fatalf("Expected no errors, got %v", err)
+		}
+
+		for path, body := range map[string]string{"/plugins/7": "7", "/admin/modules/blog": "blog", "/ping": "pong"} {
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+			if res.Code != http.StatusOK || res.Body.String() != body {
+				t.Errorf("Expected %q for %s, got %d %q", body, path, res.Code, res.Body.String())
+			}
+		}
+	})
+
+	t.Run("requests in flight finish with the old routes", func(t *testing.T) {
+		s := server.New()
+
+		started, release := make(chan struct{}), make(chan struct{})
+		s.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+
+			route, _ := server.CurrentRoute(r)
+			w.Write([]byte(route.Pattern))
+		})
+
+		h := s.Handler()
+
+		res := httptest.NewRecorder()
+		served := make(chan struct{})
+		go func() {
+			defer close(served)
+			h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		}()
+
+		<-started
+		s.HandleFunc("GET /fast", func(w http.ResponseWriter, r *http.Request) {})
+		close(release)
+		<-served
+
+		if res.Body.String() != "/slow" {
+			t.Errorf("Expected the request in flight to finish, got %q", res.Body.String())
+		}
+
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/fast", nil))
+		if res.Code != http.StatusOK {
+			t.Errorf("Expected the new route to be served, got %d", res.Code)
+		}
+	})
+
+	t.Run("host registered after start falls back to the host-less routes", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("GET /about", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("about"))
+		})
+
+		h := s.Handler()
+		s.Host("api.example.com", func(r server.Router) {
+			r.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			})
+		})
+
+		for path, body := range map[string]string{"/status": "ok", "/about": "about"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Host = "api.example.com"
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			if res.Body.String() != body {
+				t.Errorf("Expected %q for %s, got %q", body, path, res.Body.String())
+			}
+		}
+	})
+
+	t.Run("conflicts after start are reported", func(t *testing.T) {
+		s := server.New()
+		s.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {})
+		s.Handler()
+
+		s.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {})
+		if s.Check() == nil {
+			t.Error("Expected the conflict to be reported")
+		}
+	})
+}
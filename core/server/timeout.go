@@ -0,0 +1,223 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server/internal/response"
+)
+
+// WithTimeout cancels the context of the requests to the route after the
+// duration. When the handler hasn't responded by then a 503 is written
+// through the error handlers and later writes of the handler are dropped.
+// It takes the place of the Timeout middleware of the groups.
+func WithTimeout(d time.Duration) RouteOption {
+	return func(rf *RouteRef) {
+		rf.middleware = append(rf.middleware, timeout(d))
+	}
+}
+
+// Timeout returns a middleware that cancels the context of the requests
+// after the duration, writing a 503 through the error handlers when the
+// handler hasn't written the response by then. Responses that are flushed,
+// like server-sent events, or hijacked, like websockets, are not timed out.
+// The Timeout of a group takes the place of the one of its parents, and
+// WithTimeout the one of the groups, so the closest one wins.
+func Timeout(d time.Duration) Middleware {
+	return timeout(d)
+}
+
+// timeout runs the handler with a context that is canceled after the
+// duration, writing a 503 if the handler hasn't written the response.
+func timeout(d time.Duration) Middleware {
+	return timeoutMiddleware(d).wrap
+}
+
+// timeoutMiddleware is the duration of a timeout, the middleware is its
+// wrap method so all the timeouts share the code pointer of the method
+// value and closest can find them.
+type timeoutMiddleware time.Duration
+
+func (tm timeoutMiddleware) wrap(next http.Handler) http.Handler {
+	d := time.Duration(tm)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		r = r.WithContext(timeoutContext{ctx})
+
+		// The handler gets its own server writer so it doesn't
+		// race with the error response written here on timeout.
+		tw := &timeoutWriter{w: w, header: w.Header().Clone(), timer: timer}
+		inner := &response.Writer{ResponseWriter: tw, Request: r}
+		if rw := response.Root(w); rw != nil {
+			rw.Request = r
+			inner.ErrorHandler = rw.ErrorHandler
+		}
+
+		done := make(chan any, 1)
+		go func() {
+			defer func() { done <- recover() }()
+			next.ServeHTTP(inner, r)
+		}()
+
+		select {
+		case p := <-done:
+			if p != nil {
+				panic(p)
+			}
+		case <-ctx.Done():
+			// the client went away.
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			tw.timedOut = true
+		case <-timer.C:
+			tw.mu.Lock()
+
+			// the response started being streamed as the timer fired.
+			if tw.streaming {
+				tw.mu.Unlock()
+				if p := <-done; p != nil {
+					panic(p)
+				}
+
+				return
+			}
+
+			defer tw.mu.Unlock()
+
+			tw.timedOut = true
+			cancel(context.DeadlineExceeded)
+			if tw.wroteHeader {
+				return
+			}
+
+			route, _ := loggedRoute(r)
+			serverLogger(r).Error("request timed out", "route", route, "after", d, "request_id", RequestID(r))
+			Error(w, fmt.Errorf("request timed out after %v", d), http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// timeoutContext is the context of a request with a timeout, its Err
+// is context.DeadlineExceeded when it times out, like the one returned
+// by context.WithTimeout, which can't be stopped for streamed responses.
+type timeoutContext struct {
+	context.Context
+}
+
+func (c timeoutContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && errors.Is(context.Cause(c.Context), context.DeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+
+	return err
+}
+
+// timeoutPC is the code pointer of the middleware returned by timeout.
+var timeoutPC = reflect.ValueOf(timeoutMiddleware(0).wrap).Pointer()
+
+// timeoutWriter passes the response of the handler through to the
+// writer until the request times out, writes after that are dropped.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	// timer is stopped when the response is flushed or hijacked,
+	// which sets streaming, and the request doesn't time out after it.
+	timer     *time.Timer
+	streaming bool
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.writeHeader(status)
+}
+
+func (tw *timeoutWriter) writeHeader(status int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+
+	tw.wroteHeader = true
+
+	h := tw.w.Header()
+	for k := range h {
+		delete(h, k)
+	}
+
+	for k, v := range tw.header {
+		h[k] = v
+	}
+
+	tw.w.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	tw.writeHeader(http.StatusOK)
+
+	return tw.w.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+
+	tw.timer.Stop()
+	tw.streaming = true
+	tw.writeHeader(http.StatusOK)
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection, the request doesn't time out after it.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+
+	conn, rw, err := http.NewResponseController(tw.w).Hijack()
+	if err == nil {
+		tw.timer.Stop()
+		tw.streaming = true
+	}
+
+	return conn, rw, err
+}
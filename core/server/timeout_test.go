@@ -0,0 +1,144 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestWithTimeout(t *testing.T) {
+	s := server.New(
+		server.WithErrorHandler(http.StatusServiceUnavailable, func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("unavailable: " + err.Error()))
+		}),
+	)
+
+	late := make(chan error, 1)
+	s.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte("late"))
+		late <- err
+	}, server.WithTimeout(20*time.Millisecond))
+
+	s.HandleFunc("GET /fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Fast", "true")
+		w.Write([]byte("fast"))
+	}, server.WithTimeout(time.Second))
+
+	s.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}, server.WithTimeout(time.Second))
+
+	t.Run("timed out", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, res.Code)
+		}
+
+		if res.Body.String() != "unavailable: request timed out after 20ms" {
+			t.Errorf("Expected the error handler response, got %q", res.Body.String())
+		}
+
+		if err := <-late; err != http.ErrHandlerTimeout {
+			t.Errorf("Expected late writes to fail with %v, got %v", http.ErrHandlerTimeout, err)
+		}
+
+		if res.Body.String() != "unavailable: request timed out after 20ms" {
+			t.Errorf("Expected late writes to be dropped, got %q", res.Body.String())
+		}
+	})
+
+	t.Run("in time", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/fast", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusOK || res.Body.String() != "fast" {
+			t.Errorf("Expected 200 'fast', got %d %q", res.Code, res.Body.String())
+		}
+
+		if res.Header().Get("X-Fast") != "true" {
+			t.Errorf("Expected the handler headers in the response")
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+		res := httptest.NewRecorder()
+		s.Handler().ServeHTTP(res, req)
+
+		if res.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, res.Code)
+		}
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	s := server.New()
+	s.Use(server.Timeout(20 * time.Millisecond))
+
+	wait := func(d time.Duration) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(d):
+				w.Write([]byte("done"))
+			case <-r.Context().Done():
+				if r.Context().Err() != context.DeadlineExceeded {
+					t.Errorf("Expected the context to exceed its deadline, got %v", r.Context().Err())
+				}
+			}
+		}
+	}
+
+	s.HandleFunc("GET /slow", wait(100*time.Millisecond))
+	s.Group("/reports", func(r server.Router) {
+		r.Use(server.Timeout(time.Second))
+		r.HandleFunc("GET /slow", wait(50*time.Millisecond))
+		r.HandleFunc("GET /slower", wait(100*time.Millisecond), server.WithTimeout(10*time.Millisecond))
+	})
+
+	s.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("data: second\n\n"))
+	})
+
+	testCases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/slow", http.StatusServiceUnavailable, ""},
+		{"/reports/slow", http.StatusOK, "done"},
+		{"/reports/slower", http.StatusServiceUnavailable, ""},
+		{"/events", http.StatusOK, "data: first\n\ndata: second\n\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if res.Code != tc.code {
+				t.Errorf("Expected status %d, got %d", tc.code, res.Code)
+			}
+
+			if tc.body != "" && res.Body.String() != tc.body {
+				t.Errorf("Expected body %q, got %q", tc.body, res.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package tracing provides the OpenTelemetry tracing middleware of the
+// server. It's a module of its own so the apps that don't trace their
+// requests don't depend on OpenTelemetry.
+package tracing
+
+import (
+	"cmp"
+	"net/http"
+	"strings"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/internal/response"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation is the name of the tracer of the middleware.
+const instrumentation = "github.com/leapkit/leapkit/core/server/tracing"
+
+// Middleware returns a middleware that starts a span with the tracer
+// provider for each request, named after the method and pattern of the
+// route that serves it, like GET /users/{id}. The span continues the
+// trace of the W3C traceparent header of the request, it has the method,
+// route and status of the response as attributes and it's marked as an
+// error for 5xx responses and panics. The context of the request carries
+// the span so the calls made with it, like database queries, join the
+// trace.
+func Middleware(tp trace.TracerProvider) server.Middleware {
+	tracer := tp.Tracer(instrumentation)
+	propagator := propagation.TraceContext{}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route, _ := server.CurrentRoute(r)
+			ctx, span := tracer.Start(ctx, strings.TrimSpace(r.Method+" "+route.Pattern),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("http.route", route.Pattern),
+					attribute.String("url.path", r.URL.Path),
+				),
+			)
+
+			defer span.End()
+
+			rw := response.Root(w)
+			if rw == nil {
+				rw = &response.Writer{ResponseWriter: w}
+				w = rw
+			}
+
+			completed := false
+			defer func() {
+				// the server recovers the panic and writes a 500.
+				if !completed {
+					span.SetAttributes(attribute.Int("http.response.status_code", http.StatusInternalServerError))
+					span.SetStatus(codes.Error, "panic recovered")
+
+					return
+				}
+
+				status := cmp.Or(rw.Status, http.StatusOK)
+				span.SetAttributes(attribute.Int("http.response.status_code", status))
+				if status >= http.StatusInternalServerError {
+					span.SetStatus(codes.Error, http.StatusText(status))
+				}
+			}()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+			completed = true
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package tracing_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+	"github.com/leapkit/leapkit/core/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	var handlerSpan trace.SpanContext
+	s := server.New()
+	s.Use(tracing.Middleware(tp))
+	s.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.Write([]byte("Jane"))
+	})
+
+	s.HandleFunc("GET /broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	s.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	h := s.Handler()
+	serve := func(req *http.Request) sdktrace.ReadOnlySpan {
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		return spans[len(spans)-1]
+	}
+
+	attr := func(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+		for _, a := range span.Attributes() {
+			if a.Key == key {
+				return a.Value
+			}
+		}
+
+		return attribute.Value{}
+	}
+
+	t.Run("span per request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		span := serve(req)
+		if span.Name() != "GET /users/{id}" {
+			t.Errorf("Expected the span to be named after the route, got %q", span.Name())
+		}
+
+		if span.SpanKind() != trace.SpanKindServer {
+			t.Errorf("Expected a server span, got %v", span.SpanKind())
+		}
+
+		if span.Parent().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Expected the span to continue the trace of the traceparent, got %v", span.Parent().TraceID())
+		}
+
+		if attr(span, "http.route").AsString() != "/users/{id}" || attr(span, "http.response.status_code").AsInt64() != 200 {
+			t.Errorf("Expected the route and status attributes, got %v", span.Attributes())
+		}
+
+		if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+			t.Error("Expected the context of the request to carry the span")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		span := serve(httptest.NewRequest(http.MethodGet, "/broken", nil))
+		if span.Status().Code != codes.Error {
+			t.Errorf("Expected the span of a 5xx to be an error, got %v", span.Status())
+		}
+
+		span = serve(httptest.NewRequest(http.MethodGet, "/panic", nil))
+		if span.Status().Code != codes.Error || attr(span, "http.response.status_code").AsInt64() != 500 {
+			t.Errorf("Expected the span of a panic to be an error, got %v", span.Status())
+		}
+	})
+}
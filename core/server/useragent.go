@@ -0,0 +1,95 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// UserAgentOptions are the options of the FilterUserAgents middleware.
+// The patterns are globs, where * matches any characters, or regular
+// expressions between slashes, like /^curl\//, both matched regardless
+// of case. The empty pattern matches the requests without User-Agent.
+type UserAgentOptions struct {
+	// Block are the patterns of the user agents that are denied.
+	Block []string
+
+	// Allow are the patterns of the user agents that are never denied,
+	// like the ones of the search engines, even if they match Block.
+	Allow []string
+
+	// Status is the status of the responses to the denied
+	// requests, like 429 to look rate limited. It defaults to 403.
+	Status int
+}
+
+// BlockUserAgents returns a middleware that denies the requests whose
+// User-Agent matches one of the patterns with a 403, see UserAgentOptions
+// for the syntax of the patterns.
+func BlockUserAgents(patterns ...string) Middleware {
+	return FilterUserAgents(UserAgentOptions{Block: patterns})
+}
+
+// FilterUserAgents returns a middleware that denies the requests whose
+// User-Agent matches one of the Block patterns and none of the Allow ones,
+// through the error handler for the status. The patterns are compiled
+// once, it panics when one of them is not a valid regular expression.
+// The denied requests are counted by the Metrics middleware.
+func FilterUserAgents(options UserAgentOptions) Middleware {
+	block, allow := compileUserAgents(options.Block), compileUserAgents(options.Allow)
+	status := cmp.Or(options.Status, http.StatusForbidden)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ua := r.UserAgent()
+			if !matchUserAgent(block, ua) || matchUserAgent(allow, ua) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			defaultMetrics.blockedUserAgent(r)
+			Error(w, fmt.Errorf("%d user agent %q is not allowed", status, ua), status)
+		})
+	}
+}
+
+// compileUserAgents compiles the patterns into case insensitive
+// regular expressions, the globs are anchored to the whole value.
+func compileUserAgents(patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		expr, ok := strings.CutPrefix(pattern, "/")
+		if ok && len(expr) > 0 && strings.HasSuffix(expr, "/") {
+			expr = strings.TrimSuffix(expr, "/")
+		} else {
+			parts := strings.Split(pattern, "*")
+			for i, part := range parts {
+				parts[i] = regexp.QuoteMeta(part)
+			}
+
+			expr = "^" + strings.Join(parts, ".*") + "$"
+		}
+
+		re, err := regexp.Compile("(?i)" + expr)
+		if err != nil {
+			panic(fmt.Errorf("invalid user agent pattern %q: %w", pattern, err))
+		}
+
+		compiled = append(compiled, re)
+	}
+
+	return compiled
+}
+
+// matchUserAgent returns whether the user agent matches any of the patterns.
+func matchUserAgent(patterns []*regexp.Regexp, ua string) bool {
+	for _, re := range patterns {
+		if re.MatchString(ua) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,97 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestFilterUserAgents(t *testing.T) {
+	s := server.New(server.WithMetricsEndpoint("/metrics"))
+	s.Use(server.Metrics())
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+
+	s.Group("/search", func(r server.Router) {
+		r.Use(server.FilterUserAgents(server.UserAgentOptions{
+			Block: []string{"*bot*", `/^(curl|wget)\//`, ""},
+			Allow: []string{"*Googlebot*"},
+		}))
+
+		r.HandleFunc("GET /{$}", ok)
+	})
+
+	s.Group("/api", func(r server.Router) {
+		r.Use(server.FilterUserAgents(server.UserAgentOptions{
+			Block:  []string{"python-requests/*"},
+			Status: http.StatusTooManyRequests,
+		}))
+
+		r.HandleFunc("GET /items", ok)
+	})
+
+	s.Group("/feed", func(r server.Router) {
+		r.Use(server.BlockUserAgents("*Scraper*"))
+		r.HandleFunc("GET /{$}", ok)
+	})
+
+	h := s.Handler()
+	serve := func(path, ua string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("User-Agent", ua)
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		return res
+	}
+
+	cases := []struct {
+		path   string
+		ua     string
+		status int
+	}{
+		{"/search/", "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0)", http.StatusOK},
+		{"/search/", "AhrefsBot/7.0", http.StatusForbidden},
+		{"/search/", "SEMRUSHBOT", http.StatusForbidden},
+		{"/search/", "curl/8.4.0", http.StatusForbidden},
+		{"/search/", "libcurl/8.4.0", http.StatusOK},
+		{"/search/", "", http.StatusForbidden},
+		{"/search/", "Mozilla/5.0 (compatible; Googlebot/2.1)", http.StatusOK},
+		{"/api/items", "python-requests/2.31", http.StatusTooManyRequests},
+		{"/api/items", "", http.StatusOK},
+		{"/feed/", "FeedScraper 1.0", http.StatusForbidden},
+		{"/feed/", "Reader 1.0", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		if res := serve(tc.path, tc.ua); res.Code != tc.status {
+			t.Errorf("Expected %d for %q on %s, got %d", tc.status, tc.ua, tc.path, res.Code)
+		}
+	}
+
+	metrics := serve("/metrics", "").Body.String()
+	for _, exp := range []string{
+		`http_user_agents_blocked_total{route="/search/{$}"} 4`,
+		`http_user_agents_blocked_total{route="/api/items"} 1`,
+	} {
+		if !strings.Contains(metrics, exp) {
+			t.Errorf("Expected %q in the metrics, got %q", exp, metrics)
+		}
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected an invalid regular expression to panic")
+			}
+		}()
+
+		server.BlockUserAgents("/(/")
+	})
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+)
+
+// versionKey is the context key for the API version of a version group.
+const versionKey contextKey = "apiVersion"
+
+// Version allows to create a group of routes under the /{version} prefix
+// that stores the version in the request context, it can be read with
+// APIVersion. Versions can't be nested, doing so is reported by Check.
+func (rg *router) Version(version string, rfn func(rg Router), middleware ...Middleware) {
+	if rg.version != "" {
+		rg.report(fmt.Errorf("version %q registered at %s is nested in version %q", version, callerSource(), rg.version))
+		return
+	}
+
+	setVersion := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), versionKey, version))
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	group := &router{
+		prefix:     path.Join(rg.prefix, version),
+		middleware: slices.Concat(rg.middleware, []Middleware{setVersion}, middleware),
+		host:       rg.host,
+		version:    version,
+		errors:     &errorScope{parent: rg.errors},
+		names:      slices.Clip(rg.names),
+		skip:       slices.Clip(rg.skip),
+		registry:   rg.registry,
+	}
+
+	rfn(group)
+}
+
+// APIVersion returns the version of the version group
+// that served the request, empty when there is none.
+func APIVersion(r *http.Request) string {
+	version, _ := r.Context().Value(versionKey).(string)
+	return version
+}
+
+// APIVersionHeader is a middleware that sets the X-API-Version header
+// of the response to the version of the version group serving the request.
+func APIVersionHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if version := APIVersion(r); version != "" {
+			w.Header().Set("X-API-Version", version)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
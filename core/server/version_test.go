@@ -0,0 +1,71 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leapkit/leapkit/core/server"
+)
+
+func TestVersion(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("version " + server.APIVersion(r)))
+	}
+
+	s := server.New()
+	s.Group("/api/", func(r server.Router) {
+		r.Version("v1", func(r server.Router) {
+			r.HandleFunc("GET /users", handler)
+		})
+
+		r.Version("v2", func(r server.Router) {
+			r.HandleFunc("GET /users", handler)
+		}, server.APIVersionHeader)
+	})
+
+	s.HandleFunc("GET /status", handler)
+
+	cases := []struct {
+		path   string
+		body   string
+		header string
+	}{
+		{"/api/v1/users", "version v1", ""},
+		{"/api/v2/users", "version v2", "v2"},
+		{"/status", "version ", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			res := httptest.NewRecorder()
+			s.Handler().ServeHTTP(res, req)
+
+			if res.Body.String() != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, res.Body.String())
+			}
+
+			if h := res.Header().Get("X-API-Version"); h != c.header {
+				t.Errorf("Expected X-API-Version %q, got %q", c.header, h)
+			}
+		})
+	}
+
+	t.Run("nested versions", func(t *testing.T) {
+		s := server.New()
+		s.Version("v1", func(r server.Router) {
+			r.Group("/admin/", func(r server.Router) {
+				r.Version("v2", func(r server.Router) {
+					r.HandleFunc("GET /users", handler)
+				})
+			})
+		})
+
+		err := s.Check()
+		if err == nil || !strings.Contains(err.Error(), `version "v2" registered at`) || !strings.Contains(err.Error(), `nested in version "v1"`) {
+			t.Errorf("Expected a nested version error, got %v", err)
+		}
+	})
+}